@@ -0,0 +1,63 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+)
+
+// AdvisoryLockKey derives a Postgres advisory lock key from a name, ie.
+// "migrations" or "billing-worker".
+func AdvisoryLockKey(name string) int64 {
+	return int64(fnvHash(name))
+}
+
+// WithAdvisoryLock runs fn while holding a session level advisory lock on
+// key, waiting until the lock is available. It serializes work across app
+// replicas, ie. applying schema migrations while deploying several replicas
+// at once. The lock is held on a dedicated pool connection and released
+// when fn returns, or by Postgres if the connection is lost.
+func (d *DB) WithAdvisoryLock(ctx context.Context, key int64, fn func(ctx context.Context) error) error {
+	conn, err := d.Conn.Acquire(ctx)
+	if err != nil {
+		return wrapErr(err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+		return fmt.Errorf("pgkit: advisory lock %d: %w", key, err)
+	}
+	defer func() {
+		// unlock with a fresh context, so a canceled ctx doesn't leave the
+		// lock held on a connection returned to the pool
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			conn.Conn().Close(context.Background())
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// TryAdvisoryLock is like WithAdvisoryLock but doesn't wait for the lock. It
+// reports whether the lock was acquired and fn was run.
+func (d *DB) TryAdvisoryLock(ctx context.Context, key int64, fn func(ctx context.Context) error) (bool, error) {
+	conn, err := d.Conn.Acquire(ctx)
+	if err != nil {
+		return false, wrapErr(err)
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&locked); err != nil {
+		return false, fmt.Errorf("pgkit: advisory lock %d: %w", key, err)
+	}
+	if !locked {
+		return false, nil
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			conn.Conn().Close(context.Background())
+		}
+	}()
+
+	return true, fn(ctx)
+}
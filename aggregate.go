@@ -0,0 +1,124 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Aggregate runs `SELECT <expr> FROM <table> WHERE <where>` and scans the
+// single resulting value into T, ie.
+//
+//	n, err := pgkit.Aggregate[int64](ctx, db.Query, "COUNT(DISTINCT author)", "articles", nil)
+//
+// The where condition is optional. If the aggregate is NULL, ie. MAX over no
+// rows, ErrNoRows is returned along with the zero value of T.
+func Aggregate[T any](ctx context.Context, q *Querier, expr string, tableName string, where sq.Sqlizer) (T, error) {
+	var zero T
+
+	query := q.SQL.Select(expr).From(tableName)
+	if where != nil {
+		query = query.Where(where)
+	}
+
+	var v *T
+	if err := q.QueryRow(ctx, query).Scan(&v); err != nil {
+		return zero, wrapErr(err)
+	}
+	if v == nil {
+		return zero, ErrNoRows
+	}
+	return *v, nil
+}
+
+// Sum returns the sum of column over the matching rows, or zero if no rows
+// match.
+func Sum[T any](ctx context.Context, q *Querier, tableName, column string, where sq.Sqlizer) (T, error) {
+	return Aggregate[T](ctx, q, fmt.Sprintf("COALESCE(SUM(%s), 0)", column), tableName, where)
+}
+
+// Avg returns the average of column over the matching rows, or ErrNoRows if
+// no rows match.
+func Avg[T any](ctx context.Context, q *Querier, tableName, column string, where sq.Sqlizer) (T, error) {
+	return Aggregate[T](ctx, q, fmt.Sprintf("AVG(%s)", column), tableName, where)
+}
+
+// Min returns the minimum of column over the matching rows, or ErrNoRows if
+// no rows match.
+func Min[T any](ctx context.Context, q *Querier, tableName, column string, where sq.Sqlizer) (T, error) {
+	return Aggregate[T](ctx, q, fmt.Sprintf("MIN(%s)", column), tableName, where)
+}
+
+// Max returns the maximum of column over the matching rows, or ErrNoRows if
+// no rows match.
+func Max[T any](ctx context.Context, q *Querier, tableName, column string, where sq.Sqlizer) (T, error) {
+	return Aggregate[T](ctx, q, fmt.Sprintf("MAX(%s)", column), tableName, where)
+}
+
+// GroupCount returns the number of matching rows per distinct value of the
+// group column, ie. to count rows by status. Values are keyed by their text
+// representation, with NULL as the empty string.
+func GroupCount(ctx context.Context, q *Querier, tableName, groupColumn string, where sq.Sqlizer) (map[string]uint64, error) {
+	groups, err := GroupCountBy(ctx, q, tableName, []string{groupColumn}, where)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]uint64, len(groups))
+	for _, g := range groups {
+		counts[g.Values[0]] = g.Count
+	}
+	return counts, nil
+}
+
+// GroupCountRow is a group of rows sharing the same values in the group
+// columns, see GroupCountBy.
+type GroupCountRow struct {
+	Values []string
+	Count  uint64
+}
+
+// GroupCountBy is the multi-column variant of GroupCount. Groups are returned
+// ordered by their values.
+func GroupCountBy(ctx context.Context, q *Querier, tableName string, groupColumns []string, where sq.Sqlizer) ([]GroupCountRow, error) {
+	if len(groupColumns) == 0 {
+		return nil, wrapErr(fmt.Errorf("group count requires at least one group column"))
+	}
+
+	cols := make([]string, 0, len(groupColumns)+1)
+	for _, col := range groupColumns {
+		cols = append(cols, fmt.Sprintf("COALESCE((%s)::text, '')", col))
+	}
+	cols = append(cols, "COUNT(*)")
+
+	query := q.SQL.Select(cols...).From(tableName).GroupBy(groupColumns...).OrderBy(groupColumns...)
+	if where != nil {
+		query = query.Where(where)
+	}
+
+	rows, err := q.QueryRows(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []GroupCountRow
+	for rows.Next() {
+		g := GroupCountRow{Values: make([]string, len(groupColumns))}
+		dest := make([]interface{}, 0, len(cols))
+		for i := range g.Values {
+			dest = append(dest, &g.Values[i])
+		}
+		dest = append(dest, &g.Count)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, wrapErr(err)
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr(err)
+	}
+	return groups, nil
+}
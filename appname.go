@@ -0,0 +1,71 @@
+package pgkit
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type appNameCtxKey struct{}
+
+// WithApplicationName returns a context carrying the application_name the
+// statements run with it are reported with in pg_stat_activity and the
+// Postgres logs, ie. to tell a background job or an endpoint apart from the
+// rest of the service:
+//
+//	ctx = pgkit.WithApplicationName(ctx, "myapp/billing-job")
+//
+// Within transactions opened by DB.BeginFunc or DB.BeginRequest, the name is
+// set once at the start of the transaction. Outside of a transaction, each
+// statement runs with the name in an implicit transaction of its own, see
+// Querier.WithSettings. The name is local to the transaction, so it never
+// leaks onto other users of the pooled connections.
+func WithApplicationName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, appNameCtxKey{}, name)
+}
+
+// ApplicationName returns the application_name of the context set by
+// WithApplicationName.
+func ApplicationName(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(appNameCtxKey{}).(string)
+	return name, ok && name != ""
+}
+
+func hasApplicationName(ctx context.Context) bool {
+	_, ok := ApplicationName(ctx)
+	return ok
+}
+
+// statementSettings returns the settings of the statements run by the querier
+// outside of a transaction, which are its settings along with the
+// application_name of the context, if any.
+func (q *Querier) statementSettings(ctx context.Context) *settings {
+	name, ok := ApplicationName(ctx)
+	if !ok {
+		return q.settings
+	}
+	if q.settings != nil {
+		if _, ok := q.settings.values["application_name"]; ok {
+			return q.settings
+		}
+	}
+
+	values := map[string]string{"application_name": name}
+	if q.settings != nil {
+		for k, v := range q.settings.values {
+			values[k] = v
+		}
+	}
+	return newSettings(values)
+}
+
+// setApplicationName sets the application_name of the context, if any, in the
+// transaction.
+func setApplicationName(ctx context.Context, tx pgx.Tx) error {
+	name, ok := ApplicationName(ctx)
+	if !ok {
+		return nil
+	}
+	_, err := tx.Exec(ctx, "SELECT set_config('application_name', $1, true)", name)
+	return err
+}
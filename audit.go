@@ -0,0 +1,149 @@
+package pgkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// AuditTableName is the table audit entries are written to by Querier.Audit.
+// The expected schema is:
+//
+//	CREATE TABLE audit_log (
+//	  id BIGSERIAL PRIMARY KEY,
+//	  table_name VARCHAR NOT NULL,
+//	  record_id VARCHAR NOT NULL,
+//	  action VARCHAR NOT NULL,
+//	  actor VARCHAR,
+//	  before JSONB,
+//	  after JSONB,
+//	  diff JSONB,
+//	  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+//	);
+var AuditTableName = "audit_log"
+
+type AuditAction string
+
+const (
+	AuditInsert AuditAction = "insert"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// AuditEntry describes a change to a record. Before and After are records
+// mapped with the `db:""` struct tags, see Map. Before is nil for inserts and
+// After is nil for deletes.
+type AuditEntry struct {
+	TableName string
+	RecordID  interface{}
+	Action    AuditAction
+	Before    interface{}
+	After     interface{}
+}
+
+type auditActorCtxKey struct{}
+
+// WithAuditActor returns a context carrying the actor, ie. a user id, which
+// is recorded by Querier.Audit.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorCtxKey{}, actor)
+}
+
+// AuditActor returns the actor of the context set by WithAuditActor.
+func AuditActor(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(auditActorCtxKey{}).(string)
+	return actor, ok
+}
+
+// Audit records the before/after images of a change, the diff of changed
+// columns and the actor from the context into AuditTableName. Call it with a
+// transaction querier (see DB.TxQuery) to record the entry atomically with
+// the change itself.
+func (q *Querier) Audit(ctx context.Context, entry AuditEntry) error {
+	before, err := auditImage(entry.Before)
+	if err != nil {
+		return wrapErr(fmt.Errorf("audit before image: %w", err))
+	}
+	after, err := auditImage(entry.After)
+	if err != nil {
+		return wrapErr(fmt.Errorf("audit after image: %w", err))
+	}
+
+	var actor *string
+	if v, ok := AuditActor(ctx); ok {
+		actor = &v
+	}
+
+	images := []map[string]interface{}{before, after, auditDiff(before, after)}
+	values := make([]interface{}, len(images))
+	for i, image := range images {
+		if values[i], err = auditJSON(image); err != nil {
+			return wrapErr(fmt.Errorf("audit image: %w", err))
+		}
+	}
+
+	insert := q.SQL.Insert(AuditTableName).
+		Columns("table_name", "record_id", "action", "actor", "before", "after", "diff").
+		Values(entry.TableName, fmt.Sprintf("%v", entry.RecordID), string(entry.Action), actor, values[0], values[1], values[2])
+
+	_, err = q.Exec(ctx, insert)
+	return err
+}
+
+// auditImage maps a record to its column values. Columns which would use the
+// database DEFAULT are recorded as null.
+func auditImage(record interface{}) (map[string]interface{}, error) {
+	if record == nil {
+		return nil, nil
+	}
+
+	cols, vals, err := MapWithOptions(record, &MapOptions{IncludeZeroed: true, IncludeNil: true})
+	if err != nil {
+		return nil, err
+	}
+
+	image := make(map[string]interface{}, len(cols))
+	for i, col := range cols {
+		if _, ok := vals[i].(sq.Sqlizer); ok {
+			image[col] = nil
+			continue
+		}
+		image[col] = vals[i]
+	}
+	return image, nil
+}
+
+func auditDiff(before, after map[string]interface{}) map[string]interface{} {
+	if before == nil || after == nil {
+		return nil
+	}
+
+	diff := map[string]interface{}{}
+	for col, newValue := range after {
+		oldValue := before[col]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			diff[col] = map[string]interface{}{"old": oldValue, "new": newValue}
+		}
+	}
+	for col, oldValue := range before {
+		if _, ok := after[col]; !ok {
+			diff[col] = map[string]interface{}{"old": oldValue, "new": nil}
+		}
+	}
+	return diff
+}
+
+// auditJSON encodes v for a JSONB column, keeping nil as NULL.
+func auditJSON(v map[string]interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,128 @@
+package pgkit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// MaxQueryParams is the max number of bind parameters of a single statement
+// supported by the Postgres protocol.
+const MaxQueryParams = 65535
+
+// ErrTooManyParams is returned for statements with more bind parameters than
+// MaxQueryParams.
+var ErrTooManyParams = errors.New("too many bind parameters")
+
+var (
+	// BatchMaxStatements is the max number of statements BatchExec and
+	// BatchQuery send in one batch, larger batches are split into chunks.
+	BatchMaxStatements = 1000
+	// BatchMaxParams is the max total number of bind parameters BatchExec and
+	// BatchQuery send in one batch, larger batches are split into chunks.
+	BatchMaxParams = MaxQueryParams
+)
+
+// batches resolves the queries and queues them into batches of at most
+// BatchMaxStatements statements and BatchMaxParams parameters.
+//
+// Note a batch sent outside of a transaction runs in an implicit transaction,
+// so chunks are committed independently. Run large batches within a
+// transaction to apply them atomically.
+func batches(queries Queries, tagged func(sql string) string) ([]*pgx.Batch, error) {
+	var (
+		batchList []*pgx.Batch
+		batch     = getBatch()
+		params    int
+	)
+	for _, query := range queries {
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		if len(args) > MaxQueryParams {
+			return nil, fmt.Errorf("pgkit: statement has %d bind parameters, over the limit of %d, split it into smaller statements: %w", len(args), MaxQueryParams, ErrTooManyParams)
+		}
+
+		if batch.Len() > 0 && (batch.Len() >= BatchMaxStatements || params+len(args) > BatchMaxParams) {
+			batchList = append(batchList, batch)
+			batch, params = getBatch(), 0
+		}
+		batch.Queue(tagged(sql), args...)
+		params += len(args)
+	}
+	return append(batchList, batch), nil
+}
+
+// batchPool reuses the batches of BatchExec, so the queued queries slices of
+// large batches, ie. syncing many rows, are not reallocated on every call.
+var batchPool = sync.Pool{
+	New: func() interface{} { return &pgx.Batch{} },
+}
+
+func getBatch() *pgx.Batch {
+	return batchPool.Get().(*pgx.Batch)
+}
+
+// putBatches returns the batches to the pool. They must not be used after,
+// ie. their results must be closed.
+func putBatches(batchList []*pgx.Batch) {
+	for _, batch := range batchList {
+		// drop the queries, so their args can be collected
+		clear(batch.QueuedQueries)
+		batch.QueuedQueries = batch.QueuedQueries[:0]
+		batchPool.Put(batch)
+	}
+}
+
+// chunkedBatchResults reads the results of batches sent one after another,
+// sending the next batch once all results of the current one were read.
+type chunkedBatchResults struct {
+	send    func(batch *pgx.Batch) pgx.BatchResults
+	batches []*pgx.Batch
+	current pgx.BatchResults
+	read    int
+	err     error
+}
+
+func (r *chunkedBatchResults) next() pgx.BatchResults {
+	if r.current != nil && r.read == r.batches[0].Len() && len(r.batches) > 1 {
+		if err := r.current.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+		r.batches = r.batches[1:]
+		r.current = nil
+	}
+	if r.current == nil {
+		r.current = r.send(r.batches[0])
+		r.read = 0
+	}
+	r.read++
+	return r.current
+}
+
+func (r *chunkedBatchResults) Exec() (pgconn.CommandTag, error) {
+	return r.next().Exec()
+}
+
+func (r *chunkedBatchResults) Query() (pgx.Rows, error) {
+	return r.next().Query()
+}
+
+func (r *chunkedBatchResults) QueryRow() pgx.Row {
+	return r.next().QueryRow()
+}
+
+// Close closes the current batch results. Batches not sent yet are discarded.
+func (r *chunkedBatchResults) Close() error {
+	if r.current != nil {
+		if err := r.current.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+		r.current = nil
+	}
+	return r.err
+}
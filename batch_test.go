@@ -0,0 +1,31 @@
+package pgkit
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchesReuse(t *testing.T) {
+	queries := Queries{
+		sq.Expr("INSERT INTO accounts (name) VALUES (?)", "a"),
+		sq.Expr("INSERT INTO accounts (name) VALUES (?)", "b"),
+	}
+	noTag := func(sql string) string { return sql }
+
+	batchList, err := batches(queries, noTag)
+	require.NoError(t, err)
+	require.Len(t, batchList, 1)
+	assert.Equal(t, 2, batchList[0].Len())
+
+	putBatches(batchList)
+	assert.Equal(t, 0, batchList[0].Len())
+
+	batchList, err = batches(queries[:1], noTag)
+	require.NoError(t, err)
+	require.Len(t, batchList, 1)
+	assert.Equal(t, 1, batchList[0].Len())
+	assert.Equal(t, []interface{}{"a"}, batchList[0].QueuedQueries[0].Arguments)
+}
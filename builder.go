@@ -3,12 +3,22 @@ package pgkit
 import (
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 )
 
 type StatementBuilder struct {
 	sq.StatementBuilderType
+
+	// QuoteIdentifiers quotes the table and column names of the statements
+	// built from records, so tables and columns named with reserved words,
+	// ie. "order" or "user", produce valid SQL. Quoted names are case
+	// sensitive, so they must match the names stored in the catalog.
+	QuoteIdentifiers bool
 }
 
 func (s *StatementBuilder) InsertRecord(record interface{}, optTableName ...string) InsertBuilder {
@@ -20,7 +30,7 @@ func (s *StatementBuilder) InsertRecord(record interface{}, optTableName ...stri
 		return InsertBuilder{InsertBuilder: insert, err: wrapErr(err)}
 	}
 
-	return InsertBuilder{InsertBuilder: insert.Into(tableName).Columns(cols...).Values(vals...)}
+	return InsertBuilder{InsertBuilder: insert.Into(s.ident(tableName)).Columns(quoteIdents(cols)...).Values(vals...)}
 }
 
 func (s StatementBuilder) InsertRecords(recordsSlice interface{}, optTableName ...string) InsertBuilder {
@@ -54,13 +64,150 @@ func (s StatementBuilder) InsertRecords(recordsSlice interface{}, optTableName .
 		}
 
 		if i == 0 {
-			insert = insert.Columns(cols...).Values(vals...)
+			if params := len(cols) * v.Len(); params > MaxQueryParams {
+				return InsertBuilder{InsertBuilder: insert, err: fmt.Errorf("pgkit: inserting %d records of %d columns needs %d bind parameters, over the limit of %d, use InsertRecordsChunks instead: %w", v.Len(), len(cols), params, MaxQueryParams, ErrTooManyParams)}
+			}
+			insert = insert.Columns(s.idents(cols)...).Values(vals...)
 		} else {
 			insert = insert.Values(vals...)
 		}
 	}
 
-	return InsertBuilder{InsertBuilder: insert.Into(tableName)}
+	return InsertBuilder{InsertBuilder: insert.Into(s.ident(tableName))}
+}
+
+// InsertRecordsChunks is like InsertRecords, but splits the records into as
+// many INSERT statements as needed to stay within the bind parameter limit of
+// Postgres, see MaxQueryParams. Run the statements with BatchExec, within a
+// transaction to insert the records atomically.
+func (s StatementBuilder) InsertRecordsChunks(recordsSlice interface{}, optTableName ...string) Queries {
+	v := reflect.ValueOf(recordsSlice)
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		return Queries{s.InsertRecords(recordsSlice, optTableName...)}
+	}
+
+	cols, _, err := Map(v.Index(0).Interface())
+	if err != nil {
+		return Queries{InsertBuilder{InsertBuilder: sq.InsertBuilder(s.StatementBuilderType), err: wrapErr(err)}}
+	}
+	size := MaxQueryParams / max(len(cols), 1)
+
+	var queries Queries
+	for i := 0; i < v.Len(); i += size {
+		queries = append(queries, s.InsertRecords(v.Slice(i, min(i+size, v.Len())).Interface(), optTableName...))
+	}
+	return queries
+}
+
+// UpsertRecords is like InsertRecords, but updates the rows which conflict
+// with the records on the conflict columns, ie. their primary or a unique
+// key, with `ON CONFLICT (<columns>) DO UPDATE`. All mapped columns other than
+// the conflict columns are updated. recordsSlice may also be a single record.
+// See InsertBuilder.ReturningCreated to tell inserted rows from updated ones.
+func (s StatementBuilder) UpsertRecords(recordsSlice interface{}, conflictColumns []string, optTableName ...string) InsertBuilder {
+	v := reflect.ValueOf(recordsSlice)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return InsertBuilder{InsertBuilder: sq.InsertBuilder(s.StatementBuilderType), err: wrapErr(fmt.Errorf("records must not be nil"))}
+	}
+	if v.Kind() != reflect.Slice {
+		v = reflect.Append(reflect.MakeSlice(reflect.SliceOf(v.Type()), 0, 1), v)
+	}
+	for i := 0; i < v.Len(); i++ {
+		if e := v.Index(i); (e.Kind() == reflect.Ptr || e.Kind() == reflect.Interface) && e.IsNil() {
+			return InsertBuilder{InsertBuilder: sq.InsertBuilder(s.StatementBuilderType), err: wrapErr(fmt.Errorf("record %d must not be nil", i))}
+		}
+	}
+	insert := s.InsertRecords(v.Interface(), optTableName...)
+	if insert.err != nil {
+		return insert
+	}
+	if len(conflictColumns) == 0 {
+		return InsertBuilder{InsertBuilder: insert.InsertBuilder, err: wrapErr(fmt.Errorf("upsert requires conflict columns"))}
+	}
+
+	cols, _, err := Map(v.Index(0).Interface())
+	if err != nil {
+		return InsertBuilder{InsertBuilder: insert.InsertBuilder, err: wrapErr(err)}
+	}
+
+	conflict := make(map[string]bool, len(conflictColumns))
+	for _, col := range conflictColumns {
+		conflict[col] = true
+	}
+	var set []string
+	for _, col := range cols {
+		if !conflict[col] {
+			set = append(set, s.ident(col)+" = EXCLUDED."+s.ident(col))
+		}
+	}
+
+	suffix := "ON CONFLICT (" + strings.Join(s.idents(conflictColumns), ", ") + ") "
+	if len(set) == 0 {
+		suffix += "DO NOTHING"
+	} else {
+		suffix += "DO UPDATE SET " + strings.Join(set, ", ")
+	}
+	return InsertBuilder{InsertBuilder: insert.Suffix(suffix)}
+}
+
+// InsertMap builds an INSERT of the column values into the table. Columns are
+// ordered by name, so the generated SQL is deterministic. Column names are
+// always quoted, so they keep their case and can't inject SQL. Use OmitNil to
+// leave out the nil values, letting the database use the column defaults.
+func (s StatementBuilder) InsertMap(tableName string, values map[string]interface{}) InsertBuilder {
+	insert := sq.InsertBuilder(s.StatementBuilderType)
+	if len(values) == 0 {
+		return InsertBuilder{InsertBuilder: insert, err: wrapErr(fmt.Errorf("insert map is empty"))}
+	}
+
+	cols, vals := sortedMap(values)
+	return InsertBuilder{InsertBuilder: insert.Into(s.ident(tableName)).Columns(quoteIdents(cols)...).Values(vals...)}
+}
+
+// UpdateMap builds an UPDATE of the column values of the rows of the table
+// matching whereExpr, see InsertMap.
+func (s StatementBuilder) UpdateMap(tableName string, values map[string]interface{}, whereExpr sq.Sqlizer) UpdateBuilder {
+	update := sq.UpdateBuilder(s.StatementBuilderType)
+	if len(values) == 0 {
+		return UpdateBuilder{UpdateBuilder: update, err: wrapErr(fmt.Errorf("update map is empty"))}
+	}
+
+	update = update.Table(s.ident(tableName))
+	cols, vals := sortedMap(values)
+	for i, col := range cols {
+		update = update.Set(quoteIdent(col), vals[i])
+	}
+	return UpdateBuilder{UpdateBuilder: update.Where(whereExpr)}
+}
+
+// OmitNil returns a copy of the column values without the nil values,
+// including nil pointers, see InsertMap.
+func OmitNil(values map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(values))
+	for col, val := range values {
+		if val == nil {
+			continue
+		}
+		if rv := reflect.ValueOf(val); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			continue
+		}
+		m[col] = val
+	}
+	return m
+}
+
+func sortedMap(values map[string]interface{}) ([]string, []interface{}) {
+	cols := make([]string, 0, len(values))
+	for col := range values {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	vals := make([]interface{}, len(cols))
+	for i, col := range cols {
+		vals[i] = values[col]
+	}
+	return cols, vals
 }
 
 func (s StatementBuilder) UpdateRecord(record interface{}, whereExpr sq.Eq, optTableName ...string) UpdateBuilder {
@@ -84,12 +231,250 @@ func (s StatementBuilder) UpdateRecordColumns(record interface{}, whereExpr sq.E
 		filter = filterCols
 	}
 
-	valMap, err := createMap(cols, vals, filter)
+	valMap, err := createMap(s.idents(cols), vals, s.idents(filter))
 	if err != nil {
 		return UpdateBuilder{UpdateBuilder: update, err: wrapErr(err)}
 	}
 
-	return UpdateBuilder{UpdateBuilder: update.Table(tableName).SetMap(valMap).Where(whereExpr)}
+	if s.QuoteIdentifiers {
+		where := make(sq.Eq, len(whereExpr))
+		for col, val := range whereExpr {
+			where[s.ident(col)] = val
+		}
+		whereExpr = where
+	}
+
+	return UpdateBuilder{UpdateBuilder: update.Table(s.ident(tableName)).SetMap(valMap).Where(whereExpr)}
+}
+
+// Increment builds an atomic `UPDATE <table> SET <column> = <column> + delta`
+// statement for the rows matching whereExpr, avoiding read-modify-write races
+// on counters. Chain further columns as needed, ie. to bump a timestamp:
+//
+//	db.SQL.Increment("stats", "hits", 1, sq.Eq{"id": id}).Set("updated_at", sq.Expr("NOW()"))
+func (s StatementBuilder) Increment(tableName, column string, delta interface{}, whereExpr sq.Sqlizer) UpdateBuilder {
+	update := sq.UpdateBuilder(s.StatementBuilderType)
+	column = s.ident(column)
+	return UpdateBuilder{UpdateBuilder: update.Table(s.ident(tableName)).Set(column, sq.Expr(column+" + ?", delta)).Where(whereExpr)}
+}
+
+// Decrement is like Increment but subtracts delta from the column.
+func (s StatementBuilder) Decrement(tableName, column string, delta interface{}, whereExpr sq.Sqlizer) UpdateBuilder {
+	update := sq.UpdateBuilder(s.StatementBuilderType)
+	column = s.ident(column)
+	return UpdateBuilder{UpdateBuilder: update.Table(s.ident(tableName)).Set(column, sq.Expr(column+" - ?", delta)).Where(whereExpr)}
+}
+
+// Touch builds an `UPDATE <table> SET updated_at = NOW()` statement for the
+// rows matching whereExpr, without rewriting the rest of the row. When columns
+// are given, those are bumped instead of updated_at.
+func (s StatementBuilder) Touch(tableName string, whereExpr sq.Sqlizer, columns ...string) UpdateBuilder {
+	if len(columns) == 0 {
+		columns = []string{"updated_at"}
+	}
+
+	update := sq.UpdateBuilder(s.StatementBuilderType).Table(s.ident(tableName))
+	for _, column := range columns {
+		update = update.Set(s.ident(column), sq.Expr("NOW()"))
+	}
+	return UpdateBuilder{UpdateBuilder: update.Where(whereExpr)}
+}
+
+// SelectFromRecord builds a SELECT of the columns of the record struct, see
+// Columns, from its table. Unlike the plain Select, the returned builder
+// carries the errors of composing the query, ie. a record which isn't a
+// struct or an invalid condition, reported by Err and returned by the querier
+// before running the query.
+func (s StatementBuilder) SelectFromRecord(record interface{}, optTableName ...string) SelectBuilder {
+	tableName := getTableName(record, optTableName...)
+	sel := sq.SelectBuilder(s.StatementBuilderType)
+
+	fields := recordColumns(record)
+	if len(fields) == 0 {
+		return SelectBuilder{SelectBuilder: sel, err: wrapErr(fmt.Errorf("record %T has no columns", record))}
+	}
+	if tableName == "" {
+		return SelectBuilder{SelectBuilder: sel, err: wrapErr(fmt.Errorf("record %T has no table name", record))}
+	}
+
+	cols := make([]string, len(fields))
+	for i, fi := range fields {
+		cols[i] = s.ident(fi.Name)
+	}
+	return SelectBuilder{SelectBuilder: sel.Columns(cols...).From(s.ident(tableName))}
+}
+
+// SelectBuilder is a sq.SelectBuilder carrying the errors of composing the
+// query, see StatementBuilder.SelectFromRecord. Its clause methods keep the
+// error, the ones of the embedded sq.SelectBuilder drop it.
+type SelectBuilder struct {
+	sq.SelectBuilder
+	err error
+}
+
+func (b SelectBuilder) Err() error { return b.err }
+
+// ToSql builds the query, returning the error of composing it, if any.
+func (b SelectBuilder) ToSql() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	return b.SelectBuilder.ToSql()
+}
+
+// Where adds the condition, see sq.SelectBuilder.Where. Conditions failing to
+// build are reported by Err.
+func (b SelectBuilder) Where(pred interface{}, args ...interface{}) SelectBuilder {
+	b.checkCond("where", pred)
+	b.SelectBuilder = b.SelectBuilder.Where(pred, args...)
+	return b
+}
+
+// Having adds the condition, see sq.SelectBuilder.Having and Where.
+func (b SelectBuilder) Having(pred interface{}, rest ...interface{}) SelectBuilder {
+	b.checkCond("having", pred)
+	b.SelectBuilder = b.SelectBuilder.Having(pred, rest...)
+	return b
+}
+
+func (b *SelectBuilder) checkCond(clause string, pred interface{}) {
+	if cond, ok := pred.(sq.Sqlizer); ok && b.err == nil {
+		if getErr, ok := cond.(hasErr); ok && getErr.Err() != nil {
+			b.err = getErr.Err()
+		} else if _, _, err := cond.ToSql(); err != nil {
+			b.err = fmt.Errorf("pgkit: invalid %s condition: %w", clause, err)
+		}
+	}
+}
+
+func (b SelectBuilder) PlaceholderFormat(f sq.PlaceholderFormat) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.PlaceholderFormat(f)
+	return b
+}
+
+func (b SelectBuilder) Prefix(sql string, args ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.Prefix(sql, args...)
+	return b
+}
+
+func (b SelectBuilder) PrefixExpr(expr sq.Sqlizer) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.PrefixExpr(expr)
+	return b
+}
+
+func (b SelectBuilder) Distinct() SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.Distinct()
+	return b
+}
+
+func (b SelectBuilder) Options(options ...string) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.Options(options...)
+	return b
+}
+
+func (b SelectBuilder) Columns(columns ...string) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.Columns(columns...)
+	return b
+}
+
+func (b SelectBuilder) Column(column interface{}, args ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.Column(column, args...)
+	return b
+}
+
+func (b SelectBuilder) RemoveColumns() SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.RemoveColumns()
+	return b
+}
+
+func (b SelectBuilder) From(from string) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.From(from)
+	return b
+}
+
+// FromSelect selects from the subquery, see sq.SelectBuilder.FromSelect. The
+// error of composing the subquery is reported by Err.
+func (b SelectBuilder) FromSelect(from SelectBuilder, alias string) SelectBuilder {
+	if b.err == nil {
+		b.err = from.err
+	}
+	b.SelectBuilder = b.SelectBuilder.FromSelect(from.SelectBuilder, alias)
+	return b
+}
+
+func (b SelectBuilder) JoinClause(pred interface{}, args ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.JoinClause(pred, args...)
+	return b
+}
+
+func (b SelectBuilder) Join(join string, rest ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.Join(join, rest...)
+	return b
+}
+
+func (b SelectBuilder) LeftJoin(join string, rest ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.LeftJoin(join, rest...)
+	return b
+}
+
+func (b SelectBuilder) RightJoin(join string, rest ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.RightJoin(join, rest...)
+	return b
+}
+
+func (b SelectBuilder) InnerJoin(join string, rest ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.InnerJoin(join, rest...)
+	return b
+}
+
+func (b SelectBuilder) CrossJoin(join string, rest ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.CrossJoin(join, rest...)
+	return b
+}
+
+func (b SelectBuilder) GroupBy(groupBys ...string) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.GroupBy(groupBys...)
+	return b
+}
+
+func (b SelectBuilder) OrderBy(orderBys ...string) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.OrderBy(orderBys...)
+	return b
+}
+
+func (b SelectBuilder) OrderByClause(pred interface{}, args ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.OrderByClause(pred, args...)
+	return b
+}
+
+func (b SelectBuilder) Limit(limit uint64) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.Limit(limit)
+	return b
+}
+
+func (b SelectBuilder) RemoveLimit() SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.RemoveLimit()
+	return b
+}
+
+func (b SelectBuilder) Offset(offset uint64) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.Offset(offset)
+	return b
+}
+
+func (b SelectBuilder) RemoveOffset() SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.RemoveOffset()
+	return b
+}
+
+func (b SelectBuilder) Suffix(sql string, args ...interface{}) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.Suffix(sql, args...)
+	return b
+}
+
+func (b SelectBuilder) SuffixExpr(expr sq.Sqlizer) SelectBuilder {
+	b.SelectBuilder = b.SelectBuilder.SuffixExpr(expr)
+	return b
 }
 
 type InsertBuilder struct {
@@ -99,6 +484,27 @@ type InsertBuilder struct {
 
 func (b InsertBuilder) Err() error { return b.err }
 
+// Returning adds a `RETURNING <columns>` clause, so only the given columns are
+// sent back instead of the whole row.
+func (b InsertBuilder) Returning(columns ...string) InsertBuilder {
+	b.InsertBuilder = b.InsertBuilder.Suffix(returningClause(columns))
+	return b
+}
+
+// ReturningCreated adds a `RETURNING <columns>, (xmax = 0) AS created` clause
+// to an upsert, see UpsertRecords. created is true for the inserted rows and
+// false for the updated ones, so it can be scanned into a `Created bool
+// db:"created"` field, or counted with Querier.UpsertCounts. Rows skipped by
+// DO NOTHING are not returned.
+func (b InsertBuilder) ReturningCreated(columns ...string) InsertBuilder {
+	clause := "(xmax = 0) AS created"
+	if len(columns) > 0 {
+		clause = strings.Join(columns, ", ") + ", " + clause
+	}
+	b.InsertBuilder = b.InsertBuilder.Suffix("RETURNING " + clause)
+	return b
+}
+
 type UpdateBuilder struct {
 	sq.UpdateBuilder
 	err error
@@ -106,6 +512,57 @@ type UpdateBuilder struct {
 
 func (b UpdateBuilder) Err() error { return b.err }
 
+// Returning adds a `RETURNING <columns>` clause, so only the given columns are
+// sent back instead of the whole row.
+func (b UpdateBuilder) Returning(columns ...string) UpdateBuilder {
+	b.UpdateBuilder = b.UpdateBuilder.Suffix(returningClause(columns))
+	return b
+}
+
+func returningClause(columns []string) string {
+	if len(columns) == 0 {
+		return "RETURNING *"
+	}
+	return "RETURNING " + strings.Join(columns, ", ")
+}
+
+// ident quotes the identifier if QuoteIdentifiers is enabled. Names which
+// aren't plain, optionally qualified, identifiers, ie. expressions, are left
+// as is.
+func (s StatementBuilder) ident(name string) string {
+	if !s.QuoteIdentifiers || !plainIdent.MatchString(name) {
+		return name
+	}
+	return Ident(name).String()
+}
+
+func (s StatementBuilder) idents(names []string) []string {
+	if !s.QuoteIdentifiers || names == nil {
+		return names
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = s.ident(name)
+	}
+	return quoted
+}
+
+// quoteIdent quotes the column name as a single identifier, as given by the
+// keys of InsertMap and UpdateMap, which may come from untrusted input.
+func quoteIdent(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+func quoteIdents(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdent(name)
+	}
+	return quoted
+}
+
+var plainIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)*$`)
+
 func getTableName(record interface{}, optTableName ...string) string {
 	tableName := ""
 	if len(optTableName) > 0 {
@@ -0,0 +1,186 @@
+package pgkit_test
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/goware/pgkit/v2"
+	"github.com/goware/pgkit/v2/pgkittest"
+	"github.com/stretchr/testify/require"
+)
+
+var SQL = &pgkit.StatementBuilder{StatementBuilderType: sq.StatementBuilder.PlaceholderFormat(sq.Dollar)}
+
+func TestIncrement(t *testing.T) {
+	pgkittest.AssertSQL(t,
+		SQL.Increment("stats", "hits", 1, sq.Eq{"id": 7}),
+		"UPDATE stats SET hits = hits + $1 WHERE id = $2", []interface{}{1, 7})
+
+	pgkittest.AssertSQL(t,
+		SQL.Decrement("stats", "hits", 2, sq.Eq{"id": 7}).Set("updated_at", sq.Expr("NOW()")),
+		"UPDATE stats SET hits = hits - $1, updated_at = NOW() WHERE id = $2", []interface{}{2, 7})
+}
+
+func TestTouch(t *testing.T) {
+	pgkittest.AssertSQL(t,
+		SQL.Touch("accounts", sq.Eq{"id": []int64{1, 2}}),
+		"UPDATE accounts SET updated_at = NOW() WHERE id IN ($1,$2)", []interface{}{int64(1), int64(2)})
+
+	pgkittest.AssertSQL(t,
+		SQL.Touch("accounts", sq.Eq{"id": 1}, "updated_at", "cached_at"),
+		"UPDATE accounts SET updated_at = NOW(), cached_at = NOW() WHERE id = $1", []interface{}{1})
+}
+
+func TestReturning(t *testing.T) {
+	pgkittest.AssertSQL(t,
+		SQL.InsertRecord(&struct {
+			Name string `db:"name"`
+		}{Name: "joe"}, "accounts").Returning("id", "created_at"),
+		`INSERT INTO accounts ("name") VALUES ($1) RETURNING id, created_at`, []interface{}{"joe"})
+
+	pgkittest.AssertSQL(t,
+		SQL.UpdateRecordColumns(&struct {
+			Name string `db:"name"`
+		}{Name: "joe"}, sq.Eq{"id": 1}, nil, "accounts").Returning(),
+		"UPDATE accounts SET name = $1 WHERE id = $2 RETURNING *", []interface{}{"joe", 1})
+}
+
+func TestInsertRecordsChunks(t *testing.T) {
+	type row struct {
+		A int `db:"a"`
+		B int `db:"b"`
+	}
+	rows := make([]row, pgkit.MaxQueryParams/2+10)
+
+	err := SQL.InsertRecords(rows, "t").Err()
+	require.ErrorIs(t, err, pgkit.ErrTooManyParams)
+
+	queries := SQL.InsertRecordsChunks(rows, "t")
+	require.Len(t, queries, 2)
+	for _, query := range queries {
+		_, args, err := query.ToSql()
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(args), pgkit.MaxQueryParams)
+	}
+
+	_, args, err := queries[1].ToSql()
+	require.NoError(t, err)
+	require.Len(t, args, 20)
+}
+
+func TestQuoteIdentifiers(t *testing.T) {
+	type order struct {
+		ID   int64  `db:"id,omitempty"`
+		User string `db:"user"`
+	}
+	quoted := &pgkit.StatementBuilder{StatementBuilderType: SQL.StatementBuilderType, QuoteIdentifiers: true}
+
+	pgkittest.AssertSQL(t,
+		quoted.InsertRecord(&order{User: "joe"}, "public.order"),
+		`INSERT INTO "public"."order" ("user") VALUES ($1)`, []interface{}{"joe"})
+
+	pgkittest.AssertSQL(t,
+		quoted.InsertRecords([]order{{User: "joe"}, {User: "ann"}}, "order"),
+		`INSERT INTO "order" ("user") VALUES ($1),($2)`, []interface{}{"joe", "ann"})
+
+	pgkittest.AssertSQL(t,
+		quoted.UpdateRecordColumns(&order{ID: 1, User: "joe"}, sq.Eq{"id": 1}, []string{"user"}, "order"),
+		`UPDATE "order" SET "user" = $1 WHERE "id" = $2`, []interface{}{"joe", 1})
+
+	pgkittest.AssertSQL(t,
+		quoted.Increment("order", "count", 1, sq.Eq{"lower(name)": "joe"}),
+		`UPDATE "order" SET "count" = "count" + $1 WHERE lower(name) = $2`, []interface{}{1, "joe"})
+}
+
+func TestUpsertRecords(t *testing.T) {
+	type account struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	pgkittest.AssertSQL(t,
+		SQL.UpsertRecords([]account{{ID: 1, Name: "joe"}, {ID: 2, Name: "ann"}}, []string{"id"}, "accounts").ReturningCreated("id"),
+		"INSERT INTO accounts (id,name) VALUES ($1,$2),($3,$4) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name RETURNING id, (xmax = 0) AS created",
+		[]interface{}{int64(1), "joe", int64(2), "ann"})
+
+	pgkittest.AssertSQL(t,
+		SQL.UpsertRecords(&account{ID: 1, Name: "joe"}, []string{"id", "name"}, "accounts"),
+		"INSERT INTO accounts (id,name) VALUES ($1,$2) ON CONFLICT (id, name) DO NOTHING",
+		[]interface{}{int64(1), "joe"})
+
+	require.Error(t, SQL.UpsertRecords(&account{ID: 1}, nil, "accounts").Err())
+	require.Error(t, SQL.UpsertRecords(nil, []string{"id"}, "accounts").Err())
+	require.Error(t, SQL.UpsertRecords([]account{}, []string{"id"}, "accounts").Err())
+	require.Error(t, SQL.UpsertRecords((*account)(nil), []string{"id"}, "accounts").Err())
+	require.Error(t, SQL.UpsertRecords([]*account{{ID: 1}, nil}, []string{"id"}, "accounts").Err())
+}
+
+func TestInsertMap(t *testing.T) {
+	values := map[string]interface{}{"name": "joe", "Email": "joe@example.com", "note": nil, "ptr": (*string)(nil)}
+
+	pgkittest.AssertSQL(t,
+		SQL.InsertMap("accounts", pgkit.OmitNil(values)),
+		`INSERT INTO accounts ("Email","name") VALUES ($1,$2)`, []interface{}{"joe@example.com", "joe"})
+
+	pgkittest.AssertSQL(t,
+		SQL.UpdateMap("accounts", map[string]interface{}{"name": "joe", "disabled": true}, sq.Eq{"id": 1}),
+		`UPDATE accounts SET "disabled" = $1, "name" = $2 WHERE id = $3`, []interface{}{true, "joe", 1})
+
+	quoted := &pgkit.StatementBuilder{StatementBuilderType: SQL.StatementBuilderType, QuoteIdentifiers: true}
+	pgkittest.AssertSQL(t,
+		quoted.InsertMap("accounts", map[string]interface{}{"Email": "joe@example.com"}),
+		`INSERT INTO "accounts" ("Email") VALUES ($1)`, []interface{}{"joe@example.com"})
+
+	// keys are quoted as a single column name
+	pgkittest.AssertSQL(t,
+		SQL.InsertMap("accounts", map[string]interface{}{`x") VALUES (1); --`: 1, "a.b": 2}),
+		`INSERT INTO accounts ("a.b","x"") VALUES (1); --") VALUES ($1,$2)`, []interface{}{2, 1})
+
+	require.Error(t, SQL.InsertMap("accounts", nil).Err())
+}
+
+type selectRecord struct {
+	ID      int64  `db:"id,omitempty"`
+	Name    string `db:"name"`
+	Total   int64  `db:"total,readonly"`
+	Ignored string
+}
+
+func (selectRecord) DBTableName() string { return "invoices" }
+
+func TestSelectFromRecord(t *testing.T) {
+	pgkittest.AssertSQL(t,
+		SQL.SelectFromRecord(&selectRecord{}).Where(sq.Eq{"id": 1}).OrderBy("name").Limit(1),
+		"SELECT id, name, total FROM invoices WHERE id = $1 ORDER BY name LIMIT 1", []interface{}{1})
+
+	require.Error(t, SQL.SelectFromRecord(1, "invoices").Err())
+	require.Error(t, SQL.SelectFromRecord(&struct {
+		Name string `db:"name"`
+	}{}).Err())
+
+	// the error of the condition is kept through the chain
+	err := SQL.SelectFromRecord(&selectRecord{}).Where(sq.Eq{"id": 1}).Where(SQL.InsertRecords(nil)).Limit(1).Err()
+	require.ErrorContains(t, err, "records must be a slice type")
+
+	err = SQL.SelectFromRecord(&selectRecord{}).Where(pgkit.RawQuery("id = ? AND name = ?").Build(1)).Err()
+	require.ErrorContains(t, err, "expecting 2 args")
+
+	err = SQL.SelectFromRecord(&selectRecord{}).Where(sq.And{pgkit.RawQuery("id = ?").Build()}).Err()
+	require.ErrorContains(t, err, "invalid where condition")
+
+	// every clause keeps the error, and ToSql returns it
+	sel := SQL.SelectFromRecord(&selectRecord{}).Where(SQL.InsertRecords(nil)).
+		Prefix("WITH t AS (SELECT 1)").Distinct().From("invoices i").
+		LeftJoin("a ON true").RightJoin("b ON true").InnerJoin("c ON true").CrossJoin("d").
+		GroupBy("id").Having("count(*) > ?", 1).PlaceholderFormat(sq.Dollar)
+	require.ErrorContains(t, sel.Err(), "records must be a slice type")
+	_, _, err = sel.ToSql()
+	require.ErrorContains(t, err, "records must be a slice type")
+
+	err = SQL.SelectFromRecord(&selectRecord{}).Having(sq.And{pgkit.RawQuery("id = ?").Build()}).Err()
+	require.ErrorContains(t, err, "invalid having condition")
+
+	pgkittest.AssertSQL(t,
+		SQL.SelectFromRecord(&selectRecord{}).Distinct().LeftJoin("payments p ON p.invoice_id = id").GroupBy("id").Having("count(*) > ?", 1),
+		"SELECT DISTINCT id, name, total FROM invoices LEFT JOIN payments p ON p.invoice_id = id GROUP BY id HAVING count(*) > $1", []interface{}{1})
+}
@@ -0,0 +1,189 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// RowError is the error of a single record of a bulk insert, at Index in the
+// records slice.
+type RowError struct {
+	Index int
+	Err   error
+}
+
+// BulkInsertError is returned by the bulk inserts when some records failed,
+// while the other records were inserted.
+type BulkInsertError struct {
+	Rows []RowError
+}
+
+func (e *BulkInsertError) Error() string {
+	return fmt.Sprintf("pgkit: %d records failed to insert, first at index %d: %v", len(e.Rows), e.Rows[0].Index, e.Rows[0].Err)
+}
+
+func (e *BulkInsertError) Unwrap() []error {
+	errs := make([]error, len(e.Rows))
+	for i, row := range e.Rows {
+		errs[i] = row.Err
+	}
+	return errs
+}
+
+// InsertRecordsSkipping inserts the records with `ON CONFLICT DO NOTHING` in a
+// single statement and returns the indexes of the records skipped due to a
+// conflict. The records are matched to the inserted rows on keyColumn, which
+// must be set on the records and unique among them, ie. an external id. The
+// returned keys are scanned into the Go type of the record keys, so keys of
+// any type, ie. uuids or timestamps, are matched on their value.
+func (q *Querier) InsertRecordsSkipping(ctx context.Context, recordsSlice interface{}, keyColumn string, optTableName ...string) ([]int, error) {
+	v := reflect.ValueOf(recordsSlice)
+	if v.Kind() != reflect.Slice {
+		return nil, wrapErr(fmt.Errorf("records must be a slice type"))
+	}
+	if v.Len() == 0 {
+		return nil, wrapErr(fmt.Errorf("records slice is empty"))
+	}
+
+	keys := make([]interface{}, v.Len())
+	var keyType reflect.Type
+	for i := range keys {
+		key, err := recordKey(v.Index(i).Interface(), keyColumn)
+		if err != nil {
+			return nil, err
+		}
+		if keyType == nil {
+			keyType = reflect.TypeOf(key)
+		} else if reflect.TypeOf(key) != keyType {
+			return nil, wrapErr(fmt.Errorf("record %d has a %q key of type %T, expecting %s", i, keyColumn, key, keyType))
+		}
+		keys[i] = key
+	}
+
+	query := q.SQL.InsertRecords(recordsSlice, optTableName...)
+	query.InsertBuilder = query.Suffix("ON CONFLICT DO NOTHING RETURNING " + q.SQL.ident(keyColumn))
+
+	rows, err := q.QueryRows(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	insertedKeys := make(map[interface{}]bool, len(keys))
+	for rows.Next() {
+		key := reflect.New(keyType)
+		if err := rows.Scan(key.Interface()); err != nil {
+			return nil, wrapErr(err)
+		}
+		insertedKeys[matchKey(key.Elem().Interface())] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapErr(err)
+	}
+
+	var skipped []int
+	for i, key := range keys {
+		if !insertedKeys[matchKey(key)] {
+			skipped = append(skipped, i)
+		}
+	}
+	return skipped, nil
+}
+
+// recordKey returns the value of the key column of the record, dereferenced.
+func recordKey(record interface{}, keyColumn string) (interface{}, error) {
+	cols, vals, err := Map(record)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	for i, col := range cols {
+		if col != keyColumn {
+			continue
+		}
+		if _, ok := vals[i].(sq.Sqlizer); ok {
+			return nil, wrapErr(fmt.Errorf("record has no %q key set", keyColumn))
+		}
+		key := reflect.ValueOf(vals[i])
+		for key.Kind() == reflect.Ptr || key.Kind() == reflect.Interface {
+			if key.IsNil() {
+				return nil, wrapErr(fmt.Errorf("record has a nil %q key", keyColumn))
+			}
+			key = key.Elem()
+		}
+		if !key.IsValid() {
+			return nil, wrapErr(fmt.Errorf("record has a nil %q key", keyColumn))
+		}
+		return key.Interface(), nil
+	}
+	return nil, wrapErr(fmt.Errorf("record has no %q column", keyColumn))
+}
+
+// matchKey returns the value the keys of the records and the inserted rows are
+// matched on. Timestamps are matched at the microsecond precision of Postgres,
+// whatever their location, and keys which aren't comparable, ie. []byte, on
+// their string representation.
+func matchKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case time.Time:
+		return k.UTC().Round(time.Microsecond)
+	case fmt.Stringer:
+		return k.String()
+	}
+	if !reflect.TypeOf(key).Comparable() {
+		return fmt.Sprint(key)
+	}
+	return key
+}
+
+// InsertRecordsEach inserts each record within its own savepoint, so records
+// failing to insert, ie. violating a constraint, are rolled back while the
+// other records are inserted. The failed records are reported in a
+// *BulkInsertError. Without transaction, the records are inserted within a new
+// transaction, committed once all records were tried.
+//
+// It is much slower than a single multi-row INSERT, so prefer InsertRecords or
+// InsertRecordsSkipping when failures aren't expected or are only conflicts.
+func (q *Querier) InsertRecordsEach(ctx context.Context, recordsSlice interface{}, optTableName ...string) error {
+	v := reflect.ValueOf(recordsSlice)
+	if v.Kind() != reflect.Slice {
+		return wrapErr(fmt.Errorf("records must be a slice type"))
+	}
+
+	if q.tx == nil {
+		// the failed records must not roll back the transaction
+		var bulkErr error
+		err := pgx.BeginFunc(ctx, q.pool, func(tx pgx.Tx) error {
+			qq := *q
+			qq.tx = tx
+			bulkErr = qq.InsertRecordsEach(ctx, recordsSlice, optTableName...)
+			if _, ok := bulkErr.(*BulkInsertError); ok {
+				return nil
+			}
+			return bulkErr
+		})
+		if err != nil {
+			return wrapErr(err)
+		}
+		return bulkErr
+	}
+
+	var rowErrs []RowError
+	for i := 0; i < v.Len(); i++ {
+		err := q.Savepoint(ctx, func(q *Querier) error {
+			_, err := q.Exec(ctx, q.SQL.InsertRecord(v.Index(i).Interface(), optTableName...))
+			return err
+		})
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{Index: i, Err: err})
+		}
+	}
+	if len(rowErrs) > 0 {
+		return &BulkInsertError{Rows: rowErrs}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package pgkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordKey(t *testing.T) {
+	type record struct {
+		ID   *int64      `db:"id"`
+		Ref  interface{} `db:"ref"`
+		UUID [16]byte    `db:"uuid"`
+	}
+
+	id := int64(1)
+	key, err := recordKey(&record{ID: &id}, "id")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), key)
+
+	key, err = recordKey(&record{UUID: [16]byte{1}}, "uuid")
+	require.NoError(t, err)
+	assert.Equal(t, [16]byte{1}, key)
+
+	_, err = recordKey(&record{}, "id")
+	assert.Error(t, err)
+	_, err = recordKey(&record{}, "ref")
+	assert.Error(t, err)
+	_, err = recordKey(&record{}, "missing")
+	assert.Error(t, err)
+}
+
+func TestMatchKey(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.FixedZone("CET", 3600))
+	assert.Equal(t, matchKey(ts), matchKey(ts.UTC()))
+	assert.Equal(t, matchKey([16]byte{1}), matchKey([16]byte{1}))
+	assert.Equal(t, "[1 2]", matchKey([]byte{1, 2}))
+}
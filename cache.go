@@ -0,0 +1,362 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type cacheTagsCtxKey struct{}
+
+// WithCacheTags returns a context marking the queries run with it as
+// cacheable by the ResultCache middleware, under the given invalidation tags,
+// ie. the names of the tables they read.
+func WithCacheTags(ctx context.Context, tags ...string) context.Context {
+	return context.WithValue(ctx, cacheTagsCtxKey{}, tags)
+}
+
+func cacheTags(ctx context.Context) ([]string, bool) {
+	tags, ok := ctx.Value(cacheTagsCtxKey{}).([]string)
+	return tags, ok
+}
+
+// ResultCache caches the results of queries, for read heavy reference data
+// which rarely changes. Only the queries run with a context returned by
+// WithCacheTags are cached, see Middleware. Results are keyed by their
+// normalized SQL and args, and expire after the TTL or once one of their tags
+// is invalidated. Queries run within a transaction are never cached, as they
+// may read its uncommitted writes. It is safe for concurrent use.
+type ResultCache struct {
+	// NewTypeMap returns the type maps decoding the cached results, defaults
+	// to pgtype.NewMap. Set it when registering custom types or enums on the
+	// connections, ie. in AfterConnect, so they decode from the cache as they
+	// do from the connections:
+	//
+	//	cache.NewTypeMap = func() *pgtype.Map {
+	//		m := pgtype.NewMap()
+	//		m.RegisterType(statusType) // loaded with conn.LoadType
+	//		return m
+	//	}
+	//
+	// It must be set before the cache is used.
+	NewTypeMap func() *pgtype.Map
+
+	ttl time.Duration
+
+	// typeMaps reuses the type maps decoding cached results, as a type map
+	// can't be used concurrently
+	typeMaps sync.Pool
+
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	tags      map[string]map[string]struct{} // tag -> keys
+	lastSweep time.Time
+}
+
+// NewResultCache returns a result cache whose results expire after the TTL.
+func NewResultCache(ttl time.Duration) *ResultCache {
+	c := &ResultCache{
+		ttl:       ttl,
+		entries:   map[string]*cacheEntry{},
+		tags:      map[string]map[string]struct{}{},
+		lastSweep: time.Now(),
+	}
+	c.typeMaps.New = func() interface{} {
+		if c.NewTypeMap != nil {
+			return c.NewTypeMap()
+		}
+		return pgtype.NewMap()
+	}
+	return c
+}
+
+type cacheEntry struct {
+	fields     []pgconn.FieldDescription
+	values     [][][]byte
+	commandTag pgconn.CommandTag
+	tags       []string
+	expiresAt  time.Time
+}
+
+// Middleware returns the middleware serving the cacheable queries from the
+// cache, see Querier.Use.
+func (c *ResultCache) Middleware() Middleware {
+	return func(next QueryFunc) QueryFunc {
+		return func(ctx context.Context, stmt Statement) (Result, error) {
+			tags, ok := cacheTags(ctx)
+			if !ok || !stmt.Rows || stmt.InTx {
+				return next(ctx, stmt)
+			}
+
+			key := cacheKey(stmt)
+			if entry := c.get(key); entry != nil {
+				return Result{CommandTag: entry.commandTag, Rows: c.rows(entry)}, nil
+			}
+
+			res, err := next(ctx, stmt)
+			if err != nil {
+				return res, err
+			}
+			entry, err := readEntry(res.Rows)
+			if err != nil {
+				return Result{}, err
+			}
+			entry.tags = tags
+			c.set(key, entry)
+			return Result{CommandTag: entry.commandTag, Rows: c.rows(entry)}, nil
+		}
+	}
+}
+
+// Invalidate drops the cached results with any of the tags, ie. after
+// writing to the tables they read.
+func (c *ResultCache) Invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			c.delete(key)
+		}
+	}
+}
+
+// Purge drops all cached results.
+func (c *ResultCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]*cacheEntry{}
+	c.tags = map[string]map[string]struct{}{}
+}
+
+func (c *ResultCache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		c.delete(key)
+		return nil
+	}
+	return entry
+}
+
+func (c *ResultCache) set(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) > c.ttl {
+		// drop the expired results which were never read again
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				c.delete(k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	c.delete(key)
+	entry.expiresAt = now.Add(c.ttl)
+	c.entries[key] = entry
+	for _, tag := range entry.tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = map[string]struct{}{}
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+}
+
+// delete drops the cached result, c.mu must be held.
+func (c *ResultCache) delete(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// cacheKey returns the key of the statement, its SQL without query tag
+// comment nor redundant whitespace, and its args with their types.
+func cacheKey(stmt Statement) string {
+	sql := stmt.SQL
+	if strings.HasPrefix(sql, "/* ") {
+		if i := strings.Index(sql, " */ "); i >= 0 {
+			sql = sql[i+len(" */ "):]
+		}
+	}
+	return normalizeSQL(sql) + "\x00" + fmt.Sprintf("%#v", stmt.Args)
+}
+
+// normalizeSQL collapses the whitespace outside of quoted literals and
+// identifiers.
+func normalizeSQL(sql string) string {
+	var (
+		b     strings.Builder
+		quote rune
+		space bool
+	)
+	b.Grow(len(sql))
+	for _, r := range strings.TrimSpace(sql) {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// readEntry reads all rows into a cache entry and closes them.
+func readEntry(rows pgx.Rows) (*cacheEntry, error) {
+	defer rows.Close()
+
+	entry := &cacheEntry{fields: append([]pgconn.FieldDescription(nil), rows.FieldDescriptions()...)}
+	for rows.Next() {
+		raw := rows.RawValues()
+		values := make([][]byte, len(raw))
+		for i, v := range raw {
+			if v != nil {
+				values[i] = append([]byte{}, v...)
+			}
+		}
+		entry.values = append(entry.values, values)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	entry.commandTag = rows.CommandTag()
+	return entry, nil
+}
+
+func (c *ResultCache) rows(entry *cacheEntry) pgx.Rows {
+	return &cachedRows{entry: entry, typeMaps: &c.typeMaps, typeMap: c.typeMaps.Get().(*pgtype.Map), i: -1}
+}
+
+// cachedRows reads the rows of a cache entry.
+type cachedRows struct {
+	entry    *cacheEntry
+	typeMaps *sync.Pool
+	typeMap  *pgtype.Map
+	i        int
+	closed   bool
+	err      error
+}
+
+func (r *cachedRows) Close() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	r.typeMaps.Put(r.typeMap)
+	r.typeMap = nil
+}
+
+func (r *cachedRows) Err() error {
+	return r.err
+}
+
+func (r *cachedRows) CommandTag() pgconn.CommandTag {
+	return r.entry.commandTag
+}
+
+func (r *cachedRows) FieldDescriptions() []pgconn.FieldDescription {
+	return r.entry.fields
+}
+
+func (r *cachedRows) Next() bool {
+	if r.closed {
+		return false
+	}
+	r.i++
+	if r.i >= len(r.entry.values) {
+		r.Close()
+		return false
+	}
+	return true
+}
+
+func (r *cachedRows) Scan(dest ...interface{}) error {
+	if r.closed {
+		return errors.New("rows is closed")
+	}
+	if len(dest) != len(r.entry.fields) {
+		r.err = fmt.Errorf("number of field descriptions must equal number of destinations, got %d and %d", len(r.entry.fields), len(dest))
+		r.Close()
+		return r.err
+	}
+	for i, d := range dest {
+		if d == nil {
+			continue
+		}
+		fd := r.entry.fields[i]
+		if err := r.typeMap.Scan(fd.DataTypeOID, fd.Format, r.entry.values[r.i][i], d); err != nil {
+			r.err = pgx.ScanArgError{ColumnIndex: i, Err: err}
+			r.Close()
+			return r.err
+		}
+	}
+	return nil
+}
+
+func (r *cachedRows) Values() ([]interface{}, error) {
+	if r.closed {
+		return nil, errors.New("rows is closed")
+	}
+	values := make([]interface{}, len(r.entry.fields))
+	for i, fd := range r.entry.fields {
+		buf := r.entry.values[r.i][i]
+		if buf == nil {
+			continue
+		}
+		if dt, ok := r.typeMap.TypeForOID(fd.DataTypeOID); ok {
+			value, err := dt.Codec.DecodeValue(r.typeMap, fd.DataTypeOID, fd.Format, buf)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		} else if fd.Format == pgx.TextFormatCode {
+			values[i] = string(buf)
+		} else {
+			values[i] = append([]byte{}, buf...)
+		}
+	}
+	return values, nil
+}
+
+func (r *cachedRows) RawValues() [][]byte {
+	return r.entry.values[r.i]
+}
+
+func (r *cachedRows) Conn() *pgx.Conn {
+	return nil
+}
@@ -0,0 +1,34 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheKey(t *testing.T) {
+	a := cacheKey(Statement{SQL: "SELECT *\n\tFROM  accounts WHERE name = 'a  b'", Args: []interface{}{1}})
+	b := cacheKey(Statement{SQL: "/* sync-orders */ SELECT * FROM accounts WHERE name = 'a  b'", Args: []interface{}{1}})
+	assert.Equal(t, a, b)
+
+	assert.NotEqual(t, a, cacheKey(Statement{SQL: "SELECT * FROM accounts WHERE name = 'a b'", Args: []interface{}{1}}))
+	assert.NotEqual(t, a, cacheKey(Statement{SQL: "SELECT * FROM accounts WHERE name = 'a  b'", Args: []interface{}{"1"}}))
+}
+
+func TestResultCacheSkipsTx(t *testing.T) {
+	var calls int
+	next := func(ctx context.Context, stmt Statement) (Result, error) {
+		calls++
+		return Result{}, errors.New("not cached")
+	}
+
+	cache := NewResultCache(time.Minute)
+	ctx := WithCacheTags(context.Background(), "accounts")
+	_, err := cache.Middleware()(next)(ctx, Statement{SQL: "SELECT 1", Rows: true, InTx: true})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, cache.entries)
+}
@@ -0,0 +1,244 @@
+// Package cdc consumes change events from a Postgres logical replication
+// slot using the wal2json output plugin.
+//
+// Changes are read with the SQL level replication functions, so a regular
+// connection is enough, and delivered to a handler in batches of whole
+// transactions. The slot is only advanced once the handler succeeds, which
+// gives at-least-once delivery across restarts.
+//
+// As a lighter-weight alternative, InstallNotifyTrigger and Subscribe deliver
+// the same events through triggers and LISTEN/NOTIFY.
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goware/pgkit/v2"
+)
+
+// Plugin is the logical decoding output plugin used by the consumer.
+const Plugin = "wal2json"
+
+type Op string
+
+const (
+	Insert   Op = "I"
+	Update   Op = "U"
+	Delete   Op = "D"
+	Truncate Op = "T"
+)
+
+// Event is a single row change. For updates and deletes, Old holds the
+// replica identity columns of the row, which is the primary key by default.
+type Event struct {
+	LSN    string
+	Schema string
+	Table  string
+	Op     Op
+	Old    map[string]interface{}
+	New    map[string]interface{}
+	// Truncated is set on the events of Subscribe whose rows were too wide
+	// for a NOTIFY payload, whose Old and New only hold the primary key and
+	// replica identity columns of the rows. Handlers needing the whole rows
+	// read them back by key.
+	Truncated bool
+}
+
+// Handler processes a batch of events. Returning an error stops the consumer
+// without advancing the slot, so the batch is delivered again.
+type Handler func(ctx context.Context, events []Event) error
+
+type Options struct {
+	// Tables limits the changes to the given "schema.table" names. Empty
+	// means all tables.
+	Tables []string
+	// BatchSize is the approximate max number of changes per batch, batches
+	// always contain whole transactions. Default is 500.
+	BatchSize int
+	// PollInterval is the wait between polls when the slot has no changes.
+	// Default is 1s.
+	PollInterval time.Duration
+}
+
+type Consumer struct {
+	db      *pgkit.DB
+	slot    string
+	options Options
+	lastLSN string
+}
+
+func NewConsumer(db *pgkit.DB, slot string, opts *Options) *Consumer {
+	c := &Consumer{db: db, slot: slot}
+	if opts != nil {
+		c.options = *opts
+	}
+	if c.options.BatchSize <= 0 {
+		c.options.BatchSize = 500
+	}
+	if c.options.PollInterval <= 0 {
+		c.options.PollInterval = time.Second
+	}
+	return c
+}
+
+// CreateSlot creates the logical replication slot if it doesn't exist.
+func (c *Consumer) CreateSlot(ctx context.Context) error {
+	q := pgkit.RawQuery(`SELECT pg_create_logical_replication_slot(?, ?)
+		WHERE NOT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = ?)`)
+	_, err := c.db.Query.Exec(ctx, q.Build(c.slot, Plugin, c.slot))
+	if err != nil {
+		return fmt.Errorf("cdc: create slot %q: %w", c.slot, err)
+	}
+	return nil
+}
+
+// DropSlot drops the logical replication slot.
+func (c *Consumer) DropSlot(ctx context.Context) error {
+	_, err := c.db.Query.Exec(ctx, pgkit.RawQuery(`SELECT pg_drop_replication_slot(?)`).Build(c.slot))
+	if err != nil {
+		return fmt.Errorf("cdc: drop slot %q: %w", c.slot, err)
+	}
+	return nil
+}
+
+// LastLSN returns the LSN the slot was last advanced to by this consumer.
+func (c *Consumer) LastLSN() string {
+	return c.lastLSN
+}
+
+// Run polls the slot and delivers changes to the handler until the context
+// is done or the handler fails.
+func (c *Consumer) Run(ctx context.Context, handler Handler) error {
+	for {
+		n, err := c.Poll(ctx, handler)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.options.PollInterval):
+		}
+	}
+}
+
+// Poll delivers a single batch of pending changes to the handler and
+// advances the slot past them. It returns the number of events delivered.
+func (c *Consumer) Poll(ctx context.Context, handler Handler) (int, error) {
+	args := []interface{}{c.slot, c.options.BatchSize, "format-version", "2", "include-transaction", "true"}
+	query := `SELECT lsn::text, data FROM pg_logical_slot_peek_changes(?, NULL, ?, ?, ?, ?, ?`
+	if len(c.options.Tables) > 0 {
+		query += `, ?, ?`
+		args = append(args, "add-tables", strings.Join(c.options.Tables, ","))
+	}
+	query += `)`
+
+	rows, err := c.db.Query.QueryRows(ctx, pgkit.RawQuery(query).Build(args...))
+	if err != nil {
+		return 0, fmt.Errorf("cdc: peek changes: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		events    []Event
+		commitLSN string
+	)
+	for rows.Next() {
+		var lsn, data string
+		if err := rows.Scan(&lsn, &data); err != nil {
+			return 0, fmt.Errorf("cdc: scan change: %w", err)
+		}
+
+		ev, isCommit, err := decodeChange(lsn, []byte(data))
+		if err != nil {
+			return 0, err
+		}
+		if isCommit {
+			commitLSN = lsn
+			continue
+		}
+		if ev != nil {
+			events = append(events, *ev)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("cdc: read changes: %w", err)
+	}
+	rows.Close()
+
+	if commitLSN == "" {
+		return 0, nil
+	}
+
+	if len(events) > 0 {
+		if err := handler(ctx, events); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = c.db.Query.Exec(ctx, pgkit.RawQuery(`SELECT pg_replication_slot_advance(?, ?::pg_lsn)`).Build(c.slot, commitLSN))
+	if err != nil {
+		return 0, fmt.Errorf("cdc: advance slot to %s: %w", commitLSN, err)
+	}
+	c.lastLSN = commitLSN
+
+	return len(events), nil
+}
+
+type wal2jsonColumn struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type wal2jsonChange struct {
+	Action   string           `json:"action"`
+	Schema   string           `json:"schema"`
+	Table    string           `json:"table"`
+	Columns  []wal2jsonColumn `json:"columns"`
+	Identity []wal2jsonColumn `json:"identity"`
+}
+
+// decodeChange decodes a wal2json format-version 2 change. Begin and message
+// records yield a nil event, commit records are reported with isCommit.
+func decodeChange(lsn string, data []byte) (*Event, bool, error) {
+	var change wal2jsonChange
+	if err := json.Unmarshal(data, &change); err != nil {
+		return nil, false, fmt.Errorf("cdc: decode change at %s: %w", lsn, err)
+	}
+
+	switch Op(change.Action) {
+	case Insert, Update, Delete, Truncate:
+	case "C":
+		return nil, true, nil
+	default:
+		return nil, false, nil
+	}
+
+	return &Event{
+		LSN:    lsn,
+		Schema: change.Schema,
+		Table:  change.Table,
+		Op:     Op(change.Action),
+		Old:    columnMap(change.Identity),
+		New:    columnMap(change.Columns),
+	}, false, nil
+}
+
+func columnMap(cols []wal2jsonColumn) map[string]interface{} {
+	if len(cols) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		m[col.Name] = col.Value
+	}
+	return m
+}
@@ -0,0 +1,47 @@
+package cdc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeChange(t *testing.T) {
+	ev, isCommit, err := decodeChange("0/16B3748", []byte(`{"action":"U","schema":"public","table":"accounts","columns":[{"name":"id","type":"integer","value":1},{"name":"name","type":"character varying","value":"joe"}],"identity":[{"name":"id","type":"integer","value":1}]}`))
+	require.NoError(t, err)
+	assert.False(t, isCommit)
+	require.NotNil(t, ev)
+	assert.Equal(t, Update, ev.Op)
+	assert.Equal(t, "accounts", ev.Table)
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "name": "joe"}, ev.New)
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, ev.Old)
+
+	ev, isCommit, err = decodeChange("0/16B3750", []byte(`{"action":"C"}`))
+	require.NoError(t, err)
+	assert.True(t, isCommit)
+	assert.Nil(t, ev)
+
+	ev, isCommit, err = decodeChange("0/16B3740", []byte(`{"action":"B"}`))
+	require.NoError(t, err)
+	assert.False(t, isCommit)
+	assert.Nil(t, ev)
+
+	_, _, err = decodeChange("0/0", []byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestDecodeNotification(t *testing.T) {
+	ev, err := decodeNotification([]byte(`{"schema":"public","table":"accounts","op":"D","old":{"id":1,"name":"joe"},"new":null}`))
+	require.NoError(t, err)
+	assert.Equal(t, Delete, ev.Op)
+	assert.Equal(t, "accounts", ev.Table)
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "name": "joe"}, ev.Old)
+	assert.Nil(t, ev.New)
+	assert.False(t, ev.Truncated)
+
+	ev, err = decodeNotification([]byte(`{"schema":"public","table":"accounts","op":"I","truncated":true,"old":null,"new":{"id":1}}`))
+	require.NoError(t, err)
+	assert.True(t, ev.Truncated)
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, ev.New)
+}
@@ -0,0 +1,156 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/goware/pgkit/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// notifyFunction is the trigger function installed by InstallNotifyTrigger. It
+// sends the changed row as a JSON payload to the channel given as the first
+// trigger argument. Postgres rejects NOTIFY payloads of 8000 bytes or more, so
+// the payloads of wider rows only carry the primary key and replica identity
+// columns of the rows, and are flagged as truncated, see Event.Truncated.
+const notifyFunction = `CREATE OR REPLACE FUNCTION pgkit_notify_change() RETURNS trigger AS $$
+DECLARE
+  payload text;
+BEGIN
+  payload := json_build_object(
+    'schema', TG_TABLE_SCHEMA,
+    'table', TG_TABLE_NAME,
+    'op', left(TG_OP, 1),
+    'old', CASE WHEN TG_OP <> 'INSERT' THEN row_to_json(OLD) END,
+    'new', CASE WHEN TG_OP <> 'DELETE' THEN row_to_json(NEW) END
+  )::text;
+
+  IF octet_length(payload) >= 8000 THEN
+    payload := json_build_object(
+      'schema', TG_TABLE_SCHEMA,
+      'table', TG_TABLE_NAME,
+      'op', left(TG_OP, 1),
+      'truncated', true,
+      'old', CASE WHEN TG_OP <> 'INSERT' THEN (
+        SELECT jsonb_object_agg(a.attname, to_jsonb(OLD) -> a.attname)
+        FROM pg_index i JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+        WHERE i.indrelid = TG_RELID AND (i.indisprimary OR i.indisreplident)
+      ) END,
+      'new', CASE WHEN TG_OP <> 'DELETE' THEN (
+        SELECT jsonb_object_agg(a.attname, to_jsonb(NEW) -> a.attname)
+        FROM pg_index i JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+        WHERE i.indrelid = TG_RELID AND (i.indisprimary OR i.indisreplident)
+      ) END
+    )::text;
+  END IF;
+
+  PERFORM pg_notify(TG_ARGV[0], payload);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`
+
+// InstallNotifyTrigger installs a row level trigger on the table which
+// emits a NOTIFY on the channel for every insert, update and delete. It is
+// a lighter-weight alternative to logical replication, ie. for cache
+// invalidation, with the caveat that notifications are lost while no
+// subscriber is listening. The events of rows too wide for a NOTIFY payload
+// only carry their key columns, see Event.Truncated.
+func InstallNotifyTrigger(ctx context.Context, db *pgkit.DB, tableName, channel string) error {
+	table := pgx.Identifier(strings.Split(tableName, ".")).Sanitize()
+	trigger := pgx.Identifier{"pgkit_notify_" + strings.ReplaceAll(tableName, ".", "_")}.Sanitize()
+	channelLit := "'" + strings.ReplaceAll(channel, "'", "''") + "'"
+
+	queries := pgkit.Queries{
+		pgkit.RawSQL{Query: notifyFunction},
+		pgkit.RawSQL{Query: fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s`, trigger, table)},
+		pgkit.RawSQL{Query: fmt.Sprintf(`CREATE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION pgkit_notify_change(%s)`, trigger, table, channelLit)},
+	}
+
+	err := pgx.BeginFunc(ctx, db.Conn, func(tx pgx.Tx) error {
+		_, err := db.TxQuery(tx).BatchExec(ctx, queries)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("cdc: install notify trigger on %s: %w", tableName, err)
+	}
+	return nil
+}
+
+// UninstallNotifyTrigger removes the trigger installed by InstallNotifyTrigger.
+func UninstallNotifyTrigger(ctx context.Context, db *pgkit.DB, tableName string) error {
+	table := pgx.Identifier(strings.Split(tableName, ".")).Sanitize()
+	trigger := pgx.Identifier{"pgkit_notify_" + strings.ReplaceAll(tableName, ".", "_")}.Sanitize()
+
+	_, err := db.Query.Exec(ctx, pgkit.RawSQL{Query: fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s`, trigger, table)})
+	if err != nil {
+		return fmt.Errorf("cdc: uninstall notify trigger on %s: %w", tableName, err)
+	}
+	return nil
+}
+
+// Subscribe listens on the channel on a dedicated pool connection and
+// delivers the decoded events to the handler, until the context is done or
+// the handler fails.
+func Subscribe(ctx context.Context, db *pgkit.DB, channel string, handler func(ctx context.Context, ev Event) error) error {
+	return SubscribeReady(ctx, db, channel, nil, handler)
+}
+
+// SubscribeReady is like Subscribe, but calls ready once listening on the
+// channel, before delivering the events, ie. to load the state the events
+// update. The events of the changes committed while ready runs are queued on
+// the connection, so none is missed between the load and the subscription.
+func SubscribeReady(ctx context.Context, db *pgkit.DB, channel string, ready func(ctx context.Context) error, handler func(ctx context.Context, ev Event) error) error {
+	conn, err := db.Conn.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("cdc: acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("cdc: listen on %s: %w", channel, err)
+	}
+	defer func() {
+		// the connection goes back to the pool, stop listening on it
+		_, _ = conn.Exec(context.Background(), "UNLISTEN "+pgx.Identifier{channel}.Sanitize())
+	}()
+
+	if ready != nil {
+		if err := ready(ctx); err != nil {
+			return err
+		}
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("cdc: wait for notification: %w", err)
+		}
+
+		ev, err := decodeNotification([]byte(n.Payload))
+		if err != nil {
+			return err
+		}
+		if err := handler(ctx, ev); err != nil {
+			return err
+		}
+	}
+}
+
+type notifyPayload struct {
+	Schema    string                 `json:"schema"`
+	Table     string                 `json:"table"`
+	Op        string                 `json:"op"`
+	Truncated bool                   `json:"truncated"`
+	Old       map[string]interface{} `json:"old"`
+	New       map[string]interface{} `json:"new"`
+}
+
+func decodeNotification(payload []byte) (Event, error) {
+	var p notifyPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return Event{}, fmt.Errorf("cdc: decode notification: %w", err)
+	}
+	return Event{Schema: p.Schema, Table: p.Table, Op: Op(p.Op), Old: p.Old, New: p.New, Truncated: p.Truncated}, nil
+}
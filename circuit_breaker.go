@@ -0,0 +1,327 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrCircuitOpen is returned by the Querier when its circuit breaker is open
+// and the query was rejected without reaching the database.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type CircuitBreakerConfig struct {
+	// FailureRate is the ratio of failed queries, between 0 and 1, within a
+	// Window at which the breaker opens. Default is 0.5.
+	FailureRate float64
+	// MinRequests is the minimum number of queries within a Window before the
+	// failure rate is considered. Default is 20.
+	MinRequests int
+	// Window is the duration of the window failures are counted in. Default is 10s.
+	Window time.Duration
+	// SlowThreshold, when non-zero, counts queries slower than the threshold
+	// as failures.
+	SlowThreshold time.Duration
+	// OpenTimeout is how long the breaker stays open before letting a probe
+	// query through. Default is 5s.
+	OpenTimeout time.Duration
+	// OnStateChange is called whenever the breaker changes state. It is called
+	// with the breaker's lock held, so it must not call back into the breaker.
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker fails queries fast with ErrCircuitOpen when the database
+// is failing or too slow, instead of piling up goroutines waiting on it.
+// Errors caused by the query itself, such as constraint violations, are not
+// counted as failures. See Querier.WithCircuitBreaker.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mu          sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.FailureRate <= 0 {
+		cfg.FailureRate = 0.5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 5 * time.Second
+	}
+	return &CircuitBreaker{cfg: cfg, windowStart: time.Now()}
+}
+
+// State returns the current state of the breaker.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// Allow reports whether a query may run. Every allowed query must be followed
+// by a call to Done.
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.openedAt) < cb.cfg.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		cb.setState(CircuitHalfOpen)
+		cb.probing = true
+		return nil
+	case CircuitHalfOpen:
+		// only a single probe query at a time
+		if cb.probing {
+			return ErrCircuitOpen
+		}
+		cb.probing = true
+		return nil
+	}
+	return nil
+}
+
+// Done records the outcome of a query previously allowed by Allow.
+func (cb *CircuitBreaker) Done(duration time.Duration, err error) {
+	failed := isCircuitFailure(err) || (cb.cfg.SlowThreshold > 0 && duration > cb.cfg.SlowThreshold)
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		cb.probing = false
+		if failed {
+			cb.open()
+		} else {
+			cb.setState(CircuitClosed)
+			cb.resetWindow()
+		}
+		return
+	case CircuitOpen:
+		return
+	}
+
+	if time.Since(cb.windowStart) > cb.cfg.Window {
+		cb.resetWindow()
+	}
+	cb.requests++
+	if failed {
+		cb.failures++
+	}
+
+	if cb.requests >= cb.cfg.MinRequests && float64(cb.failures)/float64(cb.requests) >= cb.cfg.FailureRate {
+		cb.open()
+	}
+}
+
+func (cb *CircuitBreaker) open() {
+	cb.openedAt = time.Now()
+	cb.setState(CircuitOpen)
+}
+
+func (cb *CircuitBreaker) resetWindow() {
+	cb.windowStart = time.Now()
+	cb.requests = 0
+	cb.failures = 0
+}
+
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	if cb.state == state {
+		return
+	}
+	from := cb.state
+	cb.state = state
+	if cb.cfg.OnStateChange != nil {
+		cb.cfg.OnStateChange(from, state)
+	}
+}
+
+// isCircuitFailure reports whether err indicates the database is unhealthy,
+// as opposed to an error caused by the query or the caller.
+func isCircuitFailure(err error) bool {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if len(pgErr.Code) < 2 {
+			return true
+		}
+		switch pgErr.Code[:2] {
+		case "08", // connection exception
+			"53", // insufficient resources
+			"57", // operator intervention
+			"58", // system error
+			"XX": // internal error
+			return true
+		}
+		return false
+	}
+
+	return true
+}
+
+// run executes fn guarded by the querier's circuit breaker, if any.
+func (q *Querier) run(fn func() error) error {
+	report, err := q.guard()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	report(err)
+	return err
+}
+
+// guard takes a request of the querier's circuit breaker, if any. The
+// returned func reports the outcome of the request, along with the time
+// elapsed since guard was called, once known. Only the first report counts.
+func (q *Querier) guard() (func(err error), error) {
+	if q.breaker == nil {
+		return func(error) {}, nil
+	}
+	if err := q.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	var (
+		once    sync.Once
+		start   = time.Now()
+		breaker = q.breaker
+	)
+	return func(err error) {
+		once.Do(func() {
+			breaker.Done(time.Since(start), err)
+		})
+	}, nil
+}
+
+// breakerRow reports the outcome of a QueryRow to the circuit breaker once
+// the row is scanned.
+type breakerRow struct {
+	pgx.Row
+	report func(err error)
+}
+
+func (r breakerRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	r.report(err)
+	return err
+}
+
+// breakerRows reports the outcome of a query to the circuit breaker once its
+// rows are read or closed, as server errors may only show up while reading
+// them.
+type breakerRows struct {
+	pgx.Rows
+	report func(err error)
+}
+
+func (r breakerRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.report(r.Rows.Err())
+	return false
+}
+
+func (r breakerRows) Close() {
+	r.Rows.Close()
+	r.report(r.Rows.Err())
+}
+
+// breakerBatchResults reports the outcome of a batch to the circuit breaker
+// once its results are closed. The outcome is the first failure of its
+// statements, see isCircuitFailure, if any.
+type breakerBatchResults struct {
+	pgx.BatchResults
+	report func(err error)
+
+	mu  *sync.Mutex
+	err *error
+}
+
+func newBreakerBatchResults(results pgx.BatchResults, report func(err error)) breakerBatchResults {
+	return breakerBatchResults{BatchResults: results, report: report, mu: &sync.Mutex{}, err: new(error)}
+}
+
+// fail records the first failure of the statements of the batch.
+func (r breakerBatchResults) fail(err error) {
+	if !isCircuitFailure(err) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if *r.err == nil {
+		*r.err = err
+	}
+}
+
+func (r breakerBatchResults) Exec() (pgconn.CommandTag, error) {
+	tag, err := r.BatchResults.Exec()
+	r.fail(err)
+	return tag, err
+}
+
+func (r breakerBatchResults) Query() (pgx.Rows, error) {
+	rows, err := r.BatchResults.Query()
+	if err != nil {
+		r.fail(err)
+		return rows, err
+	}
+	return breakerRows{Rows: rows, report: r.fail}, nil
+}
+
+func (r breakerBatchResults) QueryRow() pgx.Row {
+	return breakerRow{Row: r.BatchResults.QueryRow(), report: r.fail}
+}
+
+func (r breakerBatchResults) Close() error {
+	err := r.BatchResults.Close()
+	r.fail(err)
+
+	r.mu.Lock()
+	failure := *r.err
+	r.mu.Unlock()
+	r.report(failure)
+	return err
+}
@@ -0,0 +1,93 @@
+package pgkit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var transitions []CircuitState
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureRate: 0.5,
+		MinRequests: 4,
+		OpenTimeout: 10 * time.Millisecond,
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, to)
+		},
+	})
+
+	// query errors don't count as failures
+	for i := 0; i < 10; i++ {
+		require.NoError(t, cb.Allow())
+		cb.Done(0, &pgconn.PgError{Code: "23505"})
+	}
+	require.Equal(t, CircuitClosed, cb.State())
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, cb.Allow())
+		cb.Done(0, errors.New("connection reset"))
+	}
+	require.Equal(t, CircuitOpen, cb.State())
+	require.ErrorIs(t, cb.Allow(), ErrCircuitOpen)
+
+	time.Sleep(20 * time.Millisecond)
+
+	// single probe in half-open state
+	require.NoError(t, cb.Allow())
+	require.Equal(t, CircuitHalfOpen, cb.State())
+	require.ErrorIs(t, cb.Allow(), ErrCircuitOpen)
+	cb.Done(0, nil)
+	require.Equal(t, CircuitClosed, cb.State())
+
+	require.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}, transitions)
+}
+
+// failingRows are rows failing with err once read.
+type failingRows struct {
+	pgx.Rows
+	err error
+}
+
+func (r *failingRows) Next() bool { return false }
+func (r *failingRows) Close()     {}
+func (r *failingRows) Err() error { return r.err }
+
+// failingBatchResults are batch results whose statements fail with err.
+type failingBatchResults struct {
+	pgx.BatchResults
+	err error
+}
+
+func (r *failingBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, r.err
+}
+func (r *failingBatchResults) Close() error { return nil }
+
+func TestBreakerReportsRowsErrors(t *testing.T) {
+	failure := errors.New("connection reset")
+	q := &Querier{breaker: NewCircuitBreaker(CircuitBreakerConfig{FailureRate: 1, MinRequests: 2})}
+
+	// the rows of a query fail once read
+	report, err := q.guard()
+	require.NoError(t, err)
+	rows := breakerRows{Rows: &failingRows{err: failure}, report: report}
+	assert.False(t, rows.Next())
+	rows.Close()
+	assert.Equal(t, CircuitClosed, q.breaker.State())
+
+	// the statements of a batch fail once their results are read
+	report, err = q.guard()
+	require.NoError(t, err)
+	results := newBreakerBatchResults(&failingBatchResults{err: failure}, report)
+	_, err = results.Exec()
+	assert.ErrorIs(t, err, failure)
+	require.NoError(t, results.Close())
+
+	assert.Equal(t, CircuitOpen, q.breaker.State())
+}
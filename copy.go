@@ -0,0 +1,102 @@
+package pgkit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CopyProgressInterval is the number of rows between two progress reports of
+// CopyFrom.
+var CopyProgressInterval int64 = 10000
+
+// CopyProgress is the progress of a CopyFrom, reported every
+// CopyProgressInterval rows and once done. It counts rows rather than bytes:
+// the rows are encoded by pgx on the wire, so the number of bytes sent isn't
+// known to CopyFrom.
+type CopyProgress struct {
+	// Rows is the number of rows read from the source so far.
+	Rows int64
+	// Elapsed is the time since the copy started.
+	Elapsed time.Duration
+	// Done is set on the last report, once the copy finished or failed.
+	Done bool
+}
+
+// Rate returns the number of rows sent per second.
+func (p CopyProgress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Rows) / p.Elapsed.Seconds()
+}
+
+// CopyFrom loads the rows of src into the columns of the table, optionally
+// schema-qualified, ie. "public.users", with the COPY protocol, the fastest
+// way to bulk load data, and returns the number of rows copied. The optional
+// progress func is called every CopyProgressInterval rows, ie. to show the progress of long running imports. Canceling the
+// context aborts the copy, in which case no rows are copied.
+func (q *Querier) CopyFrom(ctx context.Context, tableName string, columns []string, src pgx.CopyFromSource, progress func(CopyProgress)) (int64, error) {
+	ctx, done, err := q.execContext(ctx)
+	if err != nil {
+		return 0, wrapErr(err)
+	}
+	defer done()
+
+	if err := q.applySettings(ctx); err != nil {
+		return 0, wrapErr(err)
+	}
+
+	table := pgx.Identifier(strings.Split(tableName, "."))
+	ps := &progressSource{CopyFromSource: src, ctx: ctx, progress: progress, start: time.Now()}
+
+	var n int64
+	if q.tx != nil {
+		n, err = q.tx.CopyFrom(ctx, table, columns, ps)
+	} else {
+		n, err = q.pool.CopyFrom(ctx, table, columns, ps)
+	}
+	if progress != nil {
+		progress(CopyProgress{Rows: ps.rows, Elapsed: time.Since(ps.start), Done: true})
+	}
+	if err != nil {
+		return 0, wrapErr(err)
+	}
+	return n, nil
+}
+
+// progressSource reports the progress of the rows read from the source, and
+// stops reading once the context is canceled.
+type progressSource struct {
+	pgx.CopyFromSource
+	ctx      context.Context
+	progress func(CopyProgress)
+	start    time.Time
+	rows     int64
+	err      error
+}
+
+func (s *progressSource) Next() bool {
+	if err := s.ctx.Err(); err != nil {
+		s.err = err
+		return false
+	}
+	if !s.CopyFromSource.Next() {
+		return false
+	}
+
+	s.rows++
+	if s.progress != nil && CopyProgressInterval > 0 && s.rows%CopyProgressInterval == 0 {
+		s.progress(CopyProgress{Rows: s.rows, Elapsed: time.Since(s.start)})
+	}
+	return true
+}
+
+func (s *progressSource) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.CopyFromSource.Err()
+}
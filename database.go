@@ -0,0 +1,48 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+)
+
+// CloneDatabase creates the database newName as a copy of the template
+// database, ie. a migrated and seeded test database, which is much faster than
+// replaying its migrations. Postgres refuses to copy a database other sessions
+// are connected to, so those are terminated first. admin must be connected to
+// another database, ie. "postgres".
+func CloneDatabase(ctx context.Context, admin *DB, template, newName string) error {
+	if err := terminateConnections(ctx, admin, template); err != nil {
+		return err
+	}
+
+	stmt := RawQueryIdent("CREATE DATABASE %I TEMPLATE %I", newName, template)
+	if _, err := admin.Query.Exec(ctx, stmt.Build()); err != nil {
+		return fmt.Errorf("pgkit: clone database %q from %q: %w", newName, template, err)
+	}
+	return nil
+}
+
+// DropDatabase terminates the connections to the database, if it exists, and
+// drops it. admin must be connected to another database, ie. "postgres".
+func DropDatabase(ctx context.Context, admin *DB, name string) error {
+	if err := terminateConnections(ctx, admin, name); err != nil {
+		return err
+	}
+
+	stmt := RawQueryIdent("DROP DATABASE IF EXISTS %I", name)
+	if _, err := admin.Query.Exec(ctx, stmt.Build()); err != nil {
+		return fmt.Errorf("pgkit: drop database %q: %w", name, err)
+	}
+	return nil
+}
+
+// terminateConnections terminates the other sessions connected to the
+// database. New connections may be opened right after, so callers should make
+// sure nothing connects to the database anymore.
+func terminateConnections(ctx context.Context, admin *DB, name string) error {
+	stmt := RawQuery("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = ? AND pid <> pg_backend_pid()")
+	if _, err := admin.Query.Exec(ctx, stmt.Build(name)); err != nil {
+		return fmt.Errorf("pgkit: terminate connections to %q: %w", name, err)
+	}
+	return nil
+}
@@ -41,7 +41,11 @@ func (c binaryExprLeaf) ToSql() (string, []interface{}, error) {
 		return "", nil, nil
 	}
 
-	return "?", []interface{}{c.v}, nil
+	v, err := NormalizeValue(c.v)
+	if err != nil {
+		return "", nil, err
+	}
+	return "?", []interface{}{v}, nil
 }
 
 var _ squirrel.Sqlizer = &binaryExprLeaf{}
@@ -57,7 +61,14 @@ func (n *binaryExprNode) ToSql() (string, []interface{}, error) {
 
 	if leftIsString && !rightIsExpr {
 		// backwards compatibility with old-style conditions
-		return squirrel.Eq{n.left.(string): n.right}.ToSql()
+		right, err := normalizeSlice(n.right)
+		if err == nil {
+			right, err = NormalizeValue(right)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("error normalizing right side: %w", err)
+		}
+		return squirrel.Eq{n.left.(string): right}.ToSql()
 	}
 
 	ql, argsl, err := compileLeaf(n.left)
@@ -125,7 +136,11 @@ func compileLeaf(leaf interface{}) (string, []interface{}, error) {
 		return "(" + s + ")", args, nil
 	}
 
-	return "?", []interface{}{leaf}, nil
+	v, err := NormalizeValue(leaf)
+	if err != nil {
+		return "", nil, err
+	}
+	return "?", []interface{}{v}, nil
 }
 
 // Cond is a map of conditions.
@@ -211,6 +226,32 @@ func NotIn[T interface{}](v ...T) squirrel.Sqlizer {
 	return Func("NOT IN", v...)
 }
 
+// AnyArray represents an `= ANY(?)` comparison binding the slice as a single
+// array parameter. Prefer it over In for large lists, ie. thousands of ids,
+// as the statement has a single placeholder whatever the number of values,
+// so it is planned once and can't exceed the bind parameter limit.
+func AnyArray[T interface{}](v []T) squirrel.Sqlizer {
+	return sqlExprFn(func() (string, []interface{}, error) {
+		values, err := normalizeSlice(v)
+		if err != nil {
+			return "", nil, err
+		}
+		return "= ANY(?)", []interface{}{values}, nil
+	})
+}
+
+// NotAnyArray represents a `<> ALL(?)` comparison binding the slice as a
+// single array parameter, see AnyArray.
+func NotAnyArray[T interface{}](v []T) squirrel.Sqlizer {
+	return sqlExprFn(func() (string, []interface{}, error) {
+		values, err := normalizeSlice(v)
+		if err != nil {
+			return "", nil, err
+		}
+		return "<> ALL(?)", []interface{}{values}, nil
+	})
+}
+
 // Raw represents a raw SQL expression.
 func Raw(sql string, args ...interface{}) squirrel.Sqlizer {
 	return sqlExprFn(func() (string, []interface{}, error) {
@@ -254,12 +295,20 @@ func Func[T interface{}](name string, params ...T) squirrel.Sqlizer {
 					} else if reflect.TypeOf(val).Kind() == reflect.Slice {
 						v := reflect.ValueOf(val)
 						for k := 0; k < v.Len(); k++ {
+							nv, err := NormalizeValue(v.Index(k).Interface())
+							if err != nil {
+								return "", nil, fmt.Errorf("%s: error normalizing argument %d: %w", name, i, err)
+							}
 							subPlaces[j] = paramPlaceholder
-							args = append(args, v.Index(k).Interface())
+							args = append(args, nv)
 						}
 					} else {
+						nv, err := NormalizeValue(val)
+						if err != nil {
+							return "", nil, fmt.Errorf("%s: error normalizing argument %d: %w", name, i, err)
+						}
 						subPlaces[j] = paramPlaceholder
-						args = append(args, val)
+						args = append(args, nv)
 					}
 				}
 
@@ -280,8 +329,12 @@ func Func[T interface{}](name string, params ...T) squirrel.Sqlizer {
 				places[i] = paramSQL
 				args = append(args, paramArgs...)
 			} else {
+				nv, err := NormalizeValue(param)
+				if err != nil {
+					return "", nil, fmt.Errorf("%s: error normalizing argument %d: %w", name, i, err)
+				}
 				places[i] = paramPlaceholder
-				args = append(args, param)
+				args = append(args, nv)
 			}
 		}
 
@@ -1,13 +1,18 @@
 package db_test
 
 import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
 	"testing"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/goware/pgkit/v2/db"
+	"github.com/goware/pgkit/v2/dbtype"
 )
 
 func TestCond(t *testing.T) {
@@ -189,6 +194,26 @@ func TestCond(t *testing.T) {
 		}
 	})
 
+	t.Run("ANY with array", func(t *testing.T) {
+		ids := []int64{1, 2, 3}
+		cond := db.Cond{"id": db.AnyArray(ids)}
+		s, args, err := cond.ToSql()
+		require.NoError(t, err)
+
+		assert.Equal(t, []interface{}{ids}, args)
+		assert.Equal(t, "id = ANY(?)", s)
+	})
+
+	t.Run("NOT ANY with array", func(t *testing.T) {
+		names := []string{"a", "b"}
+		cond := db.Cond{"name": db.NotAnyArray(names)}
+		s, args, err := cond.ToSql()
+		require.NoError(t, err)
+
+		assert.Equal(t, []interface{}{names}, args)
+		assert.Equal(t, "name <> ALL(?)", s)
+	})
+
 	t.Run("raw condition", func(t *testing.T) {
 		cond := db.Cond{"salary": db.Raw("> ANY(SELECT salary FROM managers WHERE id < ?)", 23)}
 		s, args, err := cond.ToSql()
@@ -252,3 +277,83 @@ func TestCond(t *testing.T) {
 		assert.Equal(t, "id IN (SELECT id FROM users WHERE (status = ? OR status = ?))", s)
 	})
 }
+
+func TestVectorDistance(t *testing.T) {
+	vec := dbtype.Vector{1, 2, 3}
+
+	s, args, err := db.CosineDistance("embedding", vec).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "embedding <=> ?::vector", s)
+	assert.Equal(t, []interface{}{vec}, args)
+
+	q := db.OrderByDistance(sq.Select("id").From("items").Where(db.Cond{"kind": "doc"}), db.L2Distance("embedding", vec), 5)
+	s, args, err = q.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM items WHERE kind = ? ORDER BY embedding <-> ?::vector LIMIT 5", s)
+	assert.Equal(t, []interface{}{"doc", vec}, args)
+}
+
+func TestWithinRadius(t *testing.T) {
+	s, args, err := db.WithinRadius("location", 52.5, 13.25, 1000).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "ST_DWithin(location::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?)", s)
+	assert.Equal(t, []interface{}{13.25, 52.5, 1000.0}, args)
+}
+
+type hashValue [2]byte
+
+func (h hashValue) Value() (driver.Value, error) {
+	return hex.EncodeToString(h[:]), nil
+}
+
+type textID int
+
+func (id textID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("id-%d", id)), nil
+}
+
+type rawID struct{ n int64 }
+
+func TestCondNormalizeValues(t *testing.T) {
+	h := hashValue{0xab, 0xcd}
+
+	tests := []struct {
+		cond sq.Sqlizer
+		sql  string
+		args []interface{}
+	}{
+		{db.Cond{"hash": h}, "hash = ?", []interface{}{"abcd"}},
+		{db.Cond{"hash": db.Eq(h)}, "hash = ?", []interface{}{"abcd"}},
+		{db.Cond{"hash": db.Like(h)}, "hash LIKE ?", []interface{}{"abcd"}},
+		{db.Cond{"hash": db.In(h, h)}, "hash IN (?, ?)", []interface{}{"abcd", "abcd"}},
+		{db.Cond{"hash": db.In([]hashValue{h})}, "hash IN ((?))", []interface{}{"abcd"}},
+		{db.Cond{"id": []textID{1, 2}}, "id IN (?,?)", []interface{}{"id-1", "id-2"}},
+		{db.Cond{"id": db.AnyArray([]textID{1, 2})}, "id = ANY(?)", []interface{}{[]interface{}{"id-1", "id-2"}}},
+		{db.Cond{h: "hash"}, "? = hash", []interface{}{"abcd"}},
+	}
+	for _, tt := range tests {
+		s, args, err := tt.cond.ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, tt.sql, s)
+		assert.Equal(t, tt.args, args)
+	}
+
+	// plain values are bound as is
+	now := time.Now()
+	_, args, err := db.Cond{"created_at": db.Gt(now)}.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{now}, args)
+
+	defer func(normalize func(interface{}) (interface{}, error)) { db.NormalizeValue = normalize }(db.NormalizeValue)
+	db.NormalizeValue = func(v interface{}) (interface{}, error) {
+		if id, ok := v.(rawID); ok {
+			return id.n, nil
+		}
+		return db.DefaultNormalizeValue(v)
+	}
+
+	s, args, err := db.Cond{"id": db.In(rawID{1}, rawID{2})}.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "id IN (?, ?)", s)
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, args)
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"reflect"
+	"time"
+)
+
+// NormalizeValue converts the values bound by the condition helpers before
+// they're passed to the driver. It defaults to DefaultNormalizeValue, and can
+// be replaced at init time to support custom types, ie. id or hash types
+// which implement neither driver.Valuer nor encoding.TextMarshaler:
+//
+//	db.NormalizeValue = func(v interface{}) (interface{}, error) {
+//		if id, ok := v.(ID); ok {
+//			return id.Int64(), nil
+//		}
+//		return db.DefaultNormalizeValue(v)
+//	}
+var NormalizeValue = DefaultNormalizeValue
+
+// DefaultNormalizeValue resolves driver.Valuer values to their value, and
+// encoding.TextMarshaler values, other than time.Time, to their text, so
+// custom types bind the same way in all condition helpers.
+func DefaultNormalizeValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, time.Time:
+		return v, nil
+	case driver.Valuer:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, nil
+		}
+		return v.Value()
+	case encoding.TextMarshaler:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, nil
+		}
+		text, err := v.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+	return v, nil
+}
+
+// normalizeSlice normalizes the elements of the slice bound as an array. It
+// returns the slice as is unless normalizing changes the type of an element.
+func normalizeSlice(slice interface{}) (interface{}, error) {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return slice, nil
+	}
+
+	var (
+		values  = make([]interface{}, v.Len())
+		changed bool
+	)
+	for i := range values {
+		elem := v.Index(i).Interface()
+		nv, err := NormalizeValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = nv
+		changed = changed || reflect.TypeOf(nv) != reflect.TypeOf(elem)
+	}
+	if !changed {
+		return slice, nil
+	}
+	return values, nil
+}
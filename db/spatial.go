@@ -0,0 +1,19 @@
+package db
+
+import (
+	"github.com/Masterminds/squirrel"
+)
+
+// WithinRadius represents a PostGIS condition matching the rows whose column
+// is within the given distance in meters of the GPS coordinates. The column is
+// compared as geography, so use a geography column, or an index on
+// `(col::geography)`, for the condition to be indexed.
+func WithinRadius(col string, lat, lng, meters float64) squirrel.Sqlizer {
+	return Raw("ST_DWithin("+col+"::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?)", lng, lat, meters)
+}
+
+// DistanceTo represents the PostGIS distance in meters between the column and
+// the GPS coordinates, ie. for ordering by proximity.
+func DistanceTo(col string, lat, lng float64) squirrel.Sqlizer {
+	return Raw("ST_Distance("+col+"::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography)", lng, lat)
+}
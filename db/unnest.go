@@ -0,0 +1,146 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Array is a slice bound as a Postgres array of the given element type, ie.
+// "uuid", for the element types Unnest can't infer.
+type Array struct {
+	Values interface{}
+	Type   string
+}
+
+// TypedArray binds the slice as an array of the given Postgres element type,
+// see Unnest.
+func TypedArray(values interface{}, pgType string) Array {
+	return Array{Values: values, Type: pgType}
+}
+
+// Unnest represents `unnest(?::bigint[], ?::text[], ...)`, which binds each
+// slice as a single array parameter and expands them in parallel into rows,
+// for use in FROM and JOIN clauses, ie.
+//
+//	q := sq.Select("a.*").From("accounts a").
+//		JoinClause(sq.Expr("JOIN ? AS t(id, name) ON t.id = a.id", db.Unnest(ids, names)))
+//
+// The Postgres element types are inferred from the slices' element types,
+// use TypedArray for the others. All slices must have the same length.
+func Unnest(arrays ...interface{}) squirrel.Sqlizer {
+	return sqlExprFn(func() (string, []interface{}, error) {
+		places, args, err := unnestArrays(arrays)
+		if err != nil {
+			return "", nil, err
+		}
+		return "unnest(" + strings.Join(places, ", ") + ")", args, nil
+	})
+}
+
+// UnnestSelect is like Unnest, but returns a query selecting the rows of the
+// slices as the given columns, ie. to insert or update many rows with a
+// single statement whatever their number:
+//
+//	q := sq.Insert("accounts").Columns("name", "disabled").
+//		Select(db.UnnestSelect([]string{"name", "disabled"}, names, disabled))
+func UnnestSelect(columns []string, arrays ...interface{}) squirrel.SelectBuilder {
+	places, args, err := unnestArrays(arrays)
+	if err == nil && len(columns) != len(arrays) {
+		err = fmt.Errorf("unnest: %d columns for %d arrays", len(columns), len(arrays))
+	}
+	if err != nil {
+		return squirrel.Select().Column(sqlExprFn(func() (string, []interface{}, error) {
+			return "", nil, err
+		}))
+	}
+
+	// set returning functions of the select list are expanded in lockstep,
+	// like the arrays of a multi-argument unnest
+	q := squirrel.Select()
+	for i, col := range columns {
+		q = q.Column("unnest("+places[i]+") AS "+col, args[i])
+	}
+	return q
+}
+
+// unnestArrays returns the typed placeholders of the arrays and the slices to
+// bind to them.
+func unnestArrays(arrays []interface{}) ([]string, []interface{}, error) {
+	if len(arrays) == 0 {
+		return nil, nil, fmt.Errorf("unnest: no arrays")
+	}
+
+	places := make([]string, len(arrays))
+	args := make([]interface{}, len(arrays))
+	length := -1
+	for i, array := range arrays {
+		pgType := ""
+		if a, ok := array.(Array); ok {
+			array, pgType = a.Values, a.Type
+		}
+
+		v := reflect.ValueOf(array)
+		if v.Kind() != reflect.Slice {
+			return nil, nil, fmt.Errorf("unnest: argument %d is a %T, not a slice", i, array)
+		}
+		if length >= 0 && v.Len() != length {
+			return nil, nil, fmt.Errorf("unnest: argument %d has %d elements, expecting %d", i, v.Len(), length)
+		}
+		length = v.Len()
+
+		if pgType == "" {
+			pgType = elemType(v.Type().Elem())
+			if pgType == "" {
+				return nil, nil, fmt.Errorf("unnest: unknown Postgres type of %s elements, use TypedArray", v.Type().Elem())
+			}
+		}
+		values, err := normalizeSlice(array)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unnest: argument %d: %w", i, err)
+		}
+		places[i] = "?::" + pgType + "[]"
+		args[i] = values
+	}
+	return places, args, nil
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte{})
+)
+
+// elemType returns the Postgres type of the slice elements, or "" if unknown.
+func elemType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return "timestamptz"
+	case bytesType:
+		return "bytea"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8, reflect.Int16:
+		return "smallint"
+	case reflect.Int32:
+		return "integer"
+	case reflect.Int, reflect.Int64:
+		return "bigint"
+	case reflect.Float32:
+		return "real"
+	case reflect.Float64:
+		return "double precision"
+	case reflect.String:
+		return "text"
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package db_test
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goware/pgkit/v2/db"
+)
+
+func TestUnnest(t *testing.T) {
+	ids := []int64{1, 2}
+	names := []string{"a", "b"}
+	hashes := [][]byte{{0x01}, {0x02}}
+
+	t.Run("join", func(t *testing.T) {
+		q := sq.Select("a.*").From("accounts a").
+			JoinClause(sq.Expr("JOIN ? AS t(id, name, hash) ON t.id = a.id", db.Unnest(ids, names, hashes)))
+		s, args, err := q.ToSql()
+		require.NoError(t, err)
+
+		assert.Equal(t, "SELECT a.* FROM accounts a JOIN unnest(?::bigint[], ?::text[], ?::bytea[]) AS t(id, name, hash) ON t.id = a.id", s)
+		assert.Equal(t, []interface{}{ids, names, hashes}, args)
+	})
+
+	t.Run("insert select", func(t *testing.T) {
+		uuids := []string{"0190c7e4-7ad1-7c2c-9f5a-1a2b3c4d5e6f", "0190c7e4-7ad1-7c2c-9f5a-1a2b3c4d5e70"}
+		q := sq.Insert("accounts").Columns("name", "uid").
+			Select(db.UnnestSelect([]string{"name", "uid"}, names, db.TypedArray(uuids, "uuid")))
+		s, args, err := q.ToSql()
+		require.NoError(t, err)
+
+		assert.Equal(t, "INSERT INTO accounts (name,uid) SELECT unnest(?::text[]) AS name, unnest(?::uuid[]) AS uid", s)
+		assert.Equal(t, []interface{}{names, uuids}, args)
+	})
+
+	t.Run("invalid arrays", func(t *testing.T) {
+		_, _, err := db.Unnest(ids, []string{"a"}).ToSql()
+		assert.ErrorContains(t, err, "expecting 2")
+
+		_, _, err = db.Unnest(ids, 1).ToSql()
+		assert.ErrorContains(t, err, "not a slice")
+
+		_, _, err = db.Unnest([]struct{}{{}}).ToSql()
+		assert.ErrorContains(t, err, "TypedArray")
+
+		_, _, err = db.UnnestSelect([]string{"id"}, ids, names).ToSql()
+		assert.ErrorContains(t, err, "2 arrays")
+	})
+}
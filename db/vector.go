@@ -0,0 +1,39 @@
+package db
+
+import (
+	"github.com/Masterminds/squirrel"
+	"github.com/goware/pgkit/v2/dbtype"
+)
+
+// L2Distance represents the pgvector euclidean distance between the column
+// and the vector, ie. for ordering by similarity.
+func L2Distance(col string, vec dbtype.Vector) squirrel.Sqlizer {
+	return vectorDistance(col, "<->", vec)
+}
+
+// CosineDistance represents the pgvector cosine distance between the column
+// and the vector.
+func CosineDistance(col string, vec dbtype.Vector) squirrel.Sqlizer {
+	return vectorDistance(col, "<=>", vec)
+}
+
+// InnerProduct represents the pgvector negative inner product between the
+// column and the vector.
+func InnerProduct(col string, vec dbtype.Vector) squirrel.Sqlizer {
+	return vectorDistance(col, "<#>", vec)
+}
+
+// OrderByDistance orders the query by the distance, nearest first, and limits
+// it to the given number of rows, which lets Postgres use an HNSW or IVFFlat
+// index, ie.
+//
+//	q := db.OrderByDistance(sq.Select("*").From("items"), db.CosineDistance("embedding", vec), 10)
+func OrderByDistance(q squirrel.SelectBuilder, distance squirrel.Sqlizer, limit uint64) squirrel.SelectBuilder {
+	return q.OrderByClause(distance).Limit(limit)
+}
+
+func vectorDistance(col, op string, vec dbtype.Vector) squirrel.Sqlizer {
+	// cast the bound text to vector, as pgvector operators don't resolve
+	// against an untyped parameter
+	return Raw(col+" "+op+" ?::vector", vec)
+}
@@ -0,0 +1,110 @@
+package dbtype
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ScanNumeric implements pgx/pgtype.NumericScanner
+func (b *BigInt) ScanNumeric(v pgtype.Numeric) error {
+	b.IsValid = false
+	if !v.Valid {
+		return nil
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("dbtype: BigInt cannot hold NaN or infinite numerics")
+	}
+
+	i := new(big.Int).Set(v.Int)
+	if v.Exp > 0 {
+		i.Mul(i, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(v.Exp)), nil))
+	} else if v.Exp < 0 {
+		var rem big.Int
+		i.QuoRem(i, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-v.Exp)), nil), &rem)
+		if rem.Sign() != 0 {
+			return fmt.Errorf("dbtype: BigInt cannot hold fractional numeric values")
+		}
+	}
+
+	b.V = *i
+	b.IsValid = true
+	return nil
+}
+
+// NumericValue implements pgx/pgtype.NumericValuer
+func (b BigInt) NumericValue() (pgtype.Numeric, error) {
+	if !b.IsValid {
+		return pgtype.Numeric{}, nil
+	}
+	return pgtype.Numeric{Int: b.Int(), Valid: true}, nil
+}
+
+// RegisterBigInt registers scan and encode plans on the connection's type map
+// so NUMERIC values, including arrays and composite type fields, scan
+// directly into *big.Int, and big.Int values can be passed as query
+// arguments. BigInt works with pgx without registration. It has the signature
+// of the pgxpool AfterConnect hook, ie.
+//
+//	poolCfg.AfterConnect = dbtype.RegisterBigInt
+func RegisterBigInt(ctx context.Context, conn *pgx.Conn) error {
+	registerBigInt(conn.TypeMap())
+	return nil
+}
+
+func registerBigInt(m *pgtype.Map) {
+	m.TryWrapScanPlanFuncs = append([]pgtype.TryWrapScanPlanFunc{tryWrapBigIntScanPlan}, m.TryWrapScanPlanFuncs...)
+	m.TryWrapEncodePlanFuncs = append([]pgtype.TryWrapEncodePlanFunc{tryWrapBigIntEncodePlan}, m.TryWrapEncodePlanFuncs...)
+}
+
+func tryWrapBigIntScanPlan(target interface{}) (pgtype.WrappedScanPlanNextSetter, interface{}, bool) {
+	if _, ok := target.(*big.Int); ok {
+		return &wrapBigIntScanPlan{}, &BigInt{}, true
+	}
+	return nil, nil, false
+}
+
+type wrapBigIntScanPlan struct {
+	next pgtype.ScanPlan
+}
+
+func (p *wrapBigIntScanPlan) SetNext(next pgtype.ScanPlan) { p.next = next }
+
+func (p *wrapBigIntScanPlan) Scan(src []byte, target interface{}) error {
+	var b BigInt
+	if err := p.next.Scan(src, &b); err != nil {
+		return err
+	}
+	if !b.IsValid {
+		return fmt.Errorf("dbtype: cannot scan NULL into *big.Int")
+	}
+	target.(*big.Int).Set(&b.V)
+	return nil
+}
+
+func tryWrapBigIntEncodePlan(value interface{}) (pgtype.WrappedEncodePlanNextSetter, interface{}, bool) {
+	switch value.(type) {
+	case big.Int, *big.Int:
+		return &wrapBigIntEncodePlan{}, BigInt{}, true
+	}
+	return nil, nil, false
+}
+
+type wrapBigIntEncodePlan struct {
+	next pgtype.EncodePlan
+}
+
+func (p *wrapBigIntEncodePlan) SetNext(next pgtype.EncodePlan) { p.next = next }
+
+func (p *wrapBigIntEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	switch v := value.(type) {
+	case big.Int:
+		return p.next.Encode(ToBigInt(&v), buf)
+	case *big.Int:
+		return p.next.Encode(ToBigInt(v), buf)
+	}
+	return nil, fmt.Errorf("dbtype: unexpected type %T", value)
+}
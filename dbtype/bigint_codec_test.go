@@ -0,0 +1,52 @@
+package dbtype
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigIntScanNumeric(t *testing.T) {
+	var b BigInt
+	require.NoError(t, b.ScanNumeric(pgtype.Numeric{Int: big.NewInt(12), Exp: 3, Valid: true}))
+	assert.Equal(t, "12000", b.String())
+
+	require.NoError(t, b.ScanNumeric(pgtype.Numeric{Int: big.NewInt(12000), Exp: -2, Valid: true}))
+	assert.Equal(t, "120", b.String())
+
+	assert.Error(t, b.ScanNumeric(pgtype.Numeric{Int: big.NewInt(12345), Exp: -2, Valid: true}))
+	assert.Error(t, b.ScanNumeric(pgtype.Numeric{NaN: true, Valid: true}))
+
+	require.NoError(t, b.ScanNumeric(pgtype.Numeric{}))
+	assert.False(t, b.IsValid)
+}
+
+func TestRegisterBigInt(t *testing.T) {
+	m := pgtype.NewMap()
+	registerBigInt(m)
+
+	n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	buf, err := m.Encode(pgtype.NumericOID, pgtype.BinaryFormatCode, n, nil)
+	require.NoError(t, err)
+
+	var got big.Int
+	require.NoError(t, m.Scan(pgtype.NumericOID, pgtype.BinaryFormatCode, buf, &got))
+	assert.Equal(t, 0, n.Cmp(&got))
+
+	var b BigInt
+	require.NoError(t, m.Scan(pgtype.NumericOID, pgtype.BinaryFormatCode, buf, &b))
+	assert.True(t, b.Equals(n))
+
+	buf, err = m.Encode(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, []*big.Int{n, big.NewInt(-1)}, nil)
+	require.NoError(t, err)
+
+	var arr []*big.Int
+	require.NoError(t, m.Scan(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, buf, &arr))
+	require.Len(t, arr, 2)
+	assert.Equal(t, 0, n.Cmp(arr[0]))
+	assert.Equal(t, int64(-1), arr[1].Int64())
+}
@@ -0,0 +1,63 @@
+package dbtype
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDateOf(t *testing.T) {
+	// 23:30 in New York is already the next day in UTC
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	tm := time.Date(2024, 2, 29, 23, 30, 0, 0, loc)
+
+	assert.Equal(t, Date{2024, time.February, 29}, DateOf(tm))
+	assert.Equal(t, Date{2024, time.March, 1}, DateOf(tm.UTC()))
+}
+
+func TestDateParseAndArithmetic(t *testing.T) {
+	d, err := ParseDate("2024-02-28")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-02-28", d.String())
+	assert.Equal(t, Date{2024, time.March, 1}, d.AddDays(2))
+	assert.True(t, d.Before(d.AddDays(1)))
+	assert.True(t, d.After(d.AddDays(-1)))
+
+	_, err = ParseDate("2024-02-30")
+	assert.Error(t, err)
+}
+
+func TestDateScanAndValue(t *testing.T) {
+	var d Date
+	require.NoError(t, d.Scan(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, Date{2024, time.January, 2}, d)
+
+	require.NoError(t, d.ScanDate(pgtype.Date{Time: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), Valid: true}))
+	assert.Equal(t, Date{2023, time.December, 31}, d)
+	assert.Error(t, d.ScanDate(pgtype.Date{InfinityModifier: pgtype.Infinity, Valid: true}))
+
+	v, err := d.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "2023-12-31", v)
+
+	require.NoError(t, d.Scan(nil))
+	assert.True(t, d.IsZero())
+	v, err = d.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestDateJSON(t *testing.T) {
+	data, err := json.Marshal(Date{2024, time.July, 4})
+	require.NoError(t, err)
+	assert.Equal(t, `"2024-07-04"`, string(data))
+
+	var d Date
+	require.NoError(t, json.Unmarshal(data, &d))
+	assert.Equal(t, Date{2024, time.July, 4}, d)
+}
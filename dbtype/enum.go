@@ -0,0 +1,166 @@
+package dbtype
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Enum is a string enum value stored in a Postgres ENUM column, ie.
+//
+//	type ReviewStatus string
+//
+//	const (
+//		ReviewStatusPending  ReviewStatus = "pending"
+//		ReviewStatusApproved ReviewStatus = "approved"
+//	)
+//
+//	func init() {
+//		dbtype.RegisterEnum("review_status", ReviewStatusPending, ReviewStatusApproved)
+//	}
+//
+//	type Review struct {
+//		Status dbtype.Enum[ReviewStatus] `db:"status"`
+//	}
+//
+// Values not registered with RegisterEnum fail on Value and Scan. The empty
+// value is stored as NULL.
+type Enum[T ~string] struct {
+	V T
+}
+
+func NewEnum[T ~string](v T) Enum[T] {
+	return Enum[T]{V: v}
+}
+
+// IsZero reports whether e is empty.
+func (e Enum[T]) IsZero() bool {
+	return e.V == ""
+}
+
+func (e Enum[T]) String() string {
+	return string(e.V)
+}
+
+// Value implements driver.Valuer.
+func (e Enum[T]) Value() (driver.Value, error) {
+	if e.V == "" {
+		return nil, nil
+	}
+	if err := validateEnum(e.V); err != nil {
+		return nil, err
+	}
+	return string(e.V), nil
+}
+
+// Scan implements sql.Scanner.
+func (e *Enum[T]) Scan(src interface{}) error {
+	e.V = ""
+	if src == nil {
+		return nil
+	}
+
+	var v T
+	switch s := src.(type) {
+	case string:
+		v = T(s)
+	case []byte:
+		v = T(s)
+	default:
+		return fmt.Errorf("dbtype: Enum Scan: unexpected type %T", src)
+	}
+
+	if err := validateEnum(v); err != nil {
+		return err
+	}
+	e.V = v
+	return nil
+}
+
+// EnumType is the Postgres ENUM type registered for a Go string type.
+type EnumType struct {
+	Name   string
+	Values []string
+}
+
+var enumTypes sync.Map // reflect.Type -> *EnumType
+
+// RegisterEnum registers the Postgres ENUM type name and the allowed values
+// of T, in their Postgres sort order. It is meant to be called from init.
+func RegisterEnum[T ~string](typeName string, values ...T) {
+	et := &EnumType{Name: typeName, Values: make([]string, len(values))}
+	for i, v := range values {
+		et.Values[i] = string(v)
+	}
+	enumTypes.Store(reflect.TypeOf((*T)(nil)).Elem(), et)
+}
+
+// EnumTypeOf returns the EnumType registered for T.
+func EnumTypeOf[T ~string]() (*EnumType, bool) {
+	et, ok := enumTypes.Load(reflect.TypeOf((*T)(nil)).Elem())
+	if !ok {
+		return nil, false
+	}
+	return et.(*EnumType), true
+}
+
+// CreateTypeSQL returns the `CREATE TYPE ... AS ENUM` statement of the type.
+func (et *EnumType) CreateTypeSQL() string {
+	labels := make([]string, len(et.Values))
+	for i, v := range et.Values {
+		labels[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	}
+	return fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)",
+		pgx.Identifier(strings.Split(et.Name, ".")).Sanitize(), strings.Join(labels, ", "))
+}
+
+// EnumQuerier is satisfied by *pgxpool.Pool, *pgx.Conn and pgx.Tx.
+type EnumQuerier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// VerifyEnum checks the Postgres ENUM type registered for T exists in the
+// database with the same values, in the same order, ie. as part of schema
+// validation on startup.
+func VerifyEnum[T ~string](ctx context.Context, conn EnumQuerier) error {
+	et, ok := EnumTypeOf[T]()
+	if !ok {
+		var zero T
+		return fmt.Errorf("dbtype: enum %T is not registered", zero)
+	}
+
+	rows, err := conn.Query(ctx, `SELECT e.enumlabel FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid WHERE t.oid = to_regtype($1) ORDER BY e.enumsortorder`, et.Name)
+	if err != nil {
+		return fmt.Errorf("dbtype: verify enum %s: %w", et.Name, err)
+	}
+	labels, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("dbtype: verify enum %s: %w", et.Name, err)
+	}
+
+	if len(labels) == 0 {
+		return fmt.Errorf("dbtype: enum type %s does not exist, create it with: %s", et.Name, et.CreateTypeSQL())
+	}
+	if !reflect.DeepEqual(labels, et.Values) {
+		return fmt.Errorf("dbtype: enum type %s has values %v, expecting %v", et.Name, labels, et.Values)
+	}
+	return nil
+}
+
+func validateEnum[T ~string](v T) error {
+	et, ok := EnumTypeOf[T]()
+	if !ok {
+		return fmt.Errorf("dbtype: enum %T is not registered", v)
+	}
+	for _, allowed := range et.Values {
+		if string(v) == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("dbtype: invalid %s value %q", et.Name, string(v))
+}
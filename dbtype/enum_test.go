@@ -0,0 +1,46 @@
+package dbtype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStatus string
+
+func init() {
+	RegisterEnum[testStatus]("test_status", "pending", "it's done")
+}
+
+func TestEnumValueAndScan(t *testing.T) {
+	v, err := NewEnum[testStatus]("pending").Value()
+	require.NoError(t, err)
+	assert.Equal(t, "pending", v)
+
+	_, err = NewEnum[testStatus]("bogus").Value()
+	assert.Error(t, err)
+
+	v, err = Enum[testStatus]{}.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	var e Enum[testStatus]
+	require.NoError(t, e.Scan("it's done"))
+	assert.Equal(t, testStatus("it's done"), e.V)
+	assert.Error(t, e.Scan([]byte("bogus")))
+	require.NoError(t, e.Scan(nil))
+	assert.True(t, e.IsZero())
+}
+
+func TestEnumUnregistered(t *testing.T) {
+	type other string
+	_, err := NewEnum[other]("x").Value()
+	assert.ErrorContains(t, err, "not registered")
+}
+
+func TestEnumCreateTypeSQL(t *testing.T) {
+	et, ok := EnumTypeOf[testStatus]()
+	require.True(t, ok)
+	assert.Equal(t, `CREATE TYPE "test_status" AS ENUM ('pending', 'it''s done')`, et.CreateTypeSQL())
+}
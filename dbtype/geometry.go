@@ -0,0 +1,171 @@
+package dbtype
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// SRIDWGS84 is the spatial reference id of GPS coordinates.
+const SRIDWGS84 = 4326
+
+const (
+	ewkbSRIDFlag = 0x20000000
+	ewkbZFlag    = 0x80000000
+	ewkbMFlag    = 0x40000000
+	wkbPoint     = 1
+)
+
+// Geometry is a PostGIS geometry or geography column value, held as its
+// well-known binary (WKB) representation along with its SRID. A Geometry
+// with nil WKB is stored as NULL.
+type Geometry struct {
+	SRID uint32
+	WKB  []byte
+}
+
+// Type returns the WKB geometry type, ie. 1 for a point, or 0 for a NULL
+// geometry.
+func (g Geometry) Type() uint32 {
+	if len(g.WKB) < 5 {
+		return 0
+	}
+	return g.byteOrder().Uint32(g.WKB[1:5]) &^ (ewkbSRIDFlag | ewkbZFlag | ewkbMFlag)
+}
+
+// Value implements driver.Valuer. The geometry is sent as hex encoded EWKB,
+// which PostGIS accepts as geometry and geography input.
+func (g Geometry) Value() (driver.Value, error) {
+	if g.WKB == nil {
+		return nil, nil
+	}
+	if len(g.WKB) < 5 {
+		return nil, fmt.Errorf("dbtype: Geometry Value: invalid WKB")
+	}
+
+	ewkb := make([]byte, 0, len(g.WKB)+4)
+	ewkb = append(ewkb, g.WKB[:5]...)
+	if g.SRID != 0 {
+		order := g.byteOrder()
+		order.PutUint32(ewkb[1:5], order.Uint32(ewkb[1:5])|ewkbSRIDFlag)
+		ewkb = order.AppendUint32(ewkb, g.SRID)
+	}
+	ewkb = append(ewkb, g.WKB[5:]...)
+	return hex.EncodeToString(ewkb), nil
+}
+
+// Scan implements sql.Scanner. It accepts EWKB, raw or hex encoded as
+// returned by PostGIS.
+func (g *Geometry) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*g = Geometry{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("dbtype: Geometry Scan: unexpected type %T", src)
+	}
+
+	// hex encoded EWKB starts with the byte order as "00" or "01"
+	if len(data) > 0 && data[0] == '0' {
+		dec := make([]byte, hex.DecodedLen(len(data)))
+		if _, err := hex.Decode(dec, data); err != nil {
+			return fmt.Errorf("dbtype: Geometry Scan: %w", err)
+		}
+		data = dec
+	}
+	if len(data) < 5 || data[0] > 1 {
+		return fmt.Errorf("dbtype: Geometry Scan: invalid EWKB")
+	}
+
+	geom := Geometry{WKB: make([]byte, 0, len(data))}
+	order := byteOrder(data[0])
+	typ := order.Uint32(data[1:5])
+	rest := data[5:]
+	if typ&ewkbSRIDFlag != 0 {
+		if len(rest) < 4 {
+			return fmt.Errorf("dbtype: Geometry Scan: invalid EWKB")
+		}
+		geom.SRID = order.Uint32(rest[:4])
+		rest = rest[4:]
+	}
+
+	geom.WKB = append(geom.WKB, data[0])
+	geom.WKB = order.AppendUint32(geom.WKB, typ&^ewkbSRIDFlag)
+	geom.WKB = append(geom.WKB, rest...)
+	*g = geom
+	return nil
+}
+
+type wkbByteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+func (g Geometry) byteOrder() wkbByteOrder {
+	return byteOrder(g.WKB[0])
+}
+
+func byteOrder(b byte) wkbByteOrder {
+	if b == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// Point is a 2D PostGIS point. For GPS coordinates, Lng is X and Lat is Y,
+// with SRID 4326, see NewPoint.
+type Point struct {
+	Lng  float64
+	Lat  float64
+	SRID uint32
+}
+
+// NewPoint returns a WGS 84 point for the GPS coordinates.
+func NewPoint(lat, lng float64) Point {
+	return Point{Lng: lng, Lat: lat, SRID: SRIDWGS84}
+}
+
+// Geometry returns p as a Geometry.
+func (p Point) Geometry() Geometry {
+	wkb := make([]byte, 0, 21)
+	wkb = append(wkb, 1)
+	wkb = binary.LittleEndian.AppendUint32(wkb, wkbPoint)
+	wkb = binary.LittleEndian.AppendUint64(wkb, math.Float64bits(p.Lng))
+	wkb = binary.LittleEndian.AppendUint64(wkb, math.Float64bits(p.Lat))
+	return Geometry{SRID: p.SRID, WKB: wkb}
+}
+
+// Value implements driver.Valuer.
+func (p Point) Value() (driver.Value, error) {
+	return p.Geometry().Value()
+}
+
+// Scan implements sql.Scanner. It fails for geometries other than 2D points.
+func (p *Point) Scan(src interface{}) error {
+	if src == nil {
+		return fmt.Errorf("dbtype: cannot scan NULL into Point")
+	}
+
+	var g Geometry
+	if err := g.Scan(src); err != nil {
+		return err
+	}
+	if g.Type() != wkbPoint || len(g.WKB) != 21 {
+		return fmt.Errorf("dbtype: Point Scan: not a 2D point geometry")
+	}
+
+	order := g.byteOrder()
+	*p = Point{
+		Lng:  math.Float64frombits(order.Uint64(g.WKB[5:13])),
+		Lat:  math.Float64frombits(order.Uint64(g.WKB[13:21])),
+		SRID: g.SRID,
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package dbtype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointValueAndScan(t *testing.T) {
+	p := NewPoint(52.5, 13.25)
+
+	v, err := p.Value()
+	require.NoError(t, err)
+	// as returned by SELECT 'SRID=4326;POINT(13.25 52.5)'::geometry
+	assert.Equal(t, "0101000020e61000000000000000802a400000000000404a40", v)
+
+	var p2 Point
+	require.NoError(t, p2.Scan("0101000020E61000000000000000802A400000000000404A40"))
+	assert.Equal(t, p, p2)
+
+	assert.Error(t, p2.Scan(nil))
+}
+
+func TestGeometryScan(t *testing.T) {
+	var g Geometry
+	// big endian LINESTRING(0 0, 1 1) without SRID
+	require.NoError(t, g.Scan("0000000002000000020000000000000000000000000000000000000000000000003FF00000000000003FF0000000000000"))
+	assert.Equal(t, uint32(2), g.Type())
+	assert.Equal(t, uint32(0), g.SRID)
+
+	var p Point
+	assert.Error(t, p.Scan([]byte(g.WKB)))
+
+	require.NoError(t, g.Scan(nil))
+	v, err := g.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	assert.Error(t, g.Scan("zz"))
+}
@@ -0,0 +1,115 @@
+package dbtype
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// Hash32 is a 32 byte hash, ie. a block or transaction hash, stored in a bytea
+// column and encoded as a 0x prefixed hex string. Unlike HexBytes, values of
+// any other length are rejected.
+type Hash32 [32]byte
+
+// Address20 is a 20 byte account address stored in a bytea column and encoded
+// as a 0x prefixed hex string. Unlike HexBytes, values of any other length are
+// rejected.
+type Address20 [20]byte
+
+// IsZero reports whether h is all zeros.
+func (h Hash32) IsZero() bool {
+	return h == Hash32{}
+}
+
+// String returns h as a 0x prefixed hex string.
+func (h Hash32) String() string {
+	return HexBytes(h[:]).String()
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (h Hash32) MarshalText() ([]byte, error) {
+	return HexBytes(h[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *Hash32) UnmarshalText(input []byte) error {
+	return unmarshalFixedHex("Hash32", h[:], input)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (h *Hash32) UnmarshalJSON(input []byte) error {
+	return unmarshalFixedHexJSON("Hash32", h[:], input)
+}
+
+// ScanBytes implements pgx/pgtype.BytesScanner
+func (h *Hash32) ScanBytes(v []byte) error {
+	return scanFixedBytes("Hash32", h[:], v)
+}
+
+// BytesValue implements pgx/pgtype.BytesValuer
+func (h Hash32) BytesValue() ([]byte, error) {
+	return h[:], nil
+}
+
+// IsZero reports whether a is all zeros.
+func (a Address20) IsZero() bool {
+	return a == Address20{}
+}
+
+// String returns a as a 0x prefixed hex string.
+func (a Address20) String() string {
+	return HexBytes(a[:]).String()
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (a Address20) MarshalText() ([]byte, error) {
+	return HexBytes(a[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Address20) UnmarshalText(input []byte) error {
+	return unmarshalFixedHex("Address20", a[:], input)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Address20) UnmarshalJSON(input []byte) error {
+	return unmarshalFixedHexJSON("Address20", a[:], input)
+}
+
+// ScanBytes implements pgx/pgtype.BytesScanner
+func (a *Address20) ScanBytes(v []byte) error {
+	return scanFixedBytes("Address20", a[:], v)
+}
+
+// BytesValue implements pgx/pgtype.BytesValuer
+func (a Address20) BytesValue() ([]byte, error) {
+	return a[:], nil
+}
+
+func unmarshalFixedHex(typeName string, dst []byte, input []byte) error {
+	raw, err := checkText(input, true)
+	if err != nil {
+		return err
+	}
+	if len(raw) != len(dst)*2 {
+		return fmt.Errorf("dbtype: %s UnmarshalText: expecting %d bytes but received %d", typeName, len(dst), len(raw)/2)
+	}
+	if _, err := hex.Decode(dst, raw); err != nil {
+		return fmt.Errorf("dbtype: %s UnmarshalText failed: %w", typeName, err)
+	}
+	return nil
+}
+
+func unmarshalFixedHexJSON(typeName string, dst []byte, input []byte) error {
+	if !isString(input) {
+		return fmt.Errorf("dbtype: %s UnmarshalJSON received non-string input", typeName)
+	}
+	return unmarshalFixedHex(typeName, dst, input[1:len(input)-1])
+}
+
+func scanFixedBytes(typeName string, dst []byte, v []byte) error {
+	if len(v) != len(dst) {
+		return fmt.Errorf("dbtype: %s ScanBytes: expecting %d bytes but received %d", typeName, len(dst), len(v))
+	}
+	copy(dst, v)
+	return nil
+}
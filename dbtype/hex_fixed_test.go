@@ -0,0 +1,45 @@
+package dbtype
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddress20(t *testing.T) {
+	s := "0x" + strings.Repeat("ab", 20)
+
+	var a Address20
+	require.NoError(t, a.UnmarshalText([]byte(s)))
+	assert.Equal(t, s, a.String())
+	assert.False(t, a.IsZero())
+
+	data, err := json.Marshal(a)
+	require.NoError(t, err)
+	assert.Equal(t, `"`+s+`"`, string(data))
+
+	var a2 Address20
+	require.NoError(t, json.Unmarshal(data, &a2))
+	assert.Equal(t, a, a2)
+
+	assert.Error(t, a2.UnmarshalText([]byte("0x"+strings.Repeat("ab", 19))))
+	assert.Error(t, a2.UnmarshalText([]byte(strings.Repeat("ab", 20))))
+	assert.Error(t, json.Unmarshal([]byte(`"0x`+strings.Repeat("ab", 21)+`"`), &a2))
+}
+
+func TestHash32ScanBytes(t *testing.T) {
+	var h Hash32
+	require.NoError(t, h.ScanBytes(make([]byte, 32)))
+	assert.True(t, h.IsZero())
+
+	assert.Error(t, h.ScanBytes(make([]byte, 31)))
+	assert.Error(t, h.ScanBytes(nil))
+
+	b, err := Hash32{1}.BytesValue()
+	require.NoError(t, err)
+	assert.Len(t, b, 32)
+	assert.Equal(t, byte(1), b[0])
+}
@@ -0,0 +1,89 @@
+package dbtype
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON is a typed JSON/JSONB column value that may be null, ie.
+//
+//	type Account struct {
+//		Settings dbtype.JSON[Settings] `db:"settings,omitempty"`
+//	}
+//
+// A JSON value is zero, and omitted from inserts with `,omitempty`, only if
+// it is null, so a valid but empty document is still written.
+type JSON[T any] struct {
+	V       T
+	IsValid bool
+}
+
+func NewJSON[T any](v T) JSON[T] {
+	return JSON[T]{V: v, IsValid: true}
+}
+
+// IsZero reports whether j is null.
+func (j JSON[T]) IsZero() bool {
+	return !j.IsValid
+}
+
+// Value implements driver.Valuer.
+func (j JSON[T]) Value() (driver.Value, error) {
+	if !j.IsValid {
+		return nil, nil
+	}
+	data, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, fmt.Errorf("dbtype: JSON Value: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (j *JSON[T]) Scan(src interface{}) error {
+	var zero T
+	j.V, j.IsValid = zero, false
+	if src == nil {
+		return nil
+	}
+
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("dbtype: JSON Scan: unexpected type %T", src)
+	}
+
+	if err := json.Unmarshal(data, &j.V); err != nil {
+		return fmt.Errorf("dbtype: JSON Scan: %w", err)
+	}
+	j.IsValid = true
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (j JSON[T]) MarshalJSON() ([]byte, error) {
+	if !j.IsValid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(j.V)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (j *JSON[T]) UnmarshalJSON(data []byte) error {
+	var zero T
+	j.V, j.IsValid = zero, false
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(data, &j.V); err != nil {
+		return err
+	}
+	j.IsValid = true
+	return nil
+}
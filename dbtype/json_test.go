@@ -0,0 +1,58 @@
+package dbtype
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonSettings struct {
+	Theme string   `json:"theme"`
+	Tags  []string `json:"tags"`
+}
+
+func TestJSONValueAndScan(t *testing.T) {
+	j := NewJSON(jsonSettings{Theme: "dark", Tags: []string{"a"}})
+	assert.False(t, j.IsZero())
+
+	v, err := j.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"theme":"dark","tags":["a"]}`, v)
+
+	var j2 JSON[jsonSettings]
+	require.NoError(t, j2.Scan([]byte(v.(string))))
+	assert.Equal(t, j, j2)
+
+	require.NoError(t, j2.Scan(nil))
+	assert.True(t, j2.IsZero())
+	assert.Equal(t, jsonSettings{}, j2.V)
+
+	v, err = j2.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	assert.Error(t, j2.Scan(42))
+}
+
+func TestJSONZeroValue(t *testing.T) {
+	// a valid empty document is not zero
+	assert.False(t, NewJSON(jsonSettings{}).IsZero())
+	assert.True(t, JSON[jsonSettings]{}.IsZero())
+}
+
+func TestJSONMarshalling(t *testing.T) {
+	type wrapper struct {
+		Settings JSON[jsonSettings] `json:"settings"`
+	}
+
+	data, err := json.Marshal(wrapper{})
+	require.NoError(t, err)
+	assert.Equal(t, `{"settings":null}`, string(data))
+
+	var w wrapper
+	require.NoError(t, json.Unmarshal([]byte(`{"settings":{"theme":"light"}}`), &w))
+	assert.True(t, w.Settings.IsValid)
+	assert.Equal(t, "light", w.Settings.V.Theme)
+}
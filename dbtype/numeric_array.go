@@ -0,0 +1,303 @@
+package dbtype
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// BigIntArray is a NUMERIC[] column value. A nil BigIntArray is stored as
+// NULL, invalid BigInt elements as NULL elements. Multi-dimensional arrays
+// are flattened.
+type BigIntArray []BigInt
+
+// Dimensions implements pgx/pgtype.ArrayGetter
+func (a BigIntArray) Dimensions() []pgtype.ArrayDimension {
+	return arrayDimensions(a)
+}
+
+// Index implements pgx/pgtype.ArrayGetter
+func (a BigIntArray) Index(i int) interface{} {
+	return a[i]
+}
+
+// IndexType implements pgx/pgtype.ArrayGetter
+func (a BigIntArray) IndexType() interface{} {
+	return BigInt{}
+}
+
+// SetDimensions implements pgx/pgtype.ArraySetter
+func (a *BigIntArray) SetDimensions(dimensions []pgtype.ArrayDimension) error {
+	if dimensions == nil {
+		*a = nil
+		return nil
+	}
+	*a = make(BigIntArray, cardinality(dimensions))
+	return nil
+}
+
+// ScanIndex implements pgx/pgtype.ArraySetter
+func (a BigIntArray) ScanIndex(i int) interface{} {
+	return &a[i]
+}
+
+// ScanIndexType implements pgx/pgtype.ArraySetter
+func (a BigIntArray) ScanIndexType() interface{} {
+	return &BigInt{}
+}
+
+// Value implements driver.Valuer.
+func (a BigIntArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	elems := make([]string, len(a))
+	for i, b := range a {
+		if b.IsValid {
+			elems[i] = b.V.String()
+		} else {
+			elems[i] = "NULL"
+		}
+	}
+	return "{" + strings.Join(elems, ",") + "}", nil
+}
+
+// Scan implements sql.Scanner.
+func (a *BigIntArray) Scan(src interface{}) error {
+	elems, err := parseNumericArray(src)
+	if err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+
+	arr := make(BigIntArray, len(elems))
+	for i, e := range elems {
+		if e == "NULL" {
+			continue
+		}
+		if err := arr[i].Scan(e); err != nil {
+			return err
+		}
+	}
+	*a = arr
+	return nil
+}
+
+// DecimalArray is a NUMERIC[] column value holding exact decimals, ie.
+// amounts with a fractional part. A nil DecimalArray is stored as NULL, nil
+// elements as NULL elements. Elements must be finite decimals, so 1/3 can't
+// be stored. Multi-dimensional arrays are flattened.
+type DecimalArray []*big.Rat
+
+// Dimensions implements pgx/pgtype.ArrayGetter
+func (a DecimalArray) Dimensions() []pgtype.ArrayDimension {
+	return arrayDimensions(a)
+}
+
+// Index implements pgx/pgtype.ArrayGetter
+func (a DecimalArray) Index(i int) interface{} {
+	return decimalElem{a[i]}
+}
+
+// IndexType implements pgx/pgtype.ArrayGetter
+func (a DecimalArray) IndexType() interface{} {
+	return decimalElem{}
+}
+
+// SetDimensions implements pgx/pgtype.ArraySetter
+func (a *DecimalArray) SetDimensions(dimensions []pgtype.ArrayDimension) error {
+	if dimensions == nil {
+		*a = nil
+		return nil
+	}
+	*a = make(DecimalArray, cardinality(dimensions))
+	return nil
+}
+
+// ScanIndex implements pgx/pgtype.ArraySetter
+func (a DecimalArray) ScanIndex(i int) interface{} {
+	return &decimalScanner{dest: &a[i]}
+}
+
+// ScanIndexType implements pgx/pgtype.ArraySetter
+func (a DecimalArray) ScanIndexType() interface{} {
+	return &decimalScanner{}
+}
+
+// Value implements driver.Valuer.
+func (a DecimalArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	elems := make([]string, len(a))
+	for i, r := range a {
+		if r == nil {
+			elems[i] = "NULL"
+			continue
+		}
+		n, err := ratToNumeric(r)
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = numericString(n)
+	}
+	return "{" + strings.Join(elems, ",") + "}", nil
+}
+
+// Scan implements sql.Scanner.
+func (a *DecimalArray) Scan(src interface{}) error {
+	elems, err := parseNumericArray(src)
+	if err != nil || elems == nil {
+		*a = nil
+		return err
+	}
+
+	arr := make(DecimalArray, len(elems))
+	for i, e := range elems {
+		if e == "NULL" {
+			continue
+		}
+		r, ok := new(big.Rat).SetString(e)
+		if !ok {
+			return fmt.Errorf("dbtype: DecimalArray Scan: invalid numeric %q", e)
+		}
+		arr[i] = r
+	}
+	*a = arr
+	return nil
+}
+
+// decimalElem encodes a DecimalArray element.
+type decimalElem struct {
+	r *big.Rat
+}
+
+// NumericValue implements pgx/pgtype.NumericValuer
+func (d decimalElem) NumericValue() (pgtype.Numeric, error) {
+	if d.r == nil {
+		return pgtype.Numeric{}, nil
+	}
+	return ratToNumeric(d.r)
+}
+
+// decimalScanner scans a DecimalArray element.
+type decimalScanner struct {
+	dest **big.Rat
+}
+
+// ScanNumeric implements pgx/pgtype.NumericScanner
+func (d *decimalScanner) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		*d.dest = nil
+		return nil
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("dbtype: DecimalArray cannot hold NaN or infinite numerics")
+	}
+
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(v.Exp))), nil)
+	r := new(big.Rat).SetInt(v.Int)
+	if v.Exp >= 0 {
+		r.Mul(r, new(big.Rat).SetInt(pow))
+	} else {
+		r.Quo(r, new(big.Rat).SetInt(pow))
+	}
+	*d.dest = r
+	return nil
+}
+
+// ratToNumeric converts r to a numeric, failing if r is not a finite decimal,
+// ie. its denominator has prime factors other than 2 and 5.
+func ratToNumeric(r *big.Rat) (pgtype.Numeric, error) {
+	denom := new(big.Int).Set(r.Denom())
+	two, five := big.NewInt(2), big.NewInt(5)
+
+	var twos, fives int32
+	var rem big.Int
+	for {
+		if q, _ := new(big.Int).QuoRem(denom, two, &rem); rem.Sign() == 0 {
+			denom, twos = q, twos+1
+			continue
+		}
+		if q, _ := new(big.Int).QuoRem(denom, five, &rem); rem.Sign() == 0 {
+			denom, fives = q, fives+1
+			continue
+		}
+		break
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return pgtype.Numeric{}, fmt.Errorf("dbtype: %s is not a finite decimal", r.String())
+	}
+
+	scale := twos
+	if fives > scale {
+		scale = fives
+	}
+	// num * 10^scale / denom is an integer
+	i := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	i.Mul(i, r.Num())
+	i.Quo(i, r.Denom())
+	return pgtype.Numeric{Int: i, Exp: -scale, Valid: true}, nil
+}
+
+func numericString(n pgtype.Numeric) string {
+	s := n.Int.String()
+	if n.Exp == 0 {
+		return s
+	}
+	return s + "e" + fmt.Sprint(n.Exp)
+}
+
+func cardinality(dimensions []pgtype.ArrayDimension) int {
+	if len(dimensions) == 0 {
+		return 0
+	}
+	n := 1
+	for _, d := range dimensions {
+		n *= int(d.Length)
+	}
+	return n
+}
+
+func arrayDimensions[T any](a []T) []pgtype.ArrayDimension {
+	if a == nil {
+		return nil
+	}
+	return []pgtype.ArrayDimension{{Length: int32(len(a)), LowerBound: 1}}
+}
+
+// parseNumericArray splits the text representation of a NUMERIC[] into its
+// elements. Numeric elements are never quoted, so nested braces can simply
+// be dropped to flatten multi-dimensional arrays.
+func parseNumericArray(src interface{}) ([]string, error) {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return nil, fmt.Errorf("dbtype: numeric array Scan: unexpected type %T", src)
+	}
+
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("dbtype: numeric array Scan: invalid array %q", s)
+	}
+	s = strings.NewReplacer("{", "", "}", "").Replace(s)
+	if s == "" {
+		return []string{}, nil
+	}
+	return strings.Split(s, ","), nil
+}
+
+func abs(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
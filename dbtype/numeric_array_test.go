@@ -0,0 +1,77 @@
+package dbtype
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigIntArrayCodec(t *testing.T) {
+	m := pgtype.NewMap()
+
+	in := BigIntArray{NewBigInt(1), {}, NewBigIntFromString("123456789012345678901234567890", 10)}
+	buf, err := m.Encode(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, in, nil)
+	require.NoError(t, err)
+
+	var out BigIntArray
+	require.NoError(t, m.Scan(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, buf, &out))
+	require.Len(t, out, 3)
+	assert.Equal(t, "1", out[0].String())
+	assert.False(t, out[1].IsValid)
+	assert.Equal(t, "123456789012345678901234567890", out[2].String())
+
+	require.NoError(t, m.Scan(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, nil, &out))
+	assert.Nil(t, out)
+}
+
+func TestBigIntArrayValueAndScan(t *testing.T) {
+	v, err := BigIntArray{NewBigInt(1), {}, NewBigInt(-3)}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "{1,NULL,-3}", v)
+
+	var a BigIntArray
+	require.NoError(t, a.Scan("{{1,NULL},{3,4}}"))
+	require.Len(t, a, 4)
+	assert.False(t, a[1].IsValid)
+	assert.Equal(t, int64(4), a[3].Int64())
+
+	require.NoError(t, a.Scan("{}"))
+	assert.Equal(t, BigIntArray{}, a)
+	require.NoError(t, a.Scan(nil))
+	assert.Nil(t, a)
+}
+
+func TestDecimalArrayCodec(t *testing.T) {
+	m := pgtype.NewMap()
+
+	in := DecimalArray{big.NewRat(5, 4), nil, big.NewRat(-100, 1)}
+	buf, err := m.Encode(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, in, nil)
+	require.NoError(t, err)
+
+	var out DecimalArray
+	require.NoError(t, m.Scan(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, buf, &out))
+	require.Len(t, out, 3)
+	assert.Equal(t, "5/4", out[0].String())
+	assert.Nil(t, out[1])
+	assert.Equal(t, "-100/1", out[2].String())
+
+	_, err = m.Encode(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, DecimalArray{big.NewRat(1, 3)}, nil)
+	assert.Error(t, err)
+}
+
+func TestDecimalArrayValueAndScan(t *testing.T) {
+	v, err := DecimalArray{big.NewRat(5, 4), nil, big.NewRat(7, 1)}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "{125e-2,NULL,7}", v)
+
+	var a DecimalArray
+	require.NoError(t, a.Scan("{1.25,NULL,7}"))
+	require.Len(t, a, 3)
+	assert.Equal(t, 0, a[0].Cmp(big.NewRat(5, 4)))
+	assert.Nil(t, a[1])
+
+	assert.Error(t, a.Scan("{abc}"))
+}
@@ -0,0 +1,220 @@
+package dbtype
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID is a 128-bit identifier starting with its millisecond creation time,
+// so ids sort by creation time, which keeps btree primary key indexes
+// compact. It is stored in uuid or bytea columns and formatted as a
+// 26 character Crockford base32 string, see https://github.com/ulid/spec.
+//
+// Ids created within the same millisecond are not ordered among themselves.
+type ULID [16]byte
+
+// NewULID returns a new ULID for the current time.
+func NewULID() ULID {
+	return newTimeOrderedID(time.Now())
+}
+
+// NewUUIDv7 returns a new RFC 9562 UUIDv7 for the current time. It is
+// time-sortable like a ULID, from which it only differs by its version and
+// variant bits, and is usually formatted with UUIDString.
+func NewUUIDv7() ULID {
+	u := newTimeOrderedID(time.Now())
+	u[6] = (u[6] & 0x0f) | 0x70 // version 7
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 9562 variant
+	return u
+}
+
+func newTimeOrderedID(t time.Time) ULID {
+	var u ULID
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(u[:6], ms[2:])
+	if _, err := rand.Read(u[6:]); err != nil {
+		panic(fmt.Sprintf("dbtype: failed to read random bytes: %v", err))
+	}
+	return u
+}
+
+// ParseULID parses a ULID from its base32 string, or from the uuid string
+// format as returned by Postgres.
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	switch len(s) {
+	case 26:
+		if s[0] > '7' {
+			return ULID{}, fmt.Errorf("dbtype: ULID %q overflows 128 bits", s)
+		}
+		for i := 0; i < 26; i++ {
+			v, ok := crockfordValue(s[i])
+			if !ok {
+				return ULID{}, fmt.Errorf("dbtype: invalid ULID %q", s)
+			}
+			// the first char only holds the top 3 bits
+			for b := 0; b < 5; b++ {
+				bit := i*5 + b - 2
+				if bit >= 0 && v&(0x10>>b) != 0 {
+					u[bit/8] |= 0x80 >> (bit % 8)
+				}
+			}
+		}
+		return u, nil
+	case 36:
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return ULID{}, fmt.Errorf("dbtype: invalid uuid %q", s)
+		}
+		h := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+		if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+			return ULID{}, fmt.Errorf("dbtype: invalid uuid %q: %w", s, err)
+		}
+		return u, nil
+	default:
+		return ULID{}, fmt.Errorf("dbtype: invalid ULID %q", s)
+	}
+}
+
+// IsZero reports whether u is the zero ULID.
+func (u ULID) IsZero() bool {
+	return u == ULID{}
+}
+
+// Time returns the creation time encoded in u.
+func (u ULID) Time() time.Time {
+	var ms [8]byte
+	copy(ms[2:], u[:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(ms[:])))
+}
+
+// String returns u as a 26 character base32 string.
+func (u ULID) String() string {
+	var out [26]byte
+	// 26 chars of 5 bits hold 130 bits, the first char only the top 3
+	for i := 0; i < 26; i++ {
+		var v byte
+		for b := 0; b < 5; b++ {
+			bit := i*5 + b - 2
+			v <<= 1
+			if bit >= 0 && u[bit/8]&(0x80>>(bit%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockfordAlphabet[v]
+	}
+	return string(out[:])
+}
+
+// UUIDString returns u in the uuid string format.
+func (u ULID) UUIDString() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u ULID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *ULID) UnmarshalText(text []byte) error {
+	v, err := ParseULID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (u ULID) Value() (driver.Value, error) {
+	if u.IsZero() {
+		return nil, nil
+	}
+	return u.UUIDString(), nil
+}
+
+// Scan implements sql.Scanner.
+func (u *ULID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*u = ULID{}
+		return nil
+	case []byte:
+		if len(v) == 16 {
+			copy(u[:], v)
+			return nil
+		}
+		return u.UnmarshalText(v)
+	case string:
+		return u.UnmarshalText([]byte(v))
+	default:
+		return fmt.Errorf("dbtype: ULID Scan: unexpected type %T", src)
+	}
+}
+
+// ScanUUID implements pgx/pgtype.UUIDScanner
+func (u *ULID) ScanUUID(v pgtype.UUID) error {
+	if !v.Valid {
+		*u = ULID{}
+		return nil
+	}
+	*u = v.Bytes
+	return nil
+}
+
+// UUIDValue implements pgx/pgtype.UUIDValuer
+func (u ULID) UUIDValue() (pgtype.UUID, error) {
+	return pgtype.UUID{Bytes: u, Valid: !u.IsZero()}, nil
+}
+
+// ScanBytes implements pgx/pgtype.BytesScanner
+func (u *ULID) ScanBytes(v []byte) error {
+	if v == nil {
+		*u = ULID{}
+		return nil
+	}
+	if len(v) != 16 {
+		return fmt.Errorf("dbtype: ULID ScanBytes: expecting 16 bytes but received %d", len(v))
+	}
+	copy(u[:], v)
+	return nil
+}
+
+// BytesValue implements pgx/pgtype.BytesValuer
+func (u ULID) BytesValue() ([]byte, error) {
+	if u.IsZero() {
+		return nil, nil
+	}
+	return u[:], nil
+}
+
+func crockfordValue(c byte) (byte, bool) {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for i := 0; i < len(crockfordAlphabet); i++ {
+		if crockfordAlphabet[i] == c {
+			return byte(i), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,76 @@
+package dbtype
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestULIDString(t *testing.T) {
+	// example from the ULID spec
+	u, err := ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	require.NoError(t, err)
+	assert.Equal(t, "01ARZ3NDEKTSV4RRFFQ69G5FAV", u.String())
+	assert.Equal(t, int64(1469922850259), u.Time().UnixMilli())
+
+	lower, err := ParseULID("01arz3ndektsv4rrffq69g5fav")
+	require.NoError(t, err)
+	assert.Equal(t, u, lower)
+
+	u2, err := ParseULID(u.UUIDString())
+	require.NoError(t, err)
+	assert.Equal(t, u, u2)
+
+	_, err = ParseULID("81ARZ3NDEKTSV4RRFFQ69G5FAV")
+	assert.Error(t, err)
+	_, err = ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAU")
+	assert.Error(t, err)
+}
+
+func TestULIDOrdering(t *testing.T) {
+	t0 := time.Now()
+	ids := []ULID{newTimeOrderedID(t0.Add(2 * time.Millisecond)), newTimeOrderedID(t0), newTimeOrderedID(t0.Add(time.Millisecond))}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	assert.Equal(t, t0.UnixMilli(), ids[0].Time().UnixMilli())
+	assert.Equal(t, t0.Add(2*time.Millisecond).UnixMilli(), ids[2].Time().UnixMilli())
+}
+
+func TestUUIDv7(t *testing.T) {
+	u := NewUUIDv7()
+	s := u.UUIDString()
+	assert.Equal(t, byte('7'), s[14])
+	assert.Contains(t, "89ab", string(s[19]))
+	assert.WithinDuration(t, time.Now(), u.Time(), time.Second)
+}
+
+func TestULIDScanAndValue(t *testing.T) {
+	u := NewULID()
+
+	v, err := u.Value()
+	require.NoError(t, err)
+
+	var u2 ULID
+	require.NoError(t, u2.Scan(v))
+	assert.Equal(t, u, u2)
+
+	require.NoError(t, u2.Scan(u[:]))
+	assert.Equal(t, u, u2)
+
+	pu, err := u.UUIDValue()
+	require.NoError(t, err)
+	require.NoError(t, u2.ScanUUID(pu))
+	assert.Equal(t, u, u2)
+
+	require.NoError(t, u2.ScanUUID(pgtype.UUID{}))
+	assert.True(t, u2.IsZero())
+
+	data, err := json.Marshal(u)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &u2))
+	assert.Equal(t, u, u2)
+}
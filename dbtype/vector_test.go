@@ -0,0 +1,27 @@
+package dbtype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVectorValueAndScan(t *testing.T) {
+	v, err := Vector{1, 0.5, -2.25}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "[1,0.5,-2.25]", v)
+
+	var vec Vector
+	require.NoError(t, vec.Scan("[1,0.5,-2.25]"))
+	assert.Equal(t, Vector{1, 0.5, -2.25}, vec)
+
+	require.NoError(t, vec.Scan([]byte("[]")))
+	assert.Equal(t, Vector{}, vec)
+
+	require.NoError(t, vec.Scan(nil))
+	assert.Nil(t, vec)
+
+	assert.Error(t, vec.Scan("{1,2}"))
+	assert.Error(t, vec.Scan("[1,x]"))
+}
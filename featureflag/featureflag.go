@@ -0,0 +1,129 @@
+// Package featureflag reads feature flags from a kvstore table into an
+// in-process cache, refreshed on change through NOTIFY, see Flags.Watch.
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/goware/pgkit/v2/cdc"
+	"github.com/goware/pgkit/v2/kvstore"
+)
+
+// Flags is an in-process cache of the feature flags stored under a key prefix
+// of a kvstore table, ie. "flags.". Flag values are JSON encoded, and read
+// with the typed getters, which return the given default for missing flags
+// and values of another type. It is safe for concurrent use.
+type Flags struct {
+	Store  *kvstore.Store
+	Prefix string
+
+	mu     sync.RWMutex
+	values map[string]json.RawMessage
+}
+
+// New returns the flags stored under the key prefix of the store. Call
+// Refresh or Watch to load them.
+func New(store *kvstore.Store, prefix string) *Flags {
+	return &Flags{Store: store, Prefix: prefix, values: map[string]json.RawMessage{}}
+}
+
+// Refresh reloads all flags from the store.
+func (f *Flags) Refresh(ctx context.Context) error {
+	entries, err := f.Store.List(ctx, f.Prefix)
+	if err != nil {
+		return fmt.Errorf("featureflag: refresh: %w", err)
+	}
+
+	values := make(map[string]json.RawMessage, len(entries))
+	for _, entry := range entries {
+		values[strings.TrimPrefix(entry.Key, f.Prefix)] = entry.Value
+	}
+
+	f.mu.Lock()
+	f.values = values
+	f.mu.Unlock()
+	return nil
+}
+
+// InstallTrigger installs the trigger notifying the channel of the changes of
+// the store table, which Watch listens on. It needs to run once, ie. along
+// with the schema migrations.
+func (f *Flags) InstallTrigger(ctx context.Context, channel string) error {
+	return cdc.InstallNotifyTrigger(ctx, f.Store.DB, f.Store.TableName, channel)
+}
+
+// Watch loads the flags, and reloads them whenever the channel is notified
+// of a change of the store table, see InstallTrigger, until the context is
+// done. The flags are loaded once listening on the channel, so no change is
+// missed in between, and flags changed while no one is watching, ie. between
+// reconnects, are picked up by the load. Watch holds a connection of the pool
+// of the store for its whole lifetime.
+func (f *Flags) Watch(ctx context.Context, channel string) error {
+	return cdc.SubscribeReady(ctx, f.Store.DB, channel, f.Refresh, func(ctx context.Context, ev cdc.Event) error {
+		if !f.changed(ev) {
+			return nil
+		}
+		return f.Refresh(ctx)
+	})
+}
+
+// changed reports whether the change event is about a flag of the prefix. The
+// key is the primary key of the store table, so it is part of the events of
+// values too large for a NOTIFY payload too, see cdc.Event.Truncated.
+func (f *Flags) changed(ev cdc.Event) bool {
+	for _, row := range []map[string]interface{}{ev.Old, ev.New} {
+		if key, ok := row["key"].(string); ok && strings.HasPrefix(key, f.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Set stores the value of the flag. Watching caches pick it up on the change
+// notification.
+func (f *Flags) Set(ctx context.Context, name string, value interface{}) error {
+	return f.Store.Set(ctx, f.Prefix+name, value, 0)
+}
+
+// Bool returns the value of the boolean flag, or def.
+func (f *Flags) Bool(name string, def bool) bool {
+	return Get(f, name, def)
+}
+
+// String returns the value of the string flag, or def.
+func (f *Flags) String(name string, def string) string {
+	return Get(f, name, def)
+}
+
+// Int returns the value of the integer flag, or def.
+func (f *Flags) Int(name string, def int64) int64 {
+	return Get(f, name, def)
+}
+
+// Float returns the value of the number flag, or def.
+func (f *Flags) Float(name string, def float64) float64 {
+	return Get(f, name, def)
+}
+
+// Get returns the value of the flag decoded into a T, or def if the flag is
+// missing or doesn't decode into a T, ie.
+//
+//	limits := featureflag.Get(flags, "limits", DefaultLimits)
+func Get[T any](f *Flags, name string, def T) T {
+	f.mu.RLock()
+	value, ok := f.values[name]
+	f.mu.RUnlock()
+	if !ok {
+		return def
+	}
+
+	var v T
+	if err := json.Unmarshal(value, &v); err != nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,42 @@
+package featureflag
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/goware/pgkit/v2/cdc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetters(t *testing.T) {
+	f := New(nil, "flags.")
+	f.values = map[string]json.RawMessage{
+		"checkout": json.RawMessage(`true`),
+		"theme":    json.RawMessage(`"dark"`),
+		"limit":    json.RawMessage(`100`),
+		"ratio":    json.RawMessage(`0.25`),
+		"limits":   json.RawMessage(`{"max": 5}`),
+	}
+
+	assert.True(t, f.Bool("checkout", false))
+	assert.Equal(t, "dark", f.String("theme", "light"))
+	assert.Equal(t, int64(100), f.Int("limit", 10))
+	assert.Equal(t, 0.25, f.Float("ratio", 1))
+
+	type limits struct {
+		Max int `json:"max"`
+	}
+	assert.Equal(t, limits{Max: 5}, Get(f, "limits", limits{Max: 1}))
+
+	// missing or of another type
+	assert.True(t, f.Bool("missing", true))
+	assert.Equal(t, int64(10), f.Int("theme", 10))
+}
+
+func TestChanged(t *testing.T) {
+	f := New(nil, "flags.")
+	assert.True(t, f.changed(cdc.Event{New: map[string]interface{}{"key": "flags.checkout"}}))
+	assert.True(t, f.changed(cdc.Event{Old: map[string]interface{}{"key": "flags.checkout"}}))
+	assert.False(t, f.changed(cdc.Event{New: map[string]interface{}{"key": "config.smtp"}}))
+	assert.True(t, f.changed(cdc.Event{Truncated: true, New: map[string]interface{}{"key": "flags.checkout"}}))
+}
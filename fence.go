@@ -0,0 +1,67 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrReadOnlyReplica is returned by DBs with write fencing, see
+// Config.WriteFencing, when the server they are connected to is a read-only
+// replica, ie. the former primary after a failover.
+var ErrReadOnlyReplica = errors.New("connected to a read-only replica")
+
+// fenceWrites checks each new connection of the pool, so reconnects after a
+// DNS-based failover can't land on a read-only server unnoticed.
+func fenceWrites(poolCfg *pgxpool.Config) {
+	afterConnect := poolCfg.AfterConnect
+	poolCfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		if afterConnect != nil {
+			if err := afterConnect(ctx, conn); err != nil {
+				return err
+			}
+		}
+		return checkWritable(ctx, conn)
+	}
+}
+
+// checkWritable returns ErrReadOnlyReplica if the server of the connection is
+// in recovery, or only accepts read-only transactions.
+func checkWritable(ctx context.Context, conn *pgx.Conn) error {
+	var readOnly bool
+	err := conn.QueryRow(ctx, "SELECT pg_is_in_recovery() OR current_setting('transaction_read_only')::bool").Scan(&readOnly)
+	if err != nil {
+		return fmt.Errorf("pgkit: failed to check server is writable: %w", err)
+	}
+	if readOnly {
+		return ErrReadOnlyReplica
+	}
+	return nil
+}
+
+// fence returns the QueryFunc reporting the writes of next rejected by a
+// read-only server as ErrReadOnlyReplica. The pool is reset, so its
+// connections to the read-only server are replaced by ones checked on
+// connect.
+func (q *Querier) fence(next QueryFunc) QueryFunc {
+	return func(ctx context.Context, stmt Statement) (Result, error) {
+		res, err := next(ctx, stmt)
+		if q.tx != nil || !isReadOnlyErr(err) {
+			return res, err
+		}
+		q.pool.Reset()
+		return res, fmt.Errorf("%w: %w", ErrReadOnlyReplica, err)
+	}
+}
+
+// isReadOnlyErr reports whether the error is read_only_sql_transaction. Pool
+// statements only run in read-only transactions on read-only servers, those
+// of explicit transactions may be read-only on purpose.
+func isReadOnlyErr(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "25006"
+}
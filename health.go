@@ -0,0 +1,187 @@
+package pgkit
+
+import (
+	"context"
+)
+
+// UnusedIndex is a non-unique index which has not been scanned since the
+// statistics were last reset.
+type UnusedIndex struct {
+	Schema    string `db:"schema_name"`
+	Table     string `db:"table_name"`
+	Index     string `db:"index_name"`
+	SizeBytes int64  `db:"size_bytes"`
+}
+
+// MissingIndexCandidate is a table which is mostly read with sequential scans
+// and may be missing an index.
+type MissingIndexCandidate struct {
+	Schema     string `db:"schema_name"`
+	Table      string `db:"table_name"`
+	SeqScans   int64  `db:"seq_scan"`
+	SeqTupRead int64  `db:"seq_tup_read"`
+	IdxScans   int64  `db:"idx_scan"`
+	LiveRows   int64  `db:"n_live_tup"`
+}
+
+// BloatEstimate is the estimated wasted space of a table or index. Index is
+// empty for tables.
+type BloatEstimate struct {
+	Schema     string  `db:"schema_name"`
+	Table      string  `db:"table_name"`
+	Index      string  `db:"index_name"`
+	SizeBytes  int64   `db:"size_bytes"`
+	BloatBytes int64   `db:"bloat_bytes"`
+	BloatRatio float64 `db:"bloat_ratio"`
+}
+
+type HealthReportOptions struct {
+	// MinSeqScans is the min number of sequential scans for a table to be
+	// reported as a missing index candidate. Default is 1000.
+	MinSeqScans int64
+	// MinRows is the min number of live rows for a table to be reported as a
+	// missing index candidate. Default is 10000.
+	MinRows int64
+	// MinBloatBytes is the min estimated bloat for a table or index to be
+	// reported. Default is 10MB.
+	MinBloatBytes int64
+}
+
+// HealthReport is the result of Querier.HealthReport.
+type HealthReport struct {
+	UnusedIndexes  []*UnusedIndex
+	MissingIndexes []*MissingIndexCandidate
+	TableBloat     []*BloatEstimate
+	IndexBloat     []*BloatEstimate
+}
+
+// HealthReport collects unused indexes, missing index candidates and bloat
+// estimates from the catalog and statistics views, ie. for a periodic ops job.
+func (q *Querier) HealthReport(ctx context.Context, opts *HealthReportOptions) (*HealthReport, error) {
+	var o HealthReportOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MinSeqScans == 0 {
+		o.MinSeqScans = 1000
+	}
+	if o.MinRows == 0 {
+		o.MinRows = 10000
+	}
+	if o.MinBloatBytes == 0 {
+		o.MinBloatBytes = 10 << 20
+	}
+
+	var (
+		report = &HealthReport{}
+		err    error
+	)
+	if report.UnusedIndexes, err = q.UnusedIndexes(ctx); err != nil {
+		return nil, err
+	}
+	if report.MissingIndexes, err = q.MissingIndexCandidates(ctx, o.MinSeqScans, o.MinRows); err != nil {
+		return nil, err
+	}
+	if report.TableBloat, err = q.TableBloat(ctx, o.MinBloatBytes); err != nil {
+		return nil, err
+	}
+	if report.IndexBloat, err = q.IndexBloat(ctx, o.MinBloatBytes); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// UnusedIndexes returns the non-unique indexes which have never been scanned,
+// largest first. Index usage is tracked per server, so check all replicas
+// serving reads before dropping an index.
+func (q *Querier) UnusedIndexes(ctx context.Context) ([]*UnusedIndex, error) {
+	var out []*UnusedIndex
+	err := q.GetAll(ctx, RawSQL{Query: `
+		SELECT s.schemaname AS schema_name, s.relname AS table_name, s.indexrelname AS index_name,
+		  pg_relation_size(s.indexrelid) AS size_bytes
+		FROM pg_stat_user_indexes s
+		JOIN pg_index i ON i.indexrelid = s.indexrelid
+		WHERE s.idx_scan = 0 AND NOT i.indisunique AND NOT i.indisprimary
+		ORDER BY size_bytes DESC`}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MissingIndexCandidates returns the tables with at least minRows live rows
+// which were sequentially scanned at least minSeqScans times, and more often
+// than with an index, ordered by rows read sequentially.
+func (q *Querier) MissingIndexCandidates(ctx context.Context, minSeqScans, minRows int64) ([]*MissingIndexCandidate, error) {
+	var out []*MissingIndexCandidate
+	err := q.GetAll(ctx, RawQuery(`
+		SELECT schemaname AS schema_name, relname AS table_name, seq_scan, seq_tup_read,
+		  COALESCE(idx_scan, 0) AS idx_scan, n_live_tup
+		FROM pg_stat_user_tables
+		WHERE seq_scan >= ? AND n_live_tup >= ? AND seq_scan > COALESCE(idx_scan, 0)
+		ORDER BY seq_tup_read DESC`).Build(minSeqScans, minRows), &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TableBloat returns the tables with an estimated bloat of at least minBytes,
+// most bloated first. The estimate compares the table size with the size
+// expected from the row count and the average column widths in pg_stats, so
+// it is rough and only covers analyzed tables, but good enough to spot
+// outliers.
+func (q *Querier) TableBloat(ctx context.Context, minBytes int64) ([]*BloatEstimate, error) {
+	var out []*BloatEstimate
+	err := q.GetAll(ctx, RawQuery(`
+		SELECT schema_name, table_name, '' AS index_name, size_bytes,
+		  GREATEST(size_bytes - expected_bytes, 0) AS bloat_bytes,
+		  CASE WHEN size_bytes > 0 THEN GREATEST(size_bytes - expected_bytes, 0)::float8 / size_bytes ELSE 0 END AS bloat_ratio
+		FROM (
+		  SELECT n.nspname AS schema_name, c.relname AS table_name, pg_relation_size(c.oid) AS size_bytes,
+		    -- 24 bytes tuple header, 4 bytes line pointer
+		    (c.reltuples * (SUM(s.avg_width) + 28))::bigint AS expected_bytes
+		  FROM pg_class c
+		  JOIN pg_namespace n ON n.oid = c.relnamespace
+		  JOIN pg_stats s ON s.schemaname = n.nspname AND s.tablename = c.relname
+		  WHERE c.relkind = 'r' AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  GROUP BY n.nspname, c.relname, c.oid, c.reltuples
+		) t
+		WHERE size_bytes - expected_bytes >= ?
+		ORDER BY bloat_bytes DESC`).Build(minBytes), &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IndexBloat returns the btree indexes with an estimated bloat of at least
+// minBytes, most bloated first. The estimate is as rough as TableBloat's.
+func (q *Querier) IndexBloat(ctx context.Context, minBytes int64) ([]*BloatEstimate, error) {
+	var out []*BloatEstimate
+	err := q.GetAll(ctx, RawQuery(`
+		SELECT schema_name, table_name, index_name, size_bytes,
+		  GREATEST(size_bytes - expected_bytes, 0) AS bloat_bytes,
+		  CASE WHEN size_bytes > 0 THEN GREATEST(size_bytes - expected_bytes, 0)::float8 / size_bytes ELSE 0 END AS bloat_ratio
+		FROM (
+		  SELECT n.nspname AS schema_name, t.relname AS table_name, c.relname AS index_name,
+		    pg_relation_size(c.oid) AS size_bytes,
+		    -- 8 bytes index tuple header, 4 bytes line pointer, 90% btree fillfactor
+		    (c.reltuples * (SUM(s.avg_width) + 12) / 0.9)::bigint AS expected_bytes
+		  FROM pg_index i
+		  JOIN pg_class c ON c.oid = i.indexrelid
+		  JOIN pg_class t ON t.oid = i.indrelid
+		  JOIN pg_namespace n ON n.oid = c.relnamespace
+		  JOIN pg_am am ON am.oid = c.relam AND am.amname = 'btree'
+		  JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = ANY(i.indkey)
+		  JOIN pg_stats s ON s.schemaname = n.nspname AND s.tablename = t.relname AND s.attname = a.attname
+		  WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  GROUP BY n.nspname, t.relname, c.relname, c.oid, c.reltuples
+		) t
+		WHERE size_bytes - expected_bytes >= ?
+		ORDER BY bloat_bytes DESC`).Build(minBytes), &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,89 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// historyFunction is the trigger function installed by HistoryTable.Install.
+// Every inserted or updated row version is copied into the history table
+// with an open validity range, closing the range of the previous version.
+const historyFunction = `CREATE OR REPLACE FUNCTION pgkit_history() RETURNS trigger AS $$
+DECLARE
+  history text := quote_ident(TG_TABLE_SCHEMA) || '.' || quote_ident(TG_TABLE_NAME || '_history');
+  id_col text := quote_ident(TG_ARGV[0]);
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    EXECUTE format('UPDATE %s SET valid_to = now() WHERE %s = ($1).%s AND valid_to = ''infinity''', history, id_col, id_col) USING OLD;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    EXECUTE format('INSERT INTO %s SELECT ($1).*, now(), ''infinity''', history) USING NEW;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`
+
+// HistoryTable maintains a `<table>_history` side table capturing every
+// version of the records of a table, with a [valid_from, valid_to) validity
+// range, so the state of a record at any point in time can be read back
+// with GetAsOf.
+//
+// Versions are captured by a trigger, so they are recorded for all writes
+// regardless of the client. Records which existed before Install have no
+// history until they're next updated. The history table is created with the
+// columns of the table at install time, so schema changes to the table must
+// be applied to the history table as well.
+type HistoryTable struct {
+	TableName string
+	IDColumn  string
+}
+
+// Name returns the name of the history table.
+func (h HistoryTable) Name() string {
+	return h.TableName + "_history"
+}
+
+// Install creates the history table and the trigger maintaining it.
+func (h HistoryTable) Install(ctx context.Context, q *Querier) error {
+	table := pgx.Identifier(strings.Split(h.TableName, ".")).Sanitize()
+	history := pgx.Identifier(strings.Split(h.Name(), ".")).Sanitize()
+	trigger := pgx.Identifier{"pgkit_history_" + strings.ReplaceAll(h.TableName, ".", "_")}.Sanitize()
+	idColumn := "'" + strings.ReplaceAll(h.IDColumn, "'", "''") + "'"
+
+	queries := Queries{
+		RawSQL{Query: fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (LIKE %s, valid_from TIMESTAMP WITH TIME ZONE NOT NULL, valid_to TIMESTAMP WITH TIME ZONE NOT NULL)`, history, table)},
+		RawSQL{Query: fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (%s, valid_from)`,
+			pgx.Identifier{strings.ReplaceAll(h.Name(), ".", "_") + "_idx"}.Sanitize(), history, pgx.Identifier{h.IDColumn}.Sanitize())},
+		RawSQL{Query: historyFunction},
+		RawSQL{Query: fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s`, trigger, table)},
+		RawSQL{Query: fmt.Sprintf(`CREATE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION pgkit_history(%s)`, trigger, table, idColumn)},
+	}
+
+	if _, err := q.BatchExec(ctx, queries); err != nil {
+		return fmt.Errorf("pgkit: install history for %s: %w", h.TableName, err)
+	}
+	return nil
+}
+
+// GetAsOf scans the version of the record with the given id which was valid
+// at time t into dest. It returns ErrNoRows if the record didn't exist then.
+func (h HistoryTable) GetAsOf(ctx context.Context, q *Querier, id interface{}, t time.Time, dest interface{}) error {
+	query := q.SQL.Select("*").From(Ident(h.Name()).String()).Where(sq.And{
+		sq.Eq{Ident(h.IDColumn).String(): id},
+		sq.LtOrEq{"valid_from": t},
+		sq.Gt{"valid_to": t},
+	})
+	return q.GetOne(ctx, query, dest)
+}
+
+// Versions scans all versions of the record with the given id into dest,
+// oldest first.
+func (h HistoryTable) Versions(ctx context.Context, q *Querier, id interface{}, dest interface{}) error {
+	query := q.SQL.Select("*").From(Ident(h.Name()).String()).Where(sq.Eq{Ident(h.IDColumn).String(): id}).OrderBy("valid_from ASC")
+	return q.GetAll(ctx, query, dest)
+}
@@ -0,0 +1,49 @@
+package pgkit
+
+import (
+	"context"
+	"reflect"
+)
+
+// AfterLoader is implemented by records which derive in-memory fields after
+// being loaded, ie. to decompress a column. AfterLoad is called by
+// Querier.GetOne and Querier.GetAll for every record scanned.
+type AfterLoader interface {
+	AfterLoad(ctx context.Context) error
+}
+
+// afterLoad calls AfterLoad on dest, or on every element if dest is a pointer
+// to a slice, when implemented by the record type.
+func afterLoad(ctx context.Context, dest interface{}) error {
+	if loader, ok := dest.(AfterLoader); ok {
+		return wrapErr(loader.AfterLoad(ctx))
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return nil
+	}
+	slice := v.Elem()
+
+	elemT := slice.Type().Elem()
+	if !elemT.Implements(afterLoaderType) && !reflect.PointerTo(elemT).Implements(afterLoaderType) {
+		return nil
+	}
+
+	for i := 0; i < slice.Len(); i++ {
+		elem := slice.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		} else if elem.IsNil() {
+			continue
+		}
+		if loader, ok := elem.Interface().(AfterLoader); ok {
+			if err := loader.AfterLoad(ctx); err != nil {
+				return wrapErr(err)
+			}
+		}
+	}
+	return nil
+}
+
+var afterLoaderType = reflect.TypeOf((*AfterLoader)(nil)).Elem()
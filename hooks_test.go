@@ -0,0 +1,46 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type loadedRecord struct {
+	Name  string
+	Upper string
+}
+
+func (r *loadedRecord) AfterLoad(ctx context.Context) error {
+	if r.Name == "" {
+		return errors.New("empty name")
+	}
+	r.Upper = strings.ToUpper(r.Name)
+	return nil
+}
+
+func TestAfterLoad(t *testing.T) {
+	ctx := context.Background()
+
+	one := &loadedRecord{Name: "joe"}
+	require.NoError(t, afterLoad(ctx, one))
+	assert.Equal(t, "JOE", one.Upper)
+
+	ptrs := []*loadedRecord{{Name: "a"}, nil, {Name: "b"}}
+	require.NoError(t, afterLoad(ctx, &ptrs))
+	assert.Equal(t, "A", ptrs[0].Upper)
+	assert.Equal(t, "B", ptrs[2].Upper)
+
+	values := []loadedRecord{{Name: "c"}}
+	require.NoError(t, afterLoad(ctx, &values))
+	assert.Equal(t, "C", values[0].Upper)
+
+	require.Error(t, afterLoad(ctx, &[]loadedRecord{{}}))
+
+	// records without the hook are left alone
+	require.NoError(t, afterLoad(ctx, &[]string{"x"}))
+}
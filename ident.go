@@ -0,0 +1,70 @@
+package pgkit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxIdentLen is the max length of a Postgres identifier, NAMEDATALEN - 1.
+const maxIdentLen = 63
+
+// Ident is a SQL identifier, ie. a table, column or schema name, optionally
+// qualified with dots, ie. "tenant_1.accounts". It formats as the quoted
+// identifier, so it can be passed to RawQueryf and fmt.Sprintf without
+// inviting SQL injection:
+//
+//	pgkit.RawQueryf("SELECT * FROM %s WHERE id = ?", pgkit.Ident(tableName))
+//
+// Prefer RawQueryIdent, which also validates the identifiers.
+type Ident string
+
+// String returns the quoted identifier.
+func (i Ident) String() string {
+	return pgx.Identifier(strings.Split(string(i), ".")).Sanitize()
+}
+
+// Validate checks every part of the identifier is non-empty and fits the
+// Postgres identifier length limit.
+func (i Ident) Validate() error {
+	for _, part := range strings.Split(string(i), ".") {
+		if part == "" {
+			return fmt.Errorf("pgkit: invalid identifier %q: empty name", string(i))
+		}
+		if len(part) > maxIdentLen {
+			return fmt.Errorf("pgkit: invalid identifier %q: longer than %d bytes", string(i), maxIdentLen)
+		}
+		if strings.ContainsRune(part, 0) {
+			return fmt.Errorf("pgkit: invalid identifier %q: contains NUL byte", string(i))
+		}
+	}
+	return nil
+}
+
+// RawQueryIdent is like RawQuery, but first replaces each %I verb of the query
+// with the next identifier, validated and quoted, like Postgres's format().
+// Values are still passed as ? placeholders at Build time, ie.
+//
+//	stmt := pgkit.RawQueryIdent("SELECT * FROM %I WHERE %I = ?", partition, column)
+//	rows, err := db.Query.QueryRows(ctx, stmt.Build(id))
+func RawQueryIdent(queryFormat string, idents ...string) RawStatement {
+	parts := strings.Split(queryFormat, "%I")
+	if len(parts)-1 != len(idents) {
+		return RawStatement{err: fmt.Errorf("pgkit: expecting %d identifiers but received %d", len(parts)-1, len(idents))}
+	}
+
+	var q strings.Builder
+	for i, part := range parts {
+		q.WriteString(part)
+		if i == len(idents) {
+			break
+		}
+		ident := Ident(idents[i])
+		if err := ident.Validate(); err != nil {
+			return RawStatement{err: err}
+		}
+		q.WriteString(ident.String())
+	}
+	return RawQuery(q.String())
+}
@@ -0,0 +1,83 @@
+package pgkit
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/goware/pgkit/v2/internal/reflectx"
+)
+
+// Columns returns the `db:""` tagged columns of the record struct qualified
+// with the table alias, ie. "a.id". Nested struct fields are skipped, see
+// NestedColumns.
+func Columns(tableAlias string, record interface{}) []string {
+	var cols []string
+	for _, fi := range recordColumns(record) {
+		cols = append(cols, qualify(tableAlias, fi.Name))
+	}
+	return cols
+}
+
+// NestedColumns returns the columns of the record struct qualified with the
+// table alias and aliased with the prefix, ie. `acc.name AS "account.name"`,
+// so they are scanned into the nested struct field tagged `db:"account"` of
+// the destination. This makes one-to-one joins scan into nested structs:
+//
+//	type ArticleWithAccount struct {
+//		Article
+//		Account Account `db:"account"`
+//	}
+//
+//	q := db.SQL.Select(pgkit.Columns("a", &Article{})...).
+//		Columns(pgkit.NestedColumns("acc", "account", &Account{})...).
+//		From("articles a").
+//		Join("accounts acc ON acc.id = a.account_id")
+//
+// For left joins, the fields of the nested struct must be nullable types.
+func NestedColumns(tableAlias, prefix string, record interface{}) []string {
+	var cols []string
+	for _, fi := range recordColumns(record) {
+		cols = append(cols, qualify(tableAlias, fi.Name)+` AS "`+prefix+"."+fi.Name+`"`)
+	}
+	return cols
+}
+
+// recordColumns returns the fields of the tagged columns of the record
+// struct, excluding nested struct fields and their children.
+func recordColumns(record interface{}) []*reflectx.FieldInfo {
+	t := reflect.TypeOf(record)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []*reflectx.FieldInfo
+	for _, fi := range Mapper.TypeMap(t).Index {
+		if !strings.Contains(string(fi.Field.Tag), dbTagPrefix) || strings.Contains(fi.Path, ".") || fi.Name == "-" {
+			continue
+		}
+		if hasTaggedChildren(fi) {
+			continue
+		}
+		fields = append(fields, fi)
+	}
+	return fields
+}
+
+func hasTaggedChildren(fi *reflectx.FieldInfo) bool {
+	for _, child := range fi.Children {
+		if child != nil && strings.Contains(string(child.Field.Tag), dbTagPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func qualify(tableAlias, column string) string {
+	if tableAlias == "" {
+		return column
+	}
+	return tableAlias + "." + column
+}
@@ -0,0 +1,31 @@
+package pgkit_test
+
+import (
+	"testing"
+
+	"github.com/goware/pgkit/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedColumns(t *testing.T) {
+	type account struct {
+		ID   int64  `db:"id,omitempty"`
+		Name string `db:"name"`
+		Note string
+	}
+	type article struct {
+		ID      int64   `db:"id"`
+		Title   string  `db:"title"`
+		Account account `db:"account"`
+	}
+
+	require.Equal(t, []string{"a.id", "a.title"}, pgkit.Columns("a", &article{}))
+	require.Equal(t, []string{"id", "name"}, pgkit.Columns("", account{}))
+	type articleWithAccount struct {
+		article
+		Author account `db:"author"`
+	}
+	require.Equal(t, []string{"a.id", "a.title"}, pgkit.Columns("a", &articleWithAccount{}))
+
+	require.Equal(t, []string{`acc.id AS "account.id"`, `acc.name AS "account.name"`}, pgkit.NestedColumns("acc", "account", &account{}))
+}
@@ -0,0 +1,149 @@
+// Package kvstore is a small key-value store over a keyed JSONB table, ie.
+// for feature flags and small config blobs.
+package kvstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/goware/pgkit/v2"
+)
+
+// ErrNotFound is returned by Get when the key doesn't exist or has expired.
+var ErrNotFound = pgkit.ErrNoRows
+
+// Store reads and writes values to a table of the schema:
+//
+//	CREATE TABLE kv (
+//	  key TEXT PRIMARY KEY,
+//	  value JSONB NOT NULL,
+//	  expires_at TIMESTAMPTZ,
+//	  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+//	);
+//
+// which CreateTable creates if it doesn't exist. The table name, optionally
+// schema-qualified, is quoted as an identifier.
+//
+// Values are encoded with encoding/json into the JSONB column. There is no
+// dedicated JSONB type in pgkit to hand them to the driver as is.
+type Store struct {
+	DB        *pgkit.DB
+	TableName string
+}
+
+// New returns a Store over the given table.
+func New(db *pgkit.DB, tableName string) *Store {
+	return &Store{DB: db, TableName: tableName}
+}
+
+// Entry is a stored key and its raw JSON value.
+type Entry struct {
+	Key       string          `db:"key"`
+	Value     json.RawMessage `db:"value"`
+	ExpiresAt *time.Time      `db:"expires_at"`
+	UpdatedAt time.Time       `db:"updated_at"`
+}
+
+// CreateTable creates the store table if it doesn't exist.
+func (s *Store) CreateTable(ctx context.Context) error {
+	_, err := s.DB.Query.Exec(ctx, pgkit.RawSQL{Query: fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		key TEXT PRIMARY KEY,
+		value JSONB NOT NULL,
+		expires_at TIMESTAMPTZ,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`, s.table())})
+	if err != nil {
+		return fmt.Errorf("kvstore: create table %s: %w", s.TableName, err)
+	}
+	return nil
+}
+
+// Get decodes the value of the key into dest. It returns ErrNotFound if the
+// key doesn't exist or has expired.
+func (s *Store) Get(ctx context.Context, key string, dest interface{}) error {
+	q := s.DB.SQL.Select("value").From(s.table()).Where(sq.Eq{"key": key}).Where(notExpired)
+
+	var value []byte
+	if err := s.DB.Query.QueryRow(ctx, q).Scan(&value); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(value, dest); err != nil {
+		return fmt.Errorf("kvstore: decode %q: %w", key, err)
+	}
+	return nil
+}
+
+// Set stores the JSON encoded value under the key, replacing any previous
+// value. A ttl of zero means the key never expires.
+func (s *Store) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("kvstore: encode %q: %w", key, err)
+	}
+
+	var expiresAt *time.Time
+	if ttl > 0 {
+		t := time.Now().Add(ttl)
+		expiresAt = &t
+	}
+
+	q := s.DB.SQL.Insert(s.table()).
+		Columns("key", "value", "expires_at", "updated_at").
+		Values(key, data, expiresAt, sq.Expr("NOW()")).
+		Suffix("ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at, updated_at = EXCLUDED.updated_at")
+
+	_, err = s.DB.Query.Exec(ctx, q)
+	return err
+}
+
+// Delete removes the key. Deleting a missing key is not an error.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	_, err := s.DB.Query.Exec(ctx, s.DB.SQL.Delete(s.table()).Where(sq.Eq{"key": key}))
+	return err
+}
+
+// List returns the unexpired entries whose key starts with prefix, ordered by
+// key. An empty prefix lists all entries.
+func (s *Store) List(ctx context.Context, prefix string) ([]*Entry, error) {
+	q := s.DB.SQL.Select("key", "value", "expires_at", "updated_at").From(s.table()).Where(notExpired).OrderBy("key")
+	if prefix != "" {
+		q = q.Where(sq.Like{"key": escapeLike(prefix) + "%"})
+	}
+
+	var entries []*Entry
+	if err := s.DB.Query.GetAll(ctx, q, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DeleteExpired removes expired entries, returning how many were removed.
+// Expired entries are never returned by Get or List, so this only reclaims
+// space and can run from an infrequent background job.
+func (s *Store) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.DB.Query.ExecAffected(ctx, s.DB.SQL.Delete(s.table()).Where("expires_at <= NOW()"))
+}
+
+// Get is a typed wrapper around Store.Get, ie.
+//
+//	flags, err := kvstore.Get[FeatureFlags](ctx, store, "flags")
+func Get[T any](ctx context.Context, s *Store, key string) (T, error) {
+	var v T
+	err := s.Get(ctx, key, &v)
+	return v, err
+}
+
+// table returns the quoted table name, used by all statements of the store.
+func (s *Store) table() string {
+	return pgkit.Ident(s.TableName).String()
+}
+
+var notExpired = sq.Expr("(expires_at IS NULL OR expires_at > NOW())")
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
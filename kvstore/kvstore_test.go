@@ -0,0 +1,17 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, "flags.", escapeLike("flags."))
+	assert.Equal(t, `100\%\_off\\`, escapeLike(`100%_off\`))
+}
+
+func TestTable(t *testing.T) {
+	assert.Equal(t, `"kv"`, (&Store{TableName: "kv"}).table())
+	assert.Equal(t, `"config"."Flags"`, (&Store{TableName: "config.Flags"}).table())
+}
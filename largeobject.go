@@ -0,0 +1,86 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// largeObjectBufSize is the buffer size of large object copies, larger than
+// io.Copy's default to save round trips, as every read or write is a query.
+const largeObjectBufSize = 1 << 20
+
+// LargeObjects returns the large object API of the querier's transaction,
+// which creates, opens and deletes large objects. An opened *pgx.LargeObject
+// is an io.ReadWriteSeeker, for storing blobs too big to fit comfortably in a
+// bytea column. Large objects can only be used within a transaction, so an
+// error is returned if the querier isn't bound to one, see DB.BeginFunc.
+func (q *Querier) LargeObjects() (*pgx.LargeObjects, error) {
+	if q.tx == nil {
+		return nil, wrapErr(fmt.Errorf("large objects require a transaction"))
+	}
+	lo := q.tx.LargeObjects()
+	return &lo, nil
+}
+
+// WriteLargeObject streams r into a new large object and returns its oid.
+func (d *DB) WriteLargeObject(ctx context.Context, r io.Reader) (uint32, error) {
+	var oid uint32
+	err := d.beginFunc(ctx, 2, func(q *Querier) error {
+		los, err := q.LargeObjects()
+		if err != nil {
+			return err
+		}
+		if oid, err = los.Create(ctx, 0); err != nil {
+			return wrapErr(err)
+		}
+		lo, err := los.Open(ctx, oid, pgx.LargeObjectModeWrite)
+		if err != nil {
+			return wrapErr(err)
+		}
+		defer lo.Close()
+
+		if _, err := io.CopyBuffer(lo, r, make([]byte, largeObjectBufSize)); err != nil {
+			return wrapErr(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return oid, nil
+}
+
+// ReadLargeObject streams the large object to w and returns the number of
+// bytes copied.
+func (d *DB) ReadLargeObject(ctx context.Context, oid uint32, w io.Writer) (int64, error) {
+	var n int64
+	err := d.beginFunc(ctx, 2, func(q *Querier) error {
+		los, err := q.LargeObjects()
+		if err != nil {
+			return err
+		}
+		lo, err := los.Open(ctx, oid, pgx.LargeObjectModeRead)
+		if err != nil {
+			return wrapErr(err)
+		}
+		defer lo.Close()
+
+		n, err = io.CopyBuffer(w, lo, make([]byte, largeObjectBufSize))
+		return wrapErr(err)
+	})
+	return n, err
+}
+
+// DeleteLargeObject deletes the large object.
+func (d *DB) DeleteLargeObject(ctx context.Context, oid uint32) error {
+	return d.beginFunc(ctx, 2, func(q *Querier) error {
+		los, err := q.LargeObjects()
+		if err != nil {
+			return err
+		}
+		return wrapErr(los.Unlink(ctx, oid))
+	})
+}
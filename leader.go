@@ -0,0 +1,153 @@
+package pgkit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LeaderElectorOptions configures a LeaderElector.
+type LeaderElectorOptions struct {
+	// Interval is how often leadership is retried by followers and verified
+	// by the leader. Default is 5s.
+	Interval time.Duration
+	// OnChange is called whenever leadership is gained or lost.
+	OnChange func(isLeader bool)
+}
+
+// LeaderElector elects a single leader among app replicas using a session
+// level advisory lock, so singleton background workers don't need an external
+// coordination service. The leader holds the lock on a dedicated connection;
+// if that connection is lost, Postgres releases the lock and another replica
+// takes over.
+type LeaderElector struct {
+	db      *DB
+	key     int64
+	options LeaderElectorOptions
+	changes chan bool
+
+	mu       sync.Mutex
+	isLeader bool
+}
+
+// NewLeaderElector returns a LeaderElector for the named role, ie.
+// "billing-worker". All replicas must use the same name.
+func NewLeaderElector(db *DB, name string, opts *LeaderElectorOptions) *LeaderElector {
+	e := &LeaderElector{
+		db:      db,
+		key:     AdvisoryLockKey("leader:" + name),
+		changes: make(chan bool, 1),
+	}
+	if opts != nil {
+		e.options = *opts
+	}
+	if e.options.Interval <= 0 {
+		e.options.Interval = 5 * time.Second
+	}
+	return e
+}
+
+// IsLeader reports whether this replica is currently the leader.
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.isLeader
+}
+
+// Changes returns a channel receiving the leadership state whenever it
+// changes. Only the latest state is kept if the receiver falls behind.
+func (e *LeaderElector) Changes() <-chan bool {
+	return e.changes
+}
+
+// Run campaigns for leadership until the context is done, re-acquiring it
+// whenever it's lost. Leadership is released before Run returns.
+func (e *LeaderElector) Run(ctx context.Context) error {
+	defer e.setLeader(false)
+
+	for {
+		conn, err := e.acquire(ctx)
+		if err == nil && conn != nil {
+			e.setLeader(true)
+			e.hold(ctx, conn)
+			e.setLeader(false)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.options.Interval):
+		}
+	}
+}
+
+// acquire tries to take the lock, returning the connection holding it, or
+// nil if another replica is the leader.
+func (e *LeaderElector) acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := e.db.Conn.Acquire(ctx)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, e.key).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, wrapErr(err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil
+	}
+	return conn, nil
+}
+
+// hold keeps verifying the connection holding the lock until it fails or
+// the context is done, then releases the lock.
+func (e *LeaderElector) hold(ctx context.Context, conn *pgxpool.Conn) {
+	defer func() {
+		// never return a connection which may still hold the lock to the pool
+		conn.Conn().Close(context.Background())
+		conn.Release()
+	}()
+
+	ticker := time.NewTicker(e.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, e.options.Interval)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (e *LeaderElector) setLeader(isLeader bool) {
+	e.mu.Lock()
+	changed := e.isLeader != isLeader
+	e.isLeader = isLeader
+	e.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	// keep only the latest state in the channel
+	select {
+	case <-e.changes:
+	default:
+	}
+	e.changes <- isLeader
+
+	if e.options.OnChange != nil {
+		e.options.OnChange(isLeader)
+	}
+}
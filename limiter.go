@@ -0,0 +1,101 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLimitExceeded is returned by the Querier when a query was rejected by its
+// concurrency limiter, see Querier.WithLimiter.
+var ErrLimitExceeded = errors.New("query concurrency limit exceeded")
+
+type LimiterConfig struct {
+	// Limits is the max number of concurrent queries per label, ie.
+	// {"reports": 2}. The label of a query is the query tag of its context,
+	// see WithQueryTag. Queries of other labels are not limited.
+	Limits map[string]int
+	// Reject fails queries beyond the limit with ErrLimitExceeded right away,
+	// instead of queueing them.
+	Reject bool
+	// MaxWait, when non-zero, is how long a queued query waits for a slot
+	// before failing with ErrLimitExceeded. Otherwise it waits until its
+	// context is done.
+	MaxWait time.Duration
+}
+
+// Limiter caps the number of concurrent queries per label, protecting the
+// database from thundering herds of expensive queries. A query holds its slot
+// until its rows are closed or its row is scanned.
+type Limiter struct {
+	cfg  LimiterConfig
+	sems map[string]chan struct{}
+}
+
+func NewLimiter(cfg LimiterConfig) *Limiter {
+	l := &Limiter{cfg: cfg, sems: make(map[string]chan struct{}, len(cfg.Limits))}
+	for label, n := range cfg.Limits {
+		if n > 0 {
+			l.sems[label] = make(chan struct{}, n)
+		}
+	}
+	return l
+}
+
+// InFlight returns the number of queries of the label currently running.
+func (l *Limiter) InFlight(label string) int {
+	return len(l.sems[label])
+}
+
+// acquire takes a slot for the label, returning the func releasing it.
+func (l *Limiter) acquire(ctx context.Context, label string) (func(), error) {
+	sem, ok := l.sems[label]
+	if !ok {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return l.release(sem), nil
+	default:
+		if l.cfg.Reject {
+			return nil, ErrLimitExceeded
+		}
+	}
+
+	var timeout <-chan time.Time
+	if l.cfg.MaxWait > 0 {
+		timer := time.NewTimer(l.cfg.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return l.release(sem), nil
+	case <-timeout:
+		return nil, ErrLimitExceeded
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *Limiter) release(sem chan struct{}) func() {
+	return func() { <-sem }
+}
+
+// WithLimiter returns a copy of the querier which limits the number of
+// concurrent queries per label with the given limiter.
+func (q *Querier) WithLimiter(l *Limiter) *Querier {
+	qq := *q
+	qq.limiter = l
+	return &qq
+}
+
+// limit takes a limiter slot for the query tag of the context.
+func (q *Querier) limit(ctx context.Context) (func(), error) {
+	if q.limiter == nil {
+		return func() {}, nil
+	}
+	return q.limiter.acquire(ctx, QueryTag(ctx))
+}
@@ -0,0 +1,88 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceLockTimeout is the lock_timeout of the maintenance commands, so
+// a command stuck waiting behind a long transaction fails instead of
+// blocking all other queries on the table queued behind it.
+var MaintenanceLockTimeout = 10 * time.Second
+
+// VacuumOptions are the options of DB.Vacuum.
+type VacuumOptions struct {
+	// Full rewrites the table to reclaim space. It holds an ACCESS EXCLUSIVE
+	// lock on the table while running.
+	Full bool
+	// Freeze aggressively freezes tuples.
+	Freeze bool
+	// Analyze also updates the planner statistics.
+	Analyze bool
+}
+
+// Analyze updates the planner statistics of the table.
+func (d *DB) Analyze(ctx context.Context, tableName string) error {
+	return d.maintenance(ctx, "ANALYZE "+Ident(tableName).String())
+}
+
+// Vacuum vacuums the table with the given options.
+func (d *DB) Vacuum(ctx context.Context, tableName string, opts VacuumOptions) error {
+	var flags []string
+	if opts.Full {
+		flags = append(flags, "FULL")
+	}
+	if opts.Freeze {
+		flags = append(flags, "FREEZE")
+	}
+	if opts.Analyze {
+		flags = append(flags, "ANALYZE")
+	}
+
+	stmt := "VACUUM "
+	if len(flags) > 0 {
+		stmt += "(" + strings.Join(flags, ", ") + ") "
+	}
+	return d.maintenance(ctx, stmt+Ident(tableName).String())
+}
+
+// ReindexTable rebuilds all indexes of the table with REINDEX CONCURRENTLY,
+// which doesn't block writes to the table.
+func (d *DB) ReindexTable(ctx context.Context, tableName string) error {
+	return d.maintenance(ctx, "REINDEX TABLE CONCURRENTLY "+Ident(tableName).String())
+}
+
+// ReindexIndex rebuilds the index with REINDEX CONCURRENTLY, which doesn't
+// block writes to its table.
+func (d *DB) ReindexIndex(ctx context.Context, indexName string) error {
+	return d.maintenance(ctx, "REINDEX INDEX CONCURRENTLY "+Ident(indexName).String())
+}
+
+// maintenance runs the command on a dedicated connection with
+// MaintenanceLockTimeout. VACUUM and REINDEX CONCURRENTLY can't run within a
+// transaction, so the timeout is set on the session and reset afterwards.
+func (d *DB) maintenance(ctx context.Context, stmt string) error {
+	conn, err := d.Conn.Acquire(ctx)
+	if err != nil {
+		return wrapErr(err)
+	}
+	defer conn.Release()
+
+	timeout := fmt.Sprintf("SET lock_timeout = %d", MaintenanceLockTimeout.Milliseconds())
+	if _, err := conn.Exec(ctx, timeout); err != nil {
+		return wrapErr(err)
+	}
+	defer func() {
+		// don't return a connection with a modified session to the pool
+		if _, err := conn.Exec(context.Background(), "RESET lock_timeout"); err != nil {
+			conn.Conn().Close(context.Background())
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("pgkit: %s: %w", stmt, err)
+	}
+	return nil
+}
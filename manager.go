@@ -0,0 +1,152 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Manager is a registry of multiple named database connections, ie. "core",
+// "analytics" or "tenant-shard-3", sharing the same tracer and lifecycle.
+type Manager struct {
+	appName string
+	tracer  pgx.QueryTracer
+
+	mu      sync.RWMutex
+	configs map[string]Config
+	dbs     map[string]*DB
+}
+
+// NewManager returns a new Manager. The tracer, if not nil, is used by all
+// registered databases which don't specify their own Config.Tracer.
+func NewManager(appName string, tracer pgx.QueryTracer) *Manager {
+	return &Manager{
+		appName: appName,
+		tracer:  tracer,
+		configs: map[string]Config{},
+		dbs:     map[string]*DB{},
+	}
+}
+
+// Register adds a named database config to the manager. The database is
+// connected by ConnectAll.
+func (m *Manager) Register(name string, cfg Config) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.configs[name]; ok {
+		return fmt.Errorf("pgkit: database %q already registered", name)
+	}
+	if cfg.Tracer == nil {
+		cfg.Tracer = m.tracer
+	}
+	m.configs[name] = cfg
+	return nil
+}
+
+// ConnectAll connects all registered databases which are not connected yet.
+// On failure, the databases connected by this call are closed again.
+func (m *Manager) ConnectAll(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	connected := []string{}
+	for _, name := range m.names() {
+		if _, ok := m.dbs[name]; ok {
+			continue
+		}
+
+		db, err := Connect(m.appName, m.configs[name])
+		if err == nil {
+			err = db.Conn.Ping(ctx)
+			if err != nil {
+				db.Close()
+			}
+		}
+		if err != nil {
+			for _, name := range connected {
+				m.dbs[name].Close()
+				delete(m.dbs, name)
+			}
+			return fmt.Errorf("pgkit: failed to connect database %q: %w", name, err)
+		}
+
+		m.dbs[name] = db
+		connected = append(connected, name)
+	}
+	return nil
+}
+
+// Get returns the named database, or an error if it's not connected.
+func (m *Manager) Get(name string) (*DB, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	db, ok := m.dbs[name]
+	if !ok {
+		return nil, fmt.Errorf("pgkit: database %q is not connected", name)
+	}
+	return db, nil
+}
+
+// MustGet is like Get but panics if the database is not connected.
+func (m *Manager) MustGet(name string) *DB {
+	db, err := m.Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// HealthCheckAll pings all connected databases and returns the errors by
+// database name. A nil map means all databases are healthy.
+func (m *Manager) HealthCheckAll(ctx context.Context) map[string]error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs map[string]error
+	)
+	for name, db := range m.dbs {
+		wg.Add(1)
+		go func(name string, db *DB) {
+			defer wg.Done()
+			if err := db.Conn.Ping(ctx); err != nil {
+				lock.Lock()
+				if errs == nil {
+					errs = map[string]error{}
+				}
+				errs[name] = wrapErr(err)
+				lock.Unlock()
+			}
+		}(name, db)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// CloseAll closes all connected databases.
+func (m *Manager) CloseAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, db := range m.dbs {
+		db.Close()
+		delete(m.dbs, name)
+	}
+}
+
+func (m *Manager) names() []string {
+	names := make([]string, 0, len(m.configs))
+	for name := range m.configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -40,6 +40,10 @@ type MapOptions struct {
 // The mapper works by reading the column names from a struct fields `db:""` struct tag.
 // If you specify `,omitempty` as a tag option, then it will omit the column from the list,
 // which allows the database to take over and use its default value.
+// Fields tagged with `,readonly`, ie. generated or computed columns, are scanned on
+// select but never mapped, so they're excluded from insert/update statements.
+// Columns are always ordered by name, for both structs and maps, so statements
+// built from the mapping are deterministic and can be cached or compared in tests.
 func Map(record interface{}) ([]string, []interface{}, error) {
 	return MapWithOptions(record, nil)
 }
@@ -87,6 +91,11 @@ func MapWithOptions(record interface{}, options *MapOptions) ([]string, []interf
 			// Field options
 			_, tagOmitEmpty := fi.Options["omitempty"]
 
+			// Skip read-only fields, ie. GENERATED ALWAYS columns
+			if _, tagReadOnly := fi.Options["readonly"]; tagReadOnly {
+				continue
+			}
+
 			fld := reflectx.FieldByIndexesReadOnly(recordV, fi.Index)
 
 			if fld.Kind() == reflect.Ptr && fld.IsNil() {
@@ -147,7 +156,9 @@ func MapWithOptions(record interface{}, options *MapOptions) ([]string, []interf
 			// if err != nil {
 			// 	return nil, nil, err
 			// }
-			v := valv
+			// the underlying value, as for struct fields, since pgx doesn't
+			// encode a reflect.Value as the value it holds
+			v := valv.Interface()
 
 			fv.values[i] = v
 		}
@@ -161,7 +172,7 @@ func MapWithOptions(record interface{}, options *MapOptions) ([]string, []interf
 		return fv.fields, fv.values, fmt.Errorf("record mapper returned %d columns and %d values", len(fv.fields), len(fv.values))
 	}
 
-	// normalize order for better cache hits
+	// normalize order for better cache hits and reproducible statements, see Map
 	sort.Sort(&fv)
 
 	return fv.fields, fv.values, nil
@@ -0,0 +1,68 @@
+package pgkit_test
+
+import (
+	"testing"
+
+	"github.com/goware/pgkit/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapReadOnly(t *testing.T) {
+	type Invoice struct {
+		ID       int64 `db:"id,omitempty"`
+		Amount   int64 `db:"amount"`
+		Tax      int64 `db:"tax"`
+		Total    int64 `db:"total,readonly"`
+		Internal int64
+	}
+
+	cols, vals, err := pgkit.Map(&Invoice{Amount: 100, Tax: 20, Total: 120})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amount", "tax"}, cols)
+	assert.Equal(t, []interface{}{int64(100), int64(20)}, vals)
+}
+
+func TestMapColumnOrder(t *testing.T) {
+	type Record struct {
+		Zeta  string `db:"zeta"`
+		Alpha string `db:"alpha"`
+		Mid   string `db:"mid"`
+	}
+
+	for i := 0; i < 10; i++ {
+		cols, vals, err := pgkit.Map(&Record{Zeta: "z", Alpha: "a", Mid: "m"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alpha", "mid", "zeta"}, cols)
+		assert.Equal(t, []interface{}{"a", "m", "z"}, vals)
+
+		cols, vals, err = pgkit.Map(map[string]interface{}{"zeta": "z", "alpha": "a", "mid": "m"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alpha", "mid", "zeta"}, cols)
+		assert.Equal(t, []interface{}{"a", "m", "z"}, vals)
+	}
+}
+
+func TestMapMapValues(t *testing.T) {
+	// map values are mapped to their underlying value, not to a reflect.Value
+	cols, vals, err := pgkit.Map(map[string]int64{"amount": 100, "tax": 20})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amount", "tax"}, cols)
+	assert.Equal(t, []interface{}{int64(100), int64(20)}, vals)
+
+	cols, vals, err = pgkit.Map(map[string]interface{}{"name": "a", "deleted_at": nil})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deleted_at", "name"}, cols)
+	assert.Equal(t, []interface{}{nil, "a"}, vals)
+
+	// values of map records build the same statement as of struct records
+	type record struct {
+		Amount int64 `db:"amount"`
+		Tax    int64 `db:"tax"`
+	}
+	_, structVals, err := pgkit.Map(&record{Amount: 100, Tax: 20})
+	require.NoError(t, err)
+	_, mapVals, err := pgkit.Map(map[string]int64{"amount": 100, "tax": 20})
+	require.NoError(t, err)
+	assert.Equal(t, structVals, mapVals)
+}
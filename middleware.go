@@ -0,0 +1,139 @@
+package pgkit
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Statement is a statement run by a QueryFunc.
+type Statement struct {
+	SQL  string
+	Args []interface{}
+	// Rows is set for statements returning rows, ie. run with QueryRows or
+	// QueryRow, and unset for statements run with Exec.
+	Rows bool
+	// InTx is set for statements run within a transaction, whose results may
+	// depend on its uncommitted writes.
+	InTx bool
+}
+
+// Result is the result of a statement run by a QueryFunc. Rows is set for
+// statements returning rows, and must be closed by the caller.
+type Result struct {
+	CommandTag pgconn.CommandTag
+	Rows       pgx.Rows
+}
+
+// QueryFunc runs a statement.
+type QueryFunc func(ctx context.Context, stmt Statement) (Result, error)
+
+// Middleware wraps the execution of statements, ie. to check permissions,
+// rewrite statements, serve results from a cache or mirror traffic. It may
+// call next to run the statement, or return a result of its own.
+type Middleware func(next QueryFunc) QueryFunc
+
+// Use returns a copy of the querier which runs the statements of Exec,
+// QueryRows and QueryRow, and of the helpers built on them, through the
+// middlewares. The first middleware is the outermost one. Batches and copies
+// are not run through the middlewares.
+func (q *Querier) Use(middlewares ...Middleware) *Querier {
+	qq := *q
+	qq.middlewares = append(q.middlewares[:len(q.middlewares):len(q.middlewares)], middlewares...)
+	return &qq
+}
+
+// handler returns the QueryFunc running the statements through the
+// middlewares of the querier.
+func (q *Querier) handler() QueryFunc {
+	h := q.runStatement
+	if q.writeFencing {
+		h = q.fence(h)
+	}
+	for i := len(q.middlewares) - 1; i >= 0; i-- {
+		h = q.middlewares[i](h)
+	}
+	return h
+}
+
+func (q *Querier) runStatement(ctx context.Context, stmt Statement) (Result, error) {
+	ctx, done, err := q.execContext(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if q.tx == nil {
+		if settings := q.statementSettings(ctx); settings != nil {
+			return q.runWithSettings(ctx, stmt, settings, done)
+		}
+	} else if err := q.applySettings(ctx); err != nil {
+		done()
+		return Result{}, err
+	}
+
+	if !stmt.Rows {
+		defer done()
+
+		var tag pgconn.CommandTag
+		err = q.run(func() (err error) {
+			if q.tx != nil {
+				tag, err = q.tx.Exec(ctx, stmt.SQL, stmt.Args...)
+			} else {
+				tag, err = q.pool.Exec(ctx, stmt.SQL, stmt.Args...)
+			}
+			return err
+		})
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{CommandTag: tag}, nil
+	}
+
+	report, err := q.guard()
+	if err != nil {
+		done()
+		return Result{}, err
+	}
+	var rows pgx.Rows
+	if q.tx != nil {
+		rows, err = q.tx.Query(ctx, stmt.SQL, stmt.Args...)
+	} else {
+		rows, err = q.pool.Query(ctx, stmt.SQL, stmt.Args...)
+	}
+	if err != nil {
+		report(err)
+		done()
+		return Result{}, err
+	}
+	if q.breaker != nil {
+		// server errors may only show up while reading the rows
+		rows = breakerRows{Rows: rows, report: report}
+	}
+	if q.tracksDone() {
+		return Result{Rows: doneRows{Rows: rows, done: done}}, nil
+	}
+	return Result{Rows: rows}, nil
+}
+
+// rowsRow is the row of QueryRow run through the middlewares, which scans the
+// first row of the rows and closes them.
+type rowsRow struct {
+	rows pgx.Rows
+}
+
+func (r rowsRow) Scan(dest ...interface{}) error {
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return wrapErr(err)
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return wrapErr(err)
+	}
+	r.rows.Close()
+	return wrapErr(r.rows.Err())
+}
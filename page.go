@@ -1,7 +1,9 @@
 package pgkit
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 
@@ -22,9 +24,20 @@ const (
 	Asc  Order = "ASC"
 )
 
+// Nulls is the position of NULL values in a sort order.
+type Nulls string
+
+const (
+	NullsFirst Nulls = "FIRST"
+	NullsLast  Nulls = "LAST"
+)
+
 type Sort struct {
 	Column string
 	Order  Order
+	// Nulls is the position of NULL values, the Postgres default, last in
+	// ascending order and first in descending order, if empty.
+	Nulls Nulls
 }
 
 func (s Sort) String() string {
@@ -34,21 +47,32 @@ func (s Sort) String() string {
 	if s.Order == "" {
 		s.Order = Asc
 	}
+	if s.Nulls != "" {
+		return fmt.Sprintf("%s %s NULLS %s", s.Column, s.Order, s.Nulls)
+	}
 	return fmt.Sprintf("%s %s", s.Column, s.Order)
 }
 
-var _MatcherOrderBy = regexp.MustCompile(`-?([a-zA-Z0-9]+)`)
+// _MatcherOrderBy matches a sort expression: an optionally qualified column,
+// optionally followed by JSONB accessors, ie. "accounts.settings->>'theme'",
+// with an optional NULLS FIRST or NULLS LAST suffix.
+var _MatcherOrderBy = regexp.MustCompile(`(?i)^-?([a-z_][a-z0-9_]*(?:\.[a-z_][a-z0-9_]*)*(?:->>?(?:'[a-z0-9_ -]+'|[0-9]+))*)(?:\s+nulls\s+(first|last))?$`)
 
+// NewSort parses the sort expression, ie. "created_at", "-accounts.created_at"
+// for descending order, "settings->>'theme'" for a JSONB field, or
+// "-updated_at nulls last". It reports false for invalid expressions, so
+// expressions taken from user input can't inject SQL.
 func NewSort(s string) (Sort, bool) {
-	if s == "" || !_MatcherOrderBy.MatchString(s) {
+	m := _MatcherOrderBy.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
 		return Sort{}, false
 	}
 	sort := Sort{
-		Column: s,
+		Column: m[1],
 		Order:  Asc,
+		Nulls:  Nulls(strings.ToUpper(m[2])),
 	}
-	if strings.HasPrefix(s, "-") {
-		sort.Column = s[1:]
+	if strings.HasPrefix(strings.TrimSpace(s), "-") {
 		sort.Order = Desc
 	}
 	return sort, true
@@ -208,3 +232,113 @@ func (p Paginator[T]) PrepareResult(result []T, page *Page) []T {
 	page.Page = 1 + uint32(page.Offset())/uint32(limit)
 	return result
 }
+
+// ForEachPage runs the query in chunks of the max page size, and calls fn with
+// the rows of each chunk, until all rows were read or fn fails, ie. for export
+// endpoints traversing the whole result server-side. Only one chunk is held
+// in memory at a time.
+//
+// Chunks are read in keyset order of the default sort of the paginator, see
+// WithSort, so the query must not be ordered nor limited. The sort columns
+// must be non-null, unique together, ie. end with the primary key, and
+// mapped by `db` tagged fields of T, which hold the keyset of the last row.
+func (p Paginator[T]) ForEachPage(ctx context.Context, querier *Querier, q sq.SelectBuilder, fn func(rows []T) error) error {
+	sorts := (*Page)(nil).GetOrder(p.defaultSort...)
+	if len(sorts) == 0 {
+		return wrapErr(fmt.Errorf("paginator has no sort to iterate pages in"))
+	}
+
+	columns := make([]string, len(sorts))
+	order := make([]string, len(sorts))
+	for i, s := range sorts {
+		columns[i] = s.Column
+		if p.columnFunc != nil {
+			columns[i] = p.columnFunc(s.Column)
+		}
+		order[i] = Sort{Column: columns[i], Order: s.Order}.String()
+	}
+
+	size := uint64(p.maxSize)
+	if size == 0 {
+		size = MaxPageSize
+	}
+
+	var last []interface{}
+	for {
+		chunk := q.OrderBy(order...).Limit(size)
+		if last != nil {
+			chunk = chunk.Where(keysetCond(columns, sorts, last))
+		}
+
+		var rows []T
+		if err := querier.GetAll(ctx, chunk, &rows); err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := fn(rows); err != nil {
+			return err
+		}
+		if uint64(len(rows)) < size {
+			return nil
+		}
+
+		keyset, err := keysetOf(rows[len(rows)-1], sorts, columns)
+		if err != nil {
+			return err
+		}
+		last = keyset
+	}
+}
+
+// keysetCond returns the condition matching the rows after the keyset in the
+// order of the sorts, ie. `a > ? OR (a = ? AND b < ?)`.
+func keysetCond(columns []string, sorts []Sort, keyset []interface{}) sq.Sqlizer {
+	cond := sq.Or{}
+	for i := range sorts {
+		and := sq.And{}
+		for j := 0; j < i; j++ {
+			and = append(and, sq.Eq{columns[j]: keyset[j]})
+		}
+		op := ">"
+		if sorts[i].Order == Desc {
+			op = "<"
+		}
+		and = append(and, sq.Expr(columns[i]+" "+op+" ?", keyset[i]))
+		cond = append(cond, and)
+	}
+	return cond
+}
+
+// keysetOf returns the values of the sort columns of the row.
+func keysetOf(row interface{}, sorts []Sort, columns []string) ([]interface{}, error) {
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, wrapErr(fmt.Errorf("nil row"))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, wrapErr(fmt.Errorf("rows of type %T have no sort fields", row))
+	}
+
+	names := Mapper.TypeMap(v.Type()).Names
+	keyset := make([]interface{}, len(sorts))
+	for i, s := range sorts {
+		fi, ok := names[s.Column]
+		if !ok {
+			fi, ok = names[columns[i]]
+		}
+		if !ok {
+			// qualified column, ie. "a.id"
+			fi, ok = names[s.Column[strings.LastIndex(s.Column, ".")+1:]]
+		}
+		if !ok {
+			return nil, wrapErr(fmt.Errorf("rows of type %T have no field for sort column %q", row, s.Column))
+		}
+		keyset[i] = v.FieldByIndex(fi.Index).Interface()
+	}
+	return keyset, nil
+}
@@ -1,6 +1,7 @@
 package pgkit_test
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -45,3 +46,38 @@ func TestPagination(t *testing.T) {
 	require.Len(t, result, MaxSize)
 	require.Equal(t, &pgkit.Page{Page: 1, Size: MaxSize, More: true}, page)
 }
+
+func TestPaginatorForEachPageWithoutSort(t *testing.T) {
+	paginator := pgkit.NewPaginator[T]()
+	err := paginator.ForEachPage(context.Background(), nil, sq.Select("*").From("t"), func(rows []T) error {
+		return nil
+	})
+	require.Error(t, err)
+}
+
+func TestNewSort(t *testing.T) {
+	tests := []struct {
+		in   string
+		sort pgkit.Sort
+	}{
+		{"id", pgkit.Sort{Column: "id", Order: pgkit.Asc}},
+		{"-created_at", pgkit.Sort{Column: "created_at", Order: pgkit.Desc}},
+		{" -accounts.created_at", pgkit.Sort{Column: "accounts.created_at", Order: pgkit.Desc}},
+		{"settings->>'theme'", pgkit.Sort{Column: "settings->>'theme'", Order: pgkit.Asc}},
+		{"a.data->'tags'->0", pgkit.Sort{Column: "a.data->'tags'->0", Order: pgkit.Asc}},
+		{"-updated_at nulls last", pgkit.Sort{Column: "updated_at", Order: pgkit.Desc, Nulls: pgkit.NullsLast}},
+		{"name NULLS FIRST", pgkit.Sort{Column: "name", Order: pgkit.Asc, Nulls: pgkit.NullsFirst}},
+	}
+	for _, tt := range tests {
+		sort, ok := pgkit.NewSort(tt.in)
+		require.True(t, ok, tt.in)
+		require.Equal(t, tt.sort, sort, tt.in)
+	}
+
+	for _, in := range []string{"", "-", "1id", "name; DROP TABLE accounts", "name DESC", "settings->>'a'b'", "name nulls", "a..b"} {
+		_, ok := pgkit.NewSort(in)
+		require.False(t, ok, in)
+	}
+
+	require.Equal(t, "updated_at DESC NULLS LAST", pgkit.Sort{Column: "updated_at", Order: pgkit.Desc, Nulls: pgkit.NullsLast}.String())
+}
@@ -0,0 +1,69 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ParallelQuery is a query of Parallel and the destination its results are
+// scanned into.
+type ParallelQuery struct {
+	Query Sqlizer
+	Dest  interface{}
+}
+
+// Into pairs the query with the destination its results are scanned into,
+// see Parallel. Results are scanned with GetAll if dest is a pointer to a
+// slice, and with GetOne otherwise.
+func Into(query Sqlizer, dest interface{}) ParallelQuery {
+	return ParallelQuery{Query: query, Dest: dest}
+}
+
+// Parallel runs independent queries concurrently, ie. the unrelated queries
+// of a dashboard, and scans each into its destination. At most as many
+// queries as the max pool size run at once. Within a transaction, queries run
+// one after another on its connection. All queries run to completion, and
+// their errors are joined in the returned error.
+func Parallel(ctx context.Context, q *Querier, queries ...ParallelQuery) error {
+	if q.tx != nil {
+		var errs []error
+		for i, query := range queries {
+			if err := query.get(ctx, q); err != nil {
+				errs = append(errs, fmt.Errorf("query %d: %w", i, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, max(int(q.pool.Config().MaxConns), 1))
+		errs = make([]error, len(queries))
+	)
+	for i, query := range queries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, query ParallelQuery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := query.get(ctx, q); err != nil {
+				errs[i] = fmt.Errorf("query %d: %w", i, err)
+			}
+		}(i, query)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (p ParallelQuery) get(ctx context.Context, q *Querier) error {
+	v := reflect.ValueOf(p.Dest)
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice && v.Elem().Type().Elem().Kind() != reflect.Uint8 {
+		return q.GetAll(ctx, p.Query, p.Dest)
+	}
+	return q.GetOne(ctx, p.Query, p.Dest)
+}
@@ -0,0 +1,243 @@
+// Package partition manages time-range partitions of Postgres declaratively
+// partitioned tables, ie. monthly partitions of an events table.
+//
+// A Maintainer creates partitions ahead of time and drops partitions past
+// their retention, and is meant to be run periodically as a maintenance job.
+// The parent table must already exist and be partitioned by RANGE over a
+// timestamp or date column:
+//
+//	CREATE TABLE events (...) PARTITION BY RANGE (created_at);
+package partition
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goware/pgkit/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+type Interval string
+
+const (
+	Daily   Interval = "daily"
+	Monthly Interval = "monthly"
+	Yearly  Interval = "yearly"
+)
+
+// Config declares the partitioning of a single table.
+type Config struct {
+	// Table is the name of the partitioned parent table.
+	Table string
+	// Interval is the time range covered by each partition.
+	Interval Interval
+	// Premake is the number of future partitions to keep created, besides
+	// the current one. Default is 2.
+	Premake int
+	// Retention is the number of past partitions to keep, besides the
+	// current one. Older partitions are dropped. Zero keeps all partitions.
+	Retention int
+}
+
+// Range is a partition and its [From, To) bounds.
+type Range struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// Maintainer creates and drops partitions according to its configs.
+type Maintainer struct {
+	DB      *pgkit.DB
+	Configs []Config
+}
+
+func NewMaintainer(db *pgkit.DB, configs ...Config) *Maintainer {
+	return &Maintainer{DB: db, Configs: configs}
+}
+
+// Run creates missing partitions and drops expired ones for all configs,
+// relative to the current time.
+func (m *Maintainer) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+	for _, cfg := range m.Configs {
+		if err := m.ensure(ctx, cfg, now); err != nil {
+			return err
+		}
+		if err := m.dropExpired(ctx, cfg, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Maintainer) ensure(ctx context.Context, cfg Config, now time.Time) error {
+	premake := cfg.Premake
+	if premake <= 0 {
+		premake = 2
+	}
+
+	for i := 0; i <= premake; i++ {
+		r, err := cfg.RangeAt(now, i)
+		if err != nil {
+			return err
+		}
+		sql := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+			ident(cfg.qualified(r.Name)), ident(cfg.Table), r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+		if _, err := m.DB.Query.Exec(ctx, pgkit.RawSQL{Query: sql}); err != nil {
+			return fmt.Errorf("partition: create %s: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Maintainer) dropExpired(ctx context.Context, cfg Config, now time.Time) error {
+	if cfg.Retention <= 0 {
+		return nil
+	}
+
+	oldest, err := cfg.RangeAt(now, -cfg.Retention)
+	if err != nil {
+		return err
+	}
+
+	partitions, err := m.Partitions(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	for _, r := range partitions {
+		if !r.To.After(oldest.From) {
+			if err := m.Drop(ctx, cfg, r.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Partitions lists the partitions of the table created by this package,
+// recognized by their naming convention.
+func (m *Maintainer) Partitions(ctx context.Context, cfg Config) ([]Range, error) {
+	q := pgkit.RawQuery(`SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = ?::regclass`)
+
+	rows, err := m.DB.Query.QueryRows(ctx, q.Build(cfg.Table))
+	if err != nil {
+		return nil, fmt.Errorf("partition: list %s: %w", cfg.Table, err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("partition: list %s: %w", cfg.Table, err)
+	}
+
+	var ranges []Range
+	for _, name := range names {
+		if r, ok := cfg.parseName(name); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges, nil
+}
+
+// Attach attaches an existing table as the partition for the given range.
+func (m *Maintainer) Attach(ctx context.Context, cfg Config, r Range) error {
+	sql := fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+		ident(cfg.Table), ident(cfg.qualified(r.Name)), r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+	if _, err := m.DB.Query.Exec(ctx, pgkit.RawSQL{Query: sql}); err != nil {
+		return fmt.Errorf("partition: attach %s: %w", r.Name, err)
+	}
+	return nil
+}
+
+// Detach detaches a partition from the table, keeping it as a regular table.
+func (m *Maintainer) Detach(ctx context.Context, cfg Config, name string) error {
+	sql := fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, ident(cfg.Table), ident(cfg.qualified(name)))
+	if _, err := m.DB.Query.Exec(ctx, pgkit.RawSQL{Query: sql}); err != nil {
+		return fmt.Errorf("partition: detach %s: %w", name, err)
+	}
+	return nil
+}
+
+// Drop detaches and drops a partition.
+func (m *Maintainer) Drop(ctx context.Context, cfg Config, name string) error {
+	if err := m.Detach(ctx, cfg, name); err != nil {
+		return err
+	}
+	if _, err := m.DB.Query.Exec(ctx, pgkit.RawSQL{Query: `DROP TABLE IF EXISTS ` + ident(cfg.qualified(name))}); err != nil {
+		return fmt.Errorf("partition: drop %s: %w", name, err)
+	}
+	return nil
+}
+
+// RangeAt returns the partition range containing t, shifted by offset
+// intervals, ie. offset 1 is the next partition.
+func (cfg Config) RangeAt(t time.Time, offset int) (Range, error) {
+	t = t.UTC()
+
+	var from, to time.Time
+	switch cfg.Interval {
+	case Daily:
+		from = time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 0, 1)
+	case Monthly:
+		from = time.Date(t.Year(), t.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 1, 0)
+	case Yearly:
+		from = time.Date(t.Year()+offset, 1, 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(1, 0, 0)
+	default:
+		return Range{}, fmt.Errorf("partition: invalid interval %q for %s", cfg.Interval, cfg.Table)
+	}
+
+	return Range{Name: cfg.tableName() + "_p" + from.Format(cfg.layout()), From: from, To: to}, nil
+}
+
+func (cfg Config) parseName(name string) (Range, bool) {
+	prefix := cfg.tableName() + "_p"
+	if !strings.HasPrefix(name, prefix) {
+		return Range{}, false
+	}
+	t, err := time.Parse(cfg.layout(), strings.TrimPrefix(name, prefix))
+	if err != nil {
+		return Range{}, false
+	}
+	r, err := cfg.RangeAt(t, 0)
+	if err != nil || r.Name != name {
+		return Range{}, false
+	}
+	return r, true
+}
+
+func (cfg Config) layout() string {
+	switch cfg.Interval {
+	case Daily:
+		return "20060102"
+	case Monthly:
+		return "200601"
+	default:
+		return "2006"
+	}
+}
+
+// tableName returns the table name without schema.
+func (cfg Config) tableName() string {
+	if i := strings.LastIndex(cfg.Table, "."); i >= 0 {
+		return cfg.Table[i+1:]
+	}
+	return cfg.Table
+}
+
+// qualified returns the partition name in the schema of the parent table.
+func (cfg Config) qualified(name string) string {
+	if i := strings.LastIndex(cfg.Table, "."); i >= 0 {
+		return cfg.Table[:i+1] + name
+	}
+	return name
+}
+
+func ident(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
@@ -0,0 +1,50 @@
+package partition
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeAt(t *testing.T) {
+	now := time.Date(2024, 12, 15, 13, 0, 0, 0, time.UTC)
+
+	cfg := Config{Table: "events", Interval: Monthly}
+	r, err := cfg.RangeAt(now, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "events_p202412", r.Name)
+	assert.Equal(t, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), r.From)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), r.To)
+
+	r, err = cfg.RangeAt(now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "events_p202501", r.Name)
+
+	r, err = cfg.RangeAt(now, -12)
+	require.NoError(t, err)
+	assert.Equal(t, "events_p202312", r.Name)
+
+	cfg = Config{Table: "public.events", Interval: Daily}
+	r, err = cfg.RangeAt(now, 17)
+	require.NoError(t, err)
+	assert.Equal(t, "events_p20250101", r.Name)
+
+	_, err = Config{Table: "events", Interval: "hourly"}.RangeAt(now, 0)
+	assert.Error(t, err)
+}
+
+func TestParseName(t *testing.T) {
+	cfg := Config{Table: "events", Interval: Monthly}
+
+	r, ok := cfg.parseName("events_p202402")
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), r.From)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), r.To)
+
+	_, ok = cfg.parseName("events_default")
+	assert.False(t, ok)
+	_, ok = cfg.parseName("events_p20240201")
+	assert.False(t, ok)
+}
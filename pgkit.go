@@ -18,10 +18,28 @@ type DB struct {
 	Conn  *pgxpool.Pool
 	SQL   *StatementBuilder
 	Query *Querier
+
+	// HeavyConn is the optional secondary pool for slow analytical queries,
+	// see Config.Heavy and Querier.Heavy. It is nil unless configured.
+	HeavyConn *pgxpool.Pool
+
+	// LongTxThreshold, when non-zero, reports transactions opened with
+	// BeginFunc which stay open longer than the threshold to the tracer.
+	LongTxThreshold time.Duration
 }
 
 func (d *DB) TxQuery(tx pgx.Tx) *Querier {
-	return &Querier{tx: tx, SQL: d.SQL, pool: d.Conn, Scan: d.Query.Scan}
+	q := *d.Query
+	q.tx = tx
+	return &q
+}
+
+// Close closes the primary pool and the heavy pool, if any.
+func (d *DB) Close() {
+	if d.HeavyConn != nil {
+		d.HeavyConn.Close()
+	}
+	d.Conn.Close()
 }
 
 type Config struct {
@@ -33,10 +51,61 @@ type Config struct {
 	MinConns        int32  `toml:"min_conns"`
 	ConnMaxLifetime string `toml:"conn_max_lifetime"` // ie. "1800s" or "1h"
 
+	// QueryExecMode sets pgx's default query exec mode, one of "cache_statement",
+	// "cache_describe", "describe_exec", "exec" or "simple_protocol". When empty,
+	// pgx's default of "cache_statement" is used.
+	QueryExecMode string `toml:"query_exec_mode"`
+	// StatementCacheCapacity and DescribeCacheCapacity set the size of pgx's
+	// implicit statement caches. Zero keeps pgx's defaults.
+	StatementCacheCapacity int `toml:"statement_cache_capacity"`
+	DescribeCacheCapacity  int `toml:"describe_cache_capacity"`
+
+	// LongTxThreshold reports transactions open longer than the threshold to
+	// the tracer, see DB.BeginFunc. Empty disables the watchdog.
+	LongTxThreshold string `toml:"long_tx_threshold"` // ie. "30s"
+
+	// DefaultQueryTimeout is applied to the queries run with a context without
+	// deadline, so a forgotten timeout can't hold a connection indefinitely,
+	// see Querier.WithTimeout. Empty disables it.
+	DefaultQueryTimeout string `toml:"default_query_timeout"` // ie. "30s"
+
+	// QuoteIdentifiers quotes the table and column names of the statements
+	// built from records, see StatementBuilder.QuoteIdentifiers.
+	QuoteIdentifiers bool `toml:"quote_identifiers"`
+
+	// WriteFencing rejects connections to read-only servers, checked with
+	// pg_is_in_recovery() and transaction_read_only on every new connection,
+	// so writes after a DNS-based failover fail with ErrReadOnlyReplica rather
+	// than landing on the demoted primary. Statements outside of transactions
+	// rejected by a read-only server also fail with ErrReadOnlyReplica, and
+	// reset the pool.
+	WriteFencing bool `toml:"write_fencing"`
+
+	// Heavy configures an optional secondary pool used by Querier.Heavy, so slow
+	// analytical queries can't starve the primary pool.
+	Heavy *HeavyConfig `toml:"heavy"`
+
 	Override func(cfg *pgx.ConnConfig) `toml:"-"`
 	Tracer   pgx.QueryTracer
 }
 
+// ConnCloseTracer is implemented by query tracers which want to be notified
+// of pool connections being closed, see tracer.LogTracer. Tracers can also
+// implement pgx.ConnectTracer and pgxpool.AcquireTracer, which pgx calls
+// directly, to trace connection establishment and acquire waits.
+type ConnCloseTracer interface {
+	TraceConnClose(ctx context.Context, conn *pgx.Conn)
+}
+
+// HeavyConfig holds the settings of the secondary pool for heavy queries. The
+// connection settings are inherited from the primary Config.
+type HeavyConfig struct {
+	MaxConns         int32  `toml:"max_conns"`
+	MinConns         int32  `toml:"min_conns"`
+	ConnMaxLifetime  string `toml:"conn_max_lifetime"` // ie. "1800s" or "1h"
+	StatementTimeout string `toml:"statement_timeout"` // ie. "5m", empty for server default
+}
+
 func Connect(appName string, cfg Config) (*DB, error) {
 	poolCfg, err := pgxpool.ParseConfig(getConnectURI(appName, cfg))
 	if err != nil {
@@ -62,13 +131,106 @@ func Connect(appName string, cfg Config) (*DB, error) {
 
 	poolCfg.HealthCheckPeriod = time.Minute
 
+	if cfg.QueryExecMode != "" {
+		poolCfg.ConnConfig.DefaultQueryExecMode, err = parseQueryExecMode(cfg.QueryExecMode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.StatementCacheCapacity > 0 {
+		poolCfg.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	}
+	if cfg.DescribeCacheCapacity > 0 {
+		poolCfg.ConnConfig.DescriptionCacheCapacity = cfg.DescribeCacheCapacity
+	}
+
 	poolCfg.ConnConfig.Tracer = cfg.Tracer
+	if t, ok := cfg.Tracer.(ConnCloseTracer); ok {
+		poolCfg.BeforeClose = func(conn *pgx.Conn) {
+			t.TraceConnClose(context.Background(), conn)
+		}
+	}
+	if cfg.WriteFencing {
+		fenceWrites(poolCfg)
+	}
 	// override settings on *pgx.ConnConfig object
 	if cfg.Override != nil {
 		cfg.Override(poolCfg.ConnConfig)
 	}
 
-	return ConnectWithPGX(appName, poolCfg)
+	var longTxThreshold time.Duration
+	if cfg.LongTxThreshold != "" {
+		longTxThreshold, err = time.ParseDuration(cfg.LongTxThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("pgkit: config invalid long_tx_threshold value: %w", err)
+		}
+	}
+
+	var defaultQueryTimeout time.Duration
+	if cfg.DefaultQueryTimeout != "" {
+		defaultQueryTimeout, err = time.ParseDuration(cfg.DefaultQueryTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("pgkit: config invalid default_query_timeout value: %w", err)
+		}
+	}
+
+	db, err := ConnectWithPGX(appName, poolCfg)
+	if err != nil {
+		return nil, err
+	}
+	db.LongTxThreshold = longTxThreshold
+	db.Query.timeout = defaultQueryTimeout
+	db.Query.writeFencing = cfg.WriteFencing
+	db.SQL.QuoteIdentifiers = cfg.QuoteIdentifiers
+
+	if cfg.Heavy != nil {
+		heavyPool, heavyTimeout, err := connectHeavyPool(poolCfg, *cfg.Heavy)
+		if err != nil {
+			db.Conn.Close()
+			return nil, err
+		}
+		db.HeavyConn = heavyPool
+		db.Query.heavyPool = heavyPool
+		db.Query.heavyTimeout = heavyTimeout
+	}
+
+	return db, nil
+}
+
+// connectHeavyPool connects the heavy pool, and returns it along with its
+// statement timeout, if any.
+func connectHeavyPool(primary *pgxpool.Config, cfg HeavyConfig) (*pgxpool.Pool, time.Duration, error) {
+	poolCfg := primary.Copy()
+
+	if cfg.MaxConns == 0 {
+		cfg.MaxConns = 2
+	}
+	poolCfg.MaxConns = cfg.MaxConns
+	poolCfg.MinConns = cfg.MinConns
+
+	if cfg.ConnMaxLifetime != "" {
+		lifetime, err := time.ParseDuration(cfg.ConnMaxLifetime)
+		if err != nil {
+			return nil, 0, fmt.Errorf("pgkit: config invalid heavy conn_max_lifetime value: %w", err)
+		}
+		poolCfg.MaxConnLifetime = lifetime
+	}
+
+	var timeout time.Duration
+	if cfg.StatementTimeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(cfg.StatementTimeout)
+		if err != nil {
+			return nil, 0, fmt.Errorf("pgkit: config invalid heavy statement_timeout value: %w", err)
+		}
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = fmt.Sprintf("%d", timeout.Milliseconds())
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+	if err != nil {
+		return nil, 0, fmt.Errorf("pgkit: failed to connect heavy pool to db: %w", err)
+	}
+	return pool, timeout, nil
 }
 
 func ConnectWithPGX(appName string, pgxConfig *pgxpool.Config) (*DB, error) {
@@ -122,6 +284,23 @@ func getConnectURI(appName string, cfg Config) string {
 	)
 }
 
+func parseQueryExecMode(mode string) (pgx.QueryExecMode, error) {
+	switch mode {
+	case "cache_statement":
+		return pgx.QueryExecModeCacheStatement, nil
+	case "cache_describe":
+		return pgx.QueryExecModeCacheDescribe, nil
+	case "describe_exec":
+		return pgx.QueryExecModeDescribeExec, nil
+	case "exec":
+		return pgx.QueryExecModeExec, nil
+	case "simple_protocol":
+		return pgx.QueryExecModeSimpleProtocol, nil
+	default:
+		return 0, fmt.Errorf("pgkit: config invalid query_exec_mode value %q", mode)
+	}
+}
+
 type hasErr interface {
 	Err() error
 }
@@ -0,0 +1,123 @@
+package pgkittest
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// Beginner opens the transaction the EXPLAIN helpers run in, ie. a
+// *pgxpool.Pool, a *pgx.Conn or a pgx.Tx.
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Plan is a node of a query plan, as returned by EXPLAIN (FORMAT JSON).
+type Plan struct {
+	NodeType      string `json:"Node Type"`
+	RelationName  string `json:"Relation Name"`
+	IndexName     string `json:"Index Name"`
+	SortSpaceType string `json:"Sort Space Type"`
+	Plans         []Plan `json:"Plans"`
+}
+
+// Walk calls fn for the plan node and all its children.
+func (p Plan) Walk(fn func(node Plan)) {
+	fn(p)
+	for _, child := range p.Plans {
+		child.Walk(fn)
+	}
+}
+
+// String returns the node types of the plan as an indented tree.
+func (p Plan) String() string {
+	var b strings.Builder
+	p.format(&b, 0)
+	return b.String()
+}
+
+func (p Plan) format(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth) + p.NodeType)
+	if p.RelationName != "" {
+		b.WriteString(" on " + p.RelationName)
+	}
+	if p.IndexName != "" {
+		b.WriteString(" using " + p.IndexName)
+	}
+	b.WriteString("\n")
+	for _, child := range p.Plans {
+		child.format(b, depth+1)
+	}
+}
+
+// Explain returns the plan of the query. With analyze, the query is executed,
+// within a transaction which is rolled back.
+func Explain(t testing.TB, db Beginner, query Sqlizer, analyze bool) Plan {
+	t.Helper()
+	return explain(t, db, query, analyze, false)
+}
+
+// AssertIndexScan fails the test when the plan of the query contains a
+// sequential scan. Sequential scans are disabled while planning, so the
+// planner only falls back to them when no index can serve the query, which
+// makes the assertion independent of the size of the test tables.
+func AssertIndexScan(t testing.TB, db Beginner, query Sqlizer) {
+	t.Helper()
+
+	plan := explain(t, db, query, false, true)
+	plan.Walk(func(node Plan) {
+		if node.NodeType == "Seq Scan" {
+			require.Failf(t, "unexpected sequential scan", "on %s, plan:\n%s", node.RelationName, plan)
+		}
+	})
+}
+
+// AssertNoSortSpill fails the test when a sort of the query spills to disk,
+// ie. because work_mem is too small for it. The query is executed, within a
+// transaction which is rolled back.
+func AssertNoSortSpill(t testing.TB, db Beginner, query Sqlizer) {
+	t.Helper()
+
+	plan := explain(t, db, query, true, false)
+	plan.Walk(func(node Plan) {
+		if node.SortSpaceType == "Disk" {
+			require.Failf(t, "unexpected sort spill to disk", "plan:\n%s", plan)
+		}
+	})
+}
+
+func explain(t testing.TB, db Beginner, query Sqlizer, analyze, noSeqScan bool) Plan {
+	t.Helper()
+
+	sql, args := buildSQL(t, query)
+	ctx := context.Background()
+
+	tx, err := db.Begin(ctx)
+	require.NoError(t, err, "explain begin")
+	defer tx.Rollback(ctx)
+
+	if noSeqScan {
+		_, err := tx.Exec(ctx, "SET LOCAL enable_seqscan = off")
+		require.NoError(t, err, "explain disable seqscan")
+	}
+
+	stmt := "EXPLAIN (FORMAT JSON) "
+	if analyze {
+		stmt = "EXPLAIN (ANALYZE, FORMAT JSON) "
+	}
+
+	var out []byte
+	err = tx.QueryRow(ctx, stmt+sql, args...).Scan(&out)
+	require.NoError(t, err, "explain query")
+
+	var plans []struct {
+		Plan Plan `json:"Plan"`
+	}
+	require.NoError(t, json.Unmarshal(out, &plans), "explain output")
+	require.NotEmpty(t, plans, "explain output")
+	return plans[0].Plan
+}
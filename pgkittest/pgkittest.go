@@ -0,0 +1,188 @@
+// Package pgkittest provides helpers to lock down the SQL generated by pgkit
+// builders in unit tests, without needing a database connection, and to
+// assert on the query plans of queries against a seeded database in CI.
+package pgkittest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/require"
+)
+
+// UpdateEnv is the environment variable which, when set to a non-empty value,
+// makes AssertGoldenSQL (re)write golden files instead of comparing them.
+const UpdateEnv = "PGKITTEST_UPDATE"
+
+// Sqlizer is satisfied by squirrel builders, pgkit builders and pgkit.RawSQL.
+type Sqlizer interface {
+	ToSql() (string, []interface{}, error)
+}
+
+type hasErr interface {
+	Err() error
+}
+
+// AssertSQL builds the query and compares it against wantSQL and wantArgs. Both
+// the generated and the expected SQL are normalized before comparison, see
+// NormalizeSQL. A nil and an empty wantArgs are considered equal.
+func AssertSQL(t testing.TB, query Sqlizer, wantSQL string, wantArgs []interface{}) {
+	t.Helper()
+
+	sql, args := buildSQL(t, query)
+
+	require.Equal(t, NormalizeSQL(wantSQL), NormalizeSQL(sql), "unexpected SQL")
+	if len(wantArgs) == 0 && len(args) == 0 {
+		return
+	}
+	require.Equal(t, wantArgs, args, "unexpected SQL args")
+}
+
+// AssertGoldenSQL builds the query and compares it against the contents of the
+// golden file at path. When the PGKITTEST_UPDATE environment variable is set,
+// the golden file is written instead.
+func AssertGoldenSQL(t testing.TB, query Sqlizer, path string) {
+	t.Helper()
+
+	sql, args := buildSQL(t, query)
+	got := formatGolden(NormalizeSQL(sql), args)
+
+	if os.Getenv(UpdateEnv) != "" {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "reading golden file, set %s=1 to create it", UpdateEnv)
+	require.Equal(t, string(want), got, "SQL does not match golden file %s", path)
+}
+
+// NormalizeSQL collapses runs of whitespace into a single space and renumbers
+// placeholders, so that both `?` and `$N` become `$1`, `$2`, ... in order of
+// first appearance. A reused `$N` keeps the number of its first appearance.
+// In statements with `$N` placeholders, `?` is the JSONB operator and is left
+// untouched, while in statements with `?` placeholders the operator is
+// escaped as `??`, and normalized to `?`. Quoted literals and identifiers are
+// left untouched.
+func NormalizeSQL(sql string) string {
+	var (
+		b       strings.Builder
+		n       int
+		space   bool
+		dollars = hasDollarPlaceholders(sql)
+		numbers = map[string]int{}
+	)
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		if space && !unicode.IsSpace(rune(c)) {
+			b.WriteByte(' ')
+			space = false
+		}
+
+		switch {
+		case c == '\'' || c == '"':
+			j := quoteEnd(sql, i)
+			b.WriteString(sql[i : j+1])
+			i = j
+
+		case unicode.IsSpace(rune(c)):
+			space = b.Len() > 0
+
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			j := i + 1
+			for j < len(sql) && isDigit(sql[j]) {
+				j++
+			}
+			num, ok := numbers[sql[i:j]]
+			if !ok {
+				n++
+				num = n
+				numbers[sql[i:j]] = num
+			}
+			b.WriteString("$" + strconv.Itoa(num))
+			i = j - 1
+
+		case c == '?' && !dollars:
+			if i+1 < len(sql) && sql[i+1] == '?' {
+				// escaped JSONB operator
+				b.WriteByte('?')
+				i++
+				continue
+			}
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// quoteEnd returns the index of the quote closing the literal or identifier
+// quoted at i, or the last index if it's not closed.
+func quoteEnd(sql string, i int) int {
+	c := sql[i]
+	j := i + 1
+	for ; j < len(sql); j++ {
+		if sql[j] == c {
+			// doubled quote is an escaped quote
+			if j+1 < len(sql) && sql[j+1] == c {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(sql) - 1
+}
+
+// hasDollarPlaceholders reports whether the statement has `$N` placeholders
+// outside of quoted literals and identifiers.
+func hasDollarPlaceholders(sql string) bool {
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"':
+			i = quoteEnd(sql, i)
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			return true
+		}
+	}
+	return false
+}
+
+func buildSQL(t testing.TB, query Sqlizer) (string, []interface{}) {
+	t.Helper()
+
+	if getErr, ok := query.(hasErr); ok && getErr.Err() != nil {
+		require.NoError(t, getErr.Err(), "query builder error")
+	}
+
+	sql, args, err := query.ToSql()
+	require.NoError(t, err, "query ToSql")
+
+	return sql, args
+}
+
+func formatGolden(sql string, args []interface{}) string {
+	var b strings.Builder
+	b.WriteString(sql)
+	b.WriteString("\n")
+	for i, arg := range args {
+		fmt.Fprintf(&b, "-- $%d: %#v\n", i+1, arg)
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,61 @@
+package pgkittest_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/goware/pgkit/v2"
+	"github.com/goware/pgkit/v2/pgkittest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1 AND b = $2", pgkittest.NormalizeSQL("SELECT *\n\tFROM t\n WHERE a = ?  AND b = ?"))
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1 AND b = $2", pgkittest.NormalizeSQL("  SELECT * FROM t WHERE a = $4 AND b = $7 "))
+	assert.Equal(t, "SELECT 'a  ?' FROM \"t  $1\" WHERE a = $1", pgkittest.NormalizeSQL("SELECT 'a  ?' FROM \"t  $1\" WHERE a = ?"))
+	assert.Equal(t, "SELECT 'it''s ?' WHERE a = $1", pgkittest.NormalizeSQL("SELECT 'it''s ?' WHERE a = $2"))
+
+	// reused placeholders keep their number
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1 OR b = $1 AND c = $2", pgkittest.NormalizeSQL("SELECT * FROM t WHERE a = $1 OR b = $1 AND c = $2"))
+	assert.Equal(t, "SELECT * FROM t WHERE a = $1 OR b = $1 AND c = $2", pgkittest.NormalizeSQL("SELECT * FROM t WHERE a = $3 OR b = $3 AND c = $5"))
+
+	// JSONB operators, escaped as ?? in statements with ? placeholders
+	assert.Equal(t, "SELECT * FROM t WHERE data ? 'a' AND data ?| $1 AND b = $2", pgkittest.NormalizeSQL("SELECT * FROM t WHERE data ? 'a' AND data ?| $1 AND b = $2"))
+	assert.Equal(t, "SELECT * FROM t WHERE data ? 'a' AND data ?| $1 AND b = $2", pgkittest.NormalizeSQL("SELECT * FROM t WHERE data ?? 'a' AND data ??| ? AND b = ?"))
+}
+
+func TestAssertSQL(t *testing.T) {
+	q := sq.Select("id", "name").From("accounts").Where(sq.Eq{"name": "peter"}).PlaceholderFormat(sq.Dollar)
+	pgkittest.AssertSQL(t, q, `
+		SELECT id, name
+		FROM accounts
+		WHERE name = ?`, []interface{}{"peter"})
+
+	pgkittest.AssertSQL(t, pgkit.RawQuery("SELECT 1").Build(), "SELECT 1", nil)
+}
+
+func TestAssertGoldenSQL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "select.golden")
+	q := sq.Select("*").From("accounts").Where(sq.Eq{"id": 1})
+
+	t.Setenv(pgkittest.UpdateEnv, "1")
+	pgkittest.AssertGoldenSQL(t, q, path)
+
+	t.Setenv(pgkittest.UpdateEnv, "")
+	pgkittest.AssertGoldenSQL(t, q, path)
+}
+
+func TestPlanString(t *testing.T) {
+	plan := pgkittest.Plan{
+		NodeType: "Sort",
+		Plans: []pgkittest.Plan{
+			{NodeType: "Index Scan", RelationName: "accounts", IndexName: "accounts_pkey"},
+		},
+	}
+	assert.Equal(t, "Sort\n  Index Scan on accounts using accounts_pkey\n", plan.String())
+
+	var nodes []string
+	plan.Walk(func(node pgkittest.Plan) { nodes = append(nodes, node.NodeType) })
+	assert.Equal(t, []string{"Sort", "Index Scan"}, nodes)
+}
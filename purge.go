@@ -0,0 +1,70 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type PurgeOptions struct {
+	// Column is the soft-delete timestamp column. Default is "deleted_at".
+	Column string
+	// BatchSize is the max number of rows deleted per statement. Default is 1000.
+	BatchSize uint64
+	// Sleep is the pause between batches, to go easy on replication and
+	// vacuum. Default is no pause.
+	Sleep time.Duration
+}
+
+// PurgeDeletedBefore hard-deletes rows of a soft-deleted table whose deletion
+// timestamp is older than cutoff. Rows are deleted in bounded batches until
+// none are left or the context is done, which makes it suitable to run as a
+// background job. The table name, optionally schema-qualified, and the column
+// are quoted as identifiers. It returns the total number of rows deleted.
+func (q *Querier) PurgeDeletedBefore(ctx context.Context, tableName string, cutoff time.Time, opts *PurgeOptions) (int64, error) {
+	var o PurgeOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Column == "" {
+		o.Column = "deleted_at"
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = 1000
+	}
+
+	table, column := Ident(tableName), Ident(o.Column)
+	if err := table.Validate(); err != nil {
+		return 0, err
+	}
+	if err := column.Validate(); err != nil {
+		return 0, err
+	}
+	// the quoted identifiers may contain "?", so the cutoff is passed as $1
+	// rather than as a placeholder of RawQuery
+	stmt := sq.Expr(fmt.Sprintf(`DELETE FROM %s WHERE ctid IN (SELECT ctid FROM %s WHERE %s < $1 LIMIT %d)`,
+		table, table, column, o.BatchSize), cutoff)
+
+	var total int64
+	for {
+		tag, err := q.Exec(ctx, stmt)
+		if err != nil {
+			return total, err
+		}
+		total += tag.RowsAffected()
+
+		if uint64(tag.RowsAffected()) < o.BatchSize {
+			return total, nil
+		}
+
+		if o.Sleep > 0 {
+			select {
+			case <-ctx.Done():
+				return total, wrapErr(fmt.Errorf("purge interrupted: %w", ctx.Err()))
+			case <-time.After(o.Sleep):
+			}
+		}
+	}
+}
@@ -3,8 +3,12 @@ package pgkit
 import (
 	"bytes"
 	"context"
+	"database/sql/driver"
 	"fmt"
+	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/georgysavva/scany/v2/pgxscan"
@@ -14,10 +18,54 @@ import (
 )
 
 type Querier struct {
-	pool *pgxpool.Pool
-	tx   pgx.Tx
-	Scan *pgxscan.API
-	SQL  *StatementBuilder
+	pool      *pgxpool.Pool
+	heavyPool *pgxpool.Pool
+	tx        pgx.Tx
+	breaker   *CircuitBreaker
+	retry     *RetryConfig
+	limiter   *Limiter
+	// middlewares wrap the execution of the statements, see Use
+	middlewares []Middleware
+	// settings are set for the statements, see WithSettings
+	settings *settings
+	// writeFencing reports writes rejected by read-only servers, see
+	// Config.WriteFencing
+	writeFencing bool
+	// maxRows is the max number of rows of GetAll, see WithMaxRows
+	maxRows int
+	timeout time.Duration
+	// heavyTimeout is the default query timeout of Heavy, see
+	// HeavyConfig.StatementTimeout
+	heavyTimeout time.Duration
+	// tagComments embeds the context query tag in the statements
+	tagComments bool
+	Scan        *pgxscan.API
+	SQL         *StatementBuilder
+}
+
+// WithCircuitBreaker returns a copy of the querier which guards all query
+// execution with the given circuit breaker.
+func (q *Querier) WithCircuitBreaker(cb *CircuitBreaker) *Querier {
+	qq := *q
+	qq.breaker = cb
+	return &qq
+}
+
+// Heavy returns a Querier which runs queries on the secondary pool for heavy
+// or analytical queries, see Config.Heavy. If no heavy pool is configured, or
+// the querier is bound to a transaction, the querier itself is returned. Heavy
+// queries aren't subject to Config.DefaultQueryTimeout, but to the statement
+// timeout of the heavy pool, see HeavyConfig.StatementTimeout.
+func (q *Querier) Heavy() *Querier {
+	if q.tx != nil || q.heavyPool == nil {
+		return q
+	}
+	heavy := *q
+	heavy.pool = q.heavyPool
+	// the default query timeout of the primary pool would cut heavy queries
+	// off, they run with the statement timeout of the heavy pool instead
+	heavy.timeout = q.heavyTimeout
+	return &heavy
 }
 
 func (q *Querier) Exec(ctx context.Context, query Sqlizer) (pgconn.CommandTag, error) {
@@ -30,18 +78,37 @@ func (q *Querier) Exec(ctx context.Context, query Sqlizer) (pgconn.CommandTag, e
 	if err != nil {
 		return pgconn.CommandTag{}, wrapErr(err)
 	}
+	sql = q.tagged(ctx, sql)
 
-	var tag pgconn.CommandTag
-	if q.tx != nil {
-		tag, err = q.tx.Exec(ctx, sql, args...)
-	} else {
-		tag, err = q.pool.Exec(ctx, sql, args...)
+	res, err := q.handler()(ctx, Statement{SQL: sql, Args: args, InTx: q.tx != nil})
+	if err != nil {
+		return pgconn.CommandTag{}, wrapErr(err)
 	}
+	return res.CommandTag, nil
+}
 
+// ExecAffected executes the query and returns the number of rows affected.
+func (q *Querier) ExecAffected(ctx context.Context, query Sqlizer) (int64, error) {
+	tag, err := q.Exec(ctx, query)
 	if err != nil {
-		return pgconn.CommandTag{}, wrapErr(err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ExecExpecting executes the query and returns a *RowCountError, matching
+// ErrUnexpectedRowCount, if the number of rows affected is not n. Note the
+// statement is not rolled back in that case, run it within a transaction to
+// do so.
+func (q *Querier) ExecExpecting(ctx context.Context, query Sqlizer, n int64) error {
+	affected, err := q.ExecAffected(ctx, query)
+	if err != nil {
+		return err
+	}
+	if affected != n {
+		return &RowCountError{Expected: n, Actual: affected}
 	}
-	return tag, nil
+	return nil
 }
 
 func (q *Querier) QueryRows(ctx context.Context, query Sqlizer) (pgx.Rows, error) {
@@ -54,21 +121,25 @@ func (q *Querier) QueryRows(ctx context.Context, query Sqlizer) (pgx.Rows, error
 	if err != nil {
 		return nil, wrapErr(err)
 	}
+	sql = q.tagged(ctx, sql)
 
-	var rows pgx.Rows
-	if q.tx != nil {
-		rows, err = q.tx.Query(ctx, sql, args...)
-	} else {
-		rows, err = q.pool.Query(ctx, sql, args...)
-	}
-
+	res, err := q.handler()(ctx, Statement{SQL: sql, Args: args, Rows: true, InTx: q.tx != nil})
 	if err != nil {
 		return nil, wrapErr(err)
 	}
-	return rows, nil
+	return res.Rows, nil
 }
 
 func (q *Querier) QueryRow(ctx context.Context, query Sqlizer) pgx.Row {
+	if len(q.middlewares) > 0 || q.settings != nil || hasApplicationName(ctx) {
+		// run through the middlewares and settings as a rows query
+		rows, err := q.QueryRows(ctx, query)
+		if err != nil {
+			return errRow{err}
+		}
+		return rowsRow{rows}
+	}
+
 	// check for query errors
 	if getErr, ok := query.(hasErr); ok && getErr.Err() != nil {
 		return errRow{wrapErr(getErr.Err())}
@@ -78,7 +149,32 @@ func (q *Querier) QueryRow(ctx context.Context, query Sqlizer) pgx.Row {
 	if err != nil {
 		return errRow{wrapErr(err)}
 	}
+	sql = q.tagged(ctx, sql)
+
+	ctx, done, err := q.execContext(ctx)
+	if err != nil {
+		return errRow{wrapErr(err)}
+	}
+
+	var row pgx.Row
+	if q.breaker != nil {
+		report, err := q.guard()
+		if err != nil {
+			done()
+			return errRow{wrapErr(err)}
+		}
+		row = breakerRow{Row: q.queryRow(ctx, sql, args...), report: report}
+	} else {
+		row = q.queryRow(ctx, sql, args...)
+	}
+
+	if q.tracksDone() {
+		return doneRow{Row: row, done: done}
+	}
+	return row
+}
 
+func (q *Querier) queryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
 	if q.tx != nil {
 		return q.tx.QueryRow(ctx, sql, args...)
 	} else {
@@ -86,29 +182,147 @@ func (q *Querier) QueryRow(ctx context.Context, query Sqlizer) pgx.Row {
 	}
 }
 
-func (q *Querier) GetAll(ctx context.Context, query Sqlizer, dest interface{}) error {
-	rows, err := q.QueryRows(ctx, query)
+// GetAll scans all rows of the query into dest, a pointer to a slice, with
+// the given scan options, if any. It fails with a *MaxRowsError if the query
+// returns more rows than the max rows of the querier, see WithMaxRows.
+func (q *Querier) GetAll(ctx context.Context, query Sqlizer, dest interface{}, options ...ScanOption) error {
+	err := q.retryRead(ctx, query, func() error {
+		rows, err := q.QueryRows(ctx, query)
+		if err != nil {
+			return wrapErr(err)
+		}
+		return wrapErr(q.Scan.ScanAll(dest, q.limitRows(scanRows(rows, options))))
+	})
 	if err != nil {
-		return wrapErr(err)
+		return err
 	}
-	return wrapErr(q.Scan.ScanAll(dest, rows))
+	return afterLoad(ctx, dest)
 }
 
-func (q *Querier) GetOne(ctx context.Context, query Sqlizer, dest interface{}) error {
+// GetOne scans the first row of the query into dest, with the given scan
+// options, if any. Select and delete builders are limited to one row.
+func (q *Querier) GetOne(ctx context.Context, query Sqlizer, dest interface{}, options ...ScanOption) error {
 	switch builder := query.(type) {
 	case sq.SelectBuilder:
 		query = builder.Limit(1)
+	case SelectBuilder:
+		query = builder.Limit(1)
 	case sq.DeleteBuilder:
 		query = builder.Limit(1)
 	}
 
-	rows, err := q.QueryRows(ctx, query)
+	err := q.retryRead(ctx, query, func() error {
+		rows, err := q.QueryRows(ctx, query)
+		if err != nil {
+			return wrapErr(err)
+		}
+		return wrapErr(q.Scan.ScanOne(dest, scanRows(rows, options)))
+	})
+	if err != nil {
+		return err
+	}
+	return afterLoad(ctx, dest)
+}
+
+// GetExactlyOne is like GetOne, but fails with ErrTooManyRows when more than
+// one row matches the query, instead of returning the first one, for lookups
+// where duplicates indicate data corruption. Select builders are limited to
+// two rows.
+func (q *Querier) GetExactlyOne(ctx context.Context, query Sqlizer, dest interface{}, options ...ScanOption) error {
+	switch builder := query.(type) {
+	case sq.SelectBuilder:
+		query = builder.Limit(2)
+	case SelectBuilder:
+		query = builder.Limit(2)
+	}
+
+	err := q.retryRead(ctx, query, func() error {
+		rows, err := q.QueryRows(ctx, query)
+		if err != nil {
+			return wrapErr(err)
+		}
+		defer rows.Close()
+
+		rows = scanRows(rows, options)
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return wrapErr(err)
+			}
+			return wrapErr(ErrNoRows)
+		}
+		if err := q.Scan.ScanRow(dest, rows); err != nil {
+			return wrapErr(err)
+		}
+		if rows.Next() {
+			return wrapErr(ErrTooManyRows)
+		}
+		return wrapErr(rows.Err())
+	})
 	if err != nil {
+		return err
+	}
+	return afterLoad(ctx, dest)
+}
+
+// DeleteReturning deletes the rows matching the query and scans the deleted
+// rows into dest, a pointer to a slice, in the same statement, ie. to archive
+// or audit them.
+func (q *Querier) DeleteReturning(ctx context.Context, query sq.DeleteBuilder, dest interface{}) error {
+	return q.GetAll(ctx, query.Suffix("RETURNING *"), dest)
+}
+
+// UpsertCounts runs the upsert, see StatementBuilder.UpsertRecords, and
+// returns the number of rows created and updated.
+func (q *Querier) UpsertCounts(ctx context.Context, query InsertBuilder) (created, updated int64, err error) {
+	var results []bool
+	if err := q.GetAll(ctx, query.ReturningCreated(), &results); err != nil {
+		return 0, 0, err
+	}
+	for _, ok := range results {
+		if ok {
+			created++
+		} else {
+			updated++
+		}
+	}
+	return created, updated, nil
+}
+
+// GetAllMaps returns all rows of the query as maps of column names to values,
+// for dynamic queries where the column set isn't known at compile time.
+func (q *Querier) GetAllMaps(ctx context.Context, query Sqlizer) ([]map[string]interface{}, error) {
+	var result []map[string]interface{}
+	err := q.retryRead(ctx, query, func() error {
+		rows, err := q.QueryRows(ctx, query)
+		if err != nil {
+			return wrapErr(err)
+		}
+		result, err = pgx.CollectRows(q.limitRows(rows), pgx.RowToMap)
 		return wrapErr(err)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return wrapErr(q.Scan.ScanOne(dest, rows))
+	return result, nil
 }
 
+// Debug resolves the query to its SQL and arguments without executing it,
+// which is handy for code review tooling and local debugging.
+func (q *Querier) Debug(query Sqlizer) (string, []interface{}, error) {
+	// check for query errors
+	if getErr, ok := query.(hasErr); ok && getErr.Err() != nil {
+		return "", nil, wrapErr(getErr.Err())
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, wrapErr(err)
+	}
+	return sql, args, nil
+}
+
+// BatchExec executes the queries in batches, see BatchMaxStatements and
+// BatchMaxParams, and returns the command tags of all of them.
 func (q *Querier) BatchExec(ctx context.Context, queries Queries) ([]pgconn.CommandTag, error) {
 	if len(queries) == 0 {
 		return nil, wrapErr(fmt.Errorf("empty query"))
@@ -122,38 +336,58 @@ func (q *Querier) BatchExec(ctx context.Context, queries Queries) ([]pgconn.Comm
 	}
 
 	// Prepare queries
-	batch := &pgx.Batch{}
-	for _, query := range queries {
-		sql, args, err := query.ToSql()
-		if err != nil {
-			return nil, wrapErr(err)
-		}
-		batch.Queue(sql, args...)
+	batchList, err := batches(queries, func(sql string) string { return q.tagged(ctx, sql) })
+	if err != nil {
+		return nil, err
 	}
+	// the results of each batch are closed before returning
+	defer putBatches(batchList)
 
-	// Send batch
-	var results pgx.BatchResults
-	if q.tx != nil {
-		results = q.tx.SendBatch(ctx, batch)
-	} else {
-		results = q.pool.SendBatch(ctx, batch)
+	ctx, done, err := q.execContext(ctx)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	defer done()
+
+	if err := q.applySettings(ctx); err != nil {
+		return nil, wrapErr(err)
 	}
-	defer results.Close()
 
-	// Exec the number of times as we have queries in the batch so we may get the exec
-	// result and potential error response.
-	tags := make([]pgconn.CommandTag, 0, batch.Len())
-	for i := 0; i < batch.Len(); i++ {
-		tag, err := results.Exec()
+	tags := make([]pgconn.CommandTag, 0, len(queries))
+	for _, batch := range batchList {
+		err = q.run(func() error {
+			// Send batch
+			var results pgx.BatchResults
+			if q.tx != nil {
+				results = q.tx.SendBatch(ctx, batch)
+			} else {
+				results = q.pool.SendBatch(ctx, batch)
+			}
+			defer results.Close()
+
+			// Exec the number of times as we have queries in the batch so we may get the exec
+			// result and potential error response.
+			for i := 0; i < batch.Len(); i++ {
+				tag, err := results.Exec()
+				if err != nil {
+					return err
+				}
+				tags = append(tags, tag)
+			}
+			return nil
+		})
 		if err != nil {
 			return tags, wrapErr(err)
 		}
-		tags = append(tags, tag)
 	}
 
 	return tags, nil
 }
 
+// BatchQuery sends the queries in batches, see BatchMaxStatements and
+// BatchMaxParams, and returns their results along with the number of queries.
+// Batches after the first one are sent as the results of the previous one are
+// read.
 func (q *Querier) BatchQuery(ctx context.Context, queries Queries) (pgx.BatchResults, int, error) {
 	if len(queries) == 0 {
 		return nil, 0, wrapErr(fmt.Errorf("empty query"))
@@ -167,26 +401,49 @@ func (q *Querier) BatchQuery(ctx context.Context, queries Queries) (pgx.BatchRes
 	}
 
 	// Prepare queries
-	batch := &pgx.Batch{}
-	for _, query := range queries {
-		sql, args, err := query.ToSql()
-		if err != nil {
-			return nil, 0, wrapErr(err)
+	batchList, err := batches(queries, func(sql string) string { return q.tagged(ctx, sql) })
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, done, err := q.execContext(ctx)
+	if err != nil {
+		return nil, 0, wrapErr(err)
+	}
+	if err := q.applySettings(ctx); err != nil {
+		done()
+		return nil, 0, wrapErr(err)
+	}
+
+	// Send batch, its errors are only known once the caller reads the results
+	report, err := q.guard()
+	if err != nil {
+		done()
+		return nil, 0, wrapErr(err)
+	}
+
+	send := func(batch *pgx.Batch) pgx.BatchResults {
+		if q.tx != nil {
+			return q.tx.SendBatch(ctx, batch)
 		}
-		batch.Queue(sql, args...)
+		return q.pool.SendBatch(ctx, batch)
 	}
 
-	// Send batch
 	var batchResults pgx.BatchResults
-	if q.tx != nil {
-		batchResults = q.tx.SendBatch(ctx, batch)
+	if len(batchList) == 1 {
+		batchResults = send(batchList[0])
 	} else {
-		batchResults = q.pool.SendBatch(ctx, batch)
+		batchResults = &chunkedBatchResults{send: send, batches: batchList}
+	}
+	if q.breaker != nil {
+		batchResults = newBreakerBatchResults(batchResults, report)
+	}
+	if q.tracksDone() {
+		batchResults = doneBatchResults{BatchResults: batchResults, done: done}
 	}
-	// defer results.Close()
 
 	// NOTE: the caller of BatchQuery must close the `batchResults` themselves.
-	return batchResults, batch.Len(), nil
+	return batchResults, len(queries), nil
 }
 
 // NOTE: WIP/experimentation to offer sugar to scan a batch of the same kinds of queries.
@@ -277,15 +534,15 @@ func (r RawSQL) Err() error {
 }
 
 func (r RawSQL) ToSql() (string, []interface{}, error) {
-	if r.Query == "" {
-		return "", nil, fmt.Errorf("pgkit: empty query called with ToSql")
-	}
-
 	if r.err != nil {
 		// error may have occured somewhere when building the query
 		return "", nil, r.err
 	}
 
+	if r.Query == "" {
+		return "", nil, fmt.Errorf("pgkit: empty query called with ToSql")
+	}
+
 	if r.statement {
 		// for statement queries, we assume its prepared correctly by RawStatement
 		return r.Query, r.Args, nil
@@ -316,10 +573,25 @@ func (r RawSQL) ToSql() (string, []interface{}, error) {
 
 // RawStatement allows you to build query statements by hand, where the query will remain the same
 // but the arguments can change. The number of arguments must always be the same.
+//
+// An argument for a placeholder within an `IN (?)` list may be a slice, which
+// gets expanded into one placeholder per element at Build time, ie.
+//
+//	RawQuery("SELECT * FROM accounts WHERE id IN (?) AND disabled = ?").Build([]int64{1, 2, 3}, false)
+//
+// An empty slice is expanded to `IN (NULL)`, which matches no rows. As
+// `NOT IN (NULL)` doesn't match any row either, an empty slice in a NOT IN list
+// is an error. Slices passed for other placeholders, ie. `= ANY(?)`, are bound
+// as arrays.
 type RawStatement struct {
 	query   RawSQL
 	numArgs int
 	err     error
+
+	// raw is the query with ? placeholders and inList marks the placeholders
+	// within an IN list, for slice expansion.
+	raw    string
+	inList []bool
 }
 
 func (r RawStatement) Err() error {
@@ -335,12 +607,92 @@ func (r RawStatement) NumArgs() int {
 }
 
 func (r RawStatement) Build(args ...interface{}) Sqlizer {
+	if r.err != nil {
+		return RawSQL{err: r.err}
+	}
 	if len(args) != r.numArgs {
 		return RawSQL{err: fmt.Errorf("pgkit: invalid arguments passed to statement, expecting %d args but received %d", r.numArgs, len(args))}
 	}
+	for i, arg := range args {
+		if r.inList[i] && isExpandableSlice(arg) {
+			query, expanded, err := r.expand(args)
+			if err != nil {
+				return RawSQL{err: err}
+			}
+			return RawSQL{Query: query, Args: expanded, statement: true}
+		}
+	}
 	return RawSQL{Query: r.query.Query, Args: args, statement: true}
 }
 
+// expand numbers the placeholders of the raw query, expanding the slice
+// arguments of IN lists into one placeholder per element.
+func (r RawStatement) expand(args []interface{}) (string, []interface{}, error) {
+	parts := strings.Split(r.raw, "?")
+	expanded := make([]interface{}, 0, len(args))
+
+	var q strings.Builder
+	for i, part := range parts {
+		q.WriteString(part)
+		if i >= len(args) {
+			break
+		}
+
+		if !r.inList[i] || !isExpandableSlice(args[i]) {
+			expanded = append(expanded, args[i])
+			fmt.Fprintf(&q, "$%d", len(expanded))
+			continue
+		}
+
+		v := reflect.ValueOf(args[i])
+		if v.Len() == 0 {
+			if notInListPlaceholder.MatchString(part) {
+				return "", nil, fmt.Errorf("pgkit: empty slice passed for NOT IN list placeholder %d", i+1)
+			}
+			q.WriteString("NULL")
+			continue
+		}
+		for j := 0; j < v.Len(); j++ {
+			if j > 0 {
+				q.WriteString(", ")
+			}
+			expanded = append(expanded, v.Index(j).Interface())
+			fmt.Fprintf(&q, "$%d", len(expanded))
+		}
+	}
+	return q.String(), expanded, nil
+}
+
+var (
+	inListPlaceholder    = regexp.MustCompile(`(?i)\bIN\s*\(\s*$`)
+	notInListPlaceholder = regexp.MustCompile(`(?i)\bNOT\s+IN\s*\(\s*$`)
+)
+
+// inListPlaceholders reports for each ? placeholder of the query whether it is
+// the sole element of an IN list.
+func inListPlaceholders(query string) []bool {
+	parts := strings.Split(query, "?")
+	inList := make([]bool, len(parts)-1)
+	for i := range inList {
+		inList[i] = inListPlaceholder.MatchString(parts[i]) && strings.HasPrefix(strings.TrimSpace(parts[i+1]), ")")
+	}
+	return inList
+}
+
+// isExpandableSlice reports whether v is a slice to expand in an IN list, as
+// opposed to a value which happens to be a slice, ie. []byte or a
+// driver.Valuer like dbtype.Vector.
+func isExpandableSlice(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
 func RawQuery(query string) RawStatement {
 	rs := RawStatement{}
 	rq := RawSQL{Query: query, statement: true}
@@ -355,6 +707,9 @@ func RawQuery(query string) RawStatement {
 	rq.Query = q
 	rs.query = rq
 	rs.numArgs = n
+	rs.raw = query
+	rs.inList = inListPlaceholders(query)
+	trackStatement(rs)
 	return rs
 }
 
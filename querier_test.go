@@ -0,0 +1,109 @@
+package pgkit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/goware/pgkit/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerierDebug(t *testing.T) {
+	q := &pgkit.Querier{}
+
+	sql, args, err := q.Debug(sq.Select("*").From("accounts").Where(sq.Eq{"id": 1}).PlaceholderFormat(sq.Dollar))
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM accounts WHERE id = $1", sql)
+	require.Equal(t, []interface{}{1}, args)
+
+	_, _, err = q.Debug(pgkit.RawQuery("SELECT ?").Build())
+	require.Error(t, err)
+}
+
+func TestRawStatementSliceExpansion(t *testing.T) {
+	stmt := pgkit.RawQuery("SELECT * FROM accounts WHERE id IN (?) AND name = ? AND tags = ANY(?) AND id NOT IN ( ? )")
+	require.Equal(t, 4, stmt.NumArgs())
+
+	sql, args, err := stmt.Build([]int64{1, 2, 3}, "peter", []string{"a"}, []int{4}).ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM accounts WHERE id IN ($1, $2, $3) AND name = $4 AND tags = ANY($5) AND id NOT IN ( $6 )", sql)
+	require.Equal(t, []interface{}{int64(1), int64(2), int64(3), "peter", []string{"a"}, 4}, args)
+
+	sql, args, err = stmt.Build([]int64{}, "peter", []string{"a"}, 4).ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM accounts WHERE id IN (NULL) AND name = $1 AND tags = ANY($2) AND id NOT IN ( $3 )", sql)
+	require.Equal(t, []interface{}{"peter", []string{"a"}, 4}, args)
+
+	_, _, err = stmt.Build(1, "peter", []string{"a"}, []int{}).ToSql()
+	require.Error(t, err)
+
+	// without slices the prepared query is used as is
+	sql, _, err = stmt.Build(1, "peter", []string{"a"}, 4).ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM accounts WHERE id IN ($1) AND name = $2 AND tags = ANY($3) AND id NOT IN ( $4 )", sql)
+}
+
+func TestRawQueryIdent(t *testing.T) {
+	stmt := pgkit.RawQueryIdent("SELECT * FROM %I WHERE %I = ?", "tenant_1.accounts", `na"me`)
+	require.NoError(t, stmt.Err())
+
+	sql, args, err := stmt.Build(1).ToSql()
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM "tenant_1"."accounts" WHERE "na""me" = $1`, sql)
+	require.Equal(t, []interface{}{1}, args)
+
+	require.Error(t, pgkit.RawQueryIdent("SELECT * FROM %I", "tenant_1.").Err())
+	require.Error(t, pgkit.RawQueryIdent("SELECT * FROM %I.%I", "accounts").Err())
+
+	_, _, err = pgkit.RawQueryIdent("SELECT * FROM %I", "").Build().ToSql()
+	require.ErrorContains(t, err, "invalid identifier")
+
+	require.Equal(t, `SELECT * FROM "accounts"`, pgkit.RawQueryf("SELECT * FROM %s", pgkit.Ident("accounts")).GetQuery())
+}
+
+func TestBatchTooManyParams(t *testing.T) {
+	q := &pgkit.Querier{}
+
+	args := make([]interface{}, pgkit.MaxQueryParams+1)
+	query := sq.Insert("accounts").Columns("name").PlaceholderFormat(sq.Dollar)
+	for i := range args {
+		query = query.Values(i)
+	}
+
+	_, err := q.BatchExec(context.Background(), pgkit.Queries{query})
+	require.ErrorIs(t, err, pgkit.ErrTooManyParams)
+}
+
+func TestQuerierMiddleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) pgkit.Middleware {
+		return func(next pgkit.QueryFunc) pgkit.QueryFunc {
+			return func(ctx context.Context, stmt pgkit.Statement) (pgkit.Result, error) {
+				calls = append(calls, name+": "+stmt.SQL)
+				return next(ctx, stmt)
+			}
+		}
+	}
+	stub := func(next pgkit.QueryFunc) pgkit.QueryFunc {
+		return func(ctx context.Context, stmt pgkit.Statement) (pgkit.Result, error) {
+			if stmt.Rows {
+				return pgkit.Result{}, errors.New("denied")
+			}
+			return pgkit.Result{CommandTag: pgconn.NewCommandTag("UPDATE 3")}, nil
+		}
+	}
+
+	q := (&pgkit.Querier{}).Use(trace("a")).Use(trace("b"), stub)
+
+	n, err := q.ExecAffected(context.Background(), pgkit.RawQuery("UPDATE accounts SET disabled = true").Build())
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+	require.Equal(t, []string{"a: UPDATE accounts SET disabled = true", "b: UPDATE accounts SET disabled = true"}, calls)
+
+	var name string
+	err = q.QueryRow(context.Background(), pgkit.RawQuery("SELECT name FROM accounts").Build()).Scan(&name)
+	require.ErrorContains(t, err, "denied")
+}
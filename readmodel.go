@@ -0,0 +1,77 @@
+package pgkit
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ReadModel is a read only repository of T records backed by a select query,
+// ie. with joins or CTEs, instead of a physical table. The query is wrapped
+// as a subquery, so filters and sort columns refer to its output columns:
+//
+//	articles := pgkit.NewReadModel[ArticleSummary](db.Query,
+//		db.SQL.Select("a.id", "a.title", "acc.name AS author", "COUNT(r.id) AS reviews").
+//			From("articles a").
+//			Join("accounts acc ON acc.id = a.account_id").
+//			LeftJoin("reviews r ON r.article_id = a.id").
+//			GroupBy("a.id", "acc.name"),
+//		pgkit.WithSort[ArticleSummary]("id"))
+//
+//	list, err := articles.List(ctx, sq.Eq{"author": "peter"}, page)
+type ReadModel[T any] struct {
+	Query     *Querier
+	Select    sq.SelectBuilder
+	Paginator Paginator[T]
+}
+
+// NewReadModel returns a read model over the select query, paginated with the
+// given options, see NewPaginator.
+func NewReadModel[T any](q *Querier, query sq.SelectBuilder, options ...PaginatorOption[T]) *ReadModel[T] {
+	return &ReadModel[T]{
+		Query:     q,
+		Select:    query,
+		Paginator: NewPaginator[T](options...),
+	}
+}
+
+// from returns a select of the given columns from the query, filtered by
+// where, if not nil.
+func (m *ReadModel[T]) from(where sq.Sqlizer, columns ...string) sq.SelectBuilder {
+	q := sq.Select(columns...).FromSelect(m.Select, "t").PlaceholderFormat(sq.Dollar)
+	if where != nil {
+		q = q.Where(where)
+	}
+	return q
+}
+
+// Get returns the record matching where, or ErrNoRows.
+func (m *ReadModel[T]) Get(ctx context.Context, where sq.Sqlizer) (*T, error) {
+	var record T
+	if err := m.Query.GetOne(ctx, m.from(where, "*"), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// List returns the page of records matching where, which can be nil. The page
+// is updated with the pagination state, see Paginator.PrepareResult.
+func (m *ReadModel[T]) List(ctx context.Context, where sq.Sqlizer, page *Page) ([]T, error) {
+	if page == nil {
+		page = &Page{}
+	}
+	records, q := m.Paginator.PrepareQuery(m.from(where, "*"), page)
+	if err := m.Query.GetAll(ctx, q, &records); err != nil {
+		return nil, err
+	}
+	return m.Paginator.PrepareResult(records, page), nil
+}
+
+// Count returns the number of records matching where, which can be nil.
+func (m *ReadModel[T]) Count(ctx context.Context, where sq.Sqlizer) (int64, error) {
+	var count int64
+	if err := m.Query.QueryRow(ctx, m.from(where, "COUNT(*)")).Scan(&count); err != nil {
+		return 0, wrapErr(err)
+	}
+	return count, nil
+}
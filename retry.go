@@ -0,0 +1,111 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type RetryConfig struct {
+	// MaxRetries is the number of times a read is retried. Default is 2.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry, doubled on every further
+	// attempt, plus a random jitter of up to BaseDelay. Default is 50ms.
+	BaseDelay time.Duration
+}
+
+// WithRetry returns a copy of the querier which transparently retries reads
+// done via GetOne and GetAll on transient connection errors, such as
+// connection resets, admin shutdowns or failovers. Only single SELECT queries
+// outside of a transaction are retried. Writes are never retried, including
+// SELECTs which lock rows (FOR UPDATE/SHARE), call nextval or setval, select
+// INTO a table or modify data in a CTE.
+func (q *Querier) WithRetry(cfg RetryConfig) *Querier {
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 50 * time.Millisecond
+	}
+	qq := *q
+	qq.retry = &cfg
+	return &qq
+}
+
+// retryRead runs fn, retrying it on transient errors if the querier has
+// retries enabled and the query is a read.
+func (q *Querier) retryRead(ctx context.Context, query Sqlizer, fn func() error) error {
+	err := fn()
+	if err == nil || q.retry == nil || q.tx != nil || !isReadQuery(query) {
+		return err
+	}
+
+	delay := q.retry.BaseDelay
+	for i := 0; i < q.retry.MaxRetries && isTransientErr(err); i++ {
+		jitter := time.Duration(rand.Int63n(int64(q.retry.BaseDelay) + 1))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay + jitter):
+		}
+		delay *= 2
+
+		err = fn()
+	}
+	return err
+}
+
+// isReadQuery reports whether the query is a single plain SELECT, without
+// locking clauses, data-modifying CTEs or sequence calls, so retrying it can't
+// apply a write twice. Builders are judged by their SQL, as a Prefix may turn
+// a SELECT into a write.
+func isReadQuery(query Sqlizer) bool {
+	sql, _, err := query.ToSql()
+	if err != nil {
+		return false
+	}
+	sql = strings.TrimRight(strings.TrimSpace(sql), "; \t\n")
+	if !readStatement.MatchString(sql) || strings.Contains(sql, ";") {
+		return false
+	}
+	return !writeClause.MatchString(sql)
+}
+
+var (
+	readStatement = regexp.MustCompile(`(?i)^(SELECT|WITH)\b`)
+	writeClause   = regexp.MustCompile(`(?i)\(\s*(INSERT|UPDATE|DELETE|MERGE)\b|\bFOR\s+(NO\s+KEY\s+)?UPDATE\b|\bFOR\s+(KEY\s+)?SHARE\b|\b(nextval|setval)\s*\(|\bINTO\b`)
+)
+
+// isTransientErr reports whether err is a connection level error which is
+// likely to succeed when retried on a new connection.
+func isTransientErr(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if pgconn.SafeToRetry(err) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		return strings.HasPrefix(pgErr.Code, "08") // connection exception
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,42 @@
+package pgkit
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReadQuery(t *testing.T) {
+	sql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	reads := []Sqlizer{
+		sql.Select("*").From("accounts").Where(sq.Eq{"id": 1}),
+		RawQuery("SELECT * FROM accounts WHERE id = ?").Build(1),
+		RawQuery("  select 1;").Build(),
+		RawQuery("WITH t AS (SELECT id FROM accounts) SELECT * FROM t").Build(),
+		RawQuery("SELECT updated_at, inserted FROM accounts").Build(),
+	}
+	for _, q := range reads {
+		assert.True(t, isReadQuery(q), q)
+	}
+
+	writes := []Sqlizer{
+		sql.Select("*").From("t").Prefix("WITH t AS (DELETE FROM accounts RETURNING *)"),
+		RawQuery("WITH t AS (UPDATE accounts SET disabled = true RETURNING *) SELECT * FROM t").Build(),
+		RawQuery("WITH t AS ( INSERT INTO accounts (name) VALUES ('a') RETURNING *) SELECT * FROM t").Build(),
+		RawQuery("SELECT * FROM accounts WHERE id = 1 FOR UPDATE").Build(),
+		RawQuery("SELECT * FROM accounts FOR NO KEY UPDATE SKIP LOCKED").Build(),
+		RawQuery("SELECT * FROM accounts FOR SHARE").Build(),
+		RawQuery("SELECT * FROM accounts FOR KEY SHARE").Build(),
+		RawQuery("SELECT nextval('accounts_id_seq')").Build(),
+		RawQuery("SELECT setval('accounts_id_seq', 1)").Build(),
+		RawQuery("SELECT * INTO accounts_copy FROM accounts").Build(),
+		RawQuery("SELECT 1; DELETE FROM accounts").Build(),
+		RawQuery("DELETE FROM accounts").Build(),
+		sql.Insert("accounts").Columns("name").Values("a"),
+	}
+	for _, q := range writes {
+		assert.False(t, isReadQuery(q), q)
+	}
+}
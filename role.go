@@ -0,0 +1,21 @@
+package pgkit
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AsRole runs fn within a transaction operating as the given role with
+// `SET LOCAL ROLE`, for least-privilege access where the app connects as a
+// login role but operates as narrower resource specific roles. The login role
+// must be a member of the role. The role is reset when the transaction ends,
+// so it never leaks to other users of the pooled connection.
+func (d *DB) AsRole(ctx context.Context, role string, fn func(q *Querier) error) error {
+	return d.beginFunc(ctx, 2, func(q *Querier) error {
+		if _, err := q.Exec(ctx, RawSQL{Query: "SET LOCAL ROLE " + pgx.Identifier{role}.Sanitize()}); err != nil {
+			return err
+		}
+		return fn(q)
+	})
+}
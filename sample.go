@@ -0,0 +1,44 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// SampleMinRows is the estimated table size under which Sample falls back to
+// `ORDER BY random()`, which is exact but scans the whole table.
+var SampleMinRows int64 = 10000
+
+// Sample scans about n random rows of the table matching the optional where
+// condition into dest, ie. for QA or ML training extracts.
+//
+// Large tables are sampled with TABLESAMPLE BERNOULLI, over-sampling based on
+// the planner's row estimate, so a very selective where condition may yield
+// fewer than n rows. Tables smaller than SampleMinRows are sampled exactly
+// with `ORDER BY random()`.
+func (q *Querier) Sample(ctx context.Context, tableName string, where sq.Sqlizer, n uint64, dest interface{}) error {
+	var estimate int64
+	err := q.QueryRow(ctx, RawQuery(`SELECT reltuples::bigint FROM pg_class WHERE oid = ?::regclass`).Build(tableName)).Scan(&estimate)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	var query sq.SelectBuilder
+	if estimate < SampleMinRows {
+		query = q.SQL.Select("*").From(tableName).OrderBy("random()")
+	} else {
+		// over-sample to make up for rows filtered out by where
+		pct := float64(n*3) / float64(estimate) * 100
+		if pct > 100 {
+			pct = 100
+		}
+		query = q.SQL.Select("*").From(fmt.Sprintf("%s TABLESAMPLE BERNOULLI (%f)", tableName, pct))
+	}
+	if where != nil {
+		query = query.Where(where)
+	}
+
+	return q.GetAll(ctx, query.Limit(n), dest)
+}
@@ -0,0 +1,115 @@
+package pgkit
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ScanOption customizes how the result columns of a single query are scanned
+// into the destination, see Querier.GetAll and Querier.GetOne.
+type ScanOption func(opts *scanOptions)
+
+type scanOptions struct {
+	renames map[string]string
+}
+
+// ScanRename scans the result column into the struct field tagged with the
+// given `db:""` name, ie. to scan an aggregate aliased "total_count" into the
+// "count" field.
+func ScanRename(column, field string) ScanOption {
+	return func(opts *scanOptions) {
+		opts.renames[column] = field
+	}
+}
+
+// ScanIgnore skips the result columns, leaving the struct fields of the same
+// names untouched.
+func ScanIgnore(columns ...string) ScanOption {
+	return func(opts *scanOptions) {
+		for _, column := range columns {
+			// unknown columns are skipped by the scanner
+			opts.renames[column] = ""
+		}
+	}
+}
+
+// scanRows applies the scan options to the rows, if any.
+func scanRows(rows pgx.Rows, options []ScanOption) pgx.Rows {
+	if len(options) == 0 {
+		return rows
+	}
+
+	opts := &scanOptions{renames: map[string]string{}}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	fields := append([]pgconn.FieldDescription(nil), rows.FieldDescriptions()...)
+	for i, field := range fields {
+		name, ok := opts.renames[field.Name]
+		if !ok {
+			continue
+		}
+		if name == "" {
+			name = "-ignored-" + field.Name
+		}
+		fields[i].Name = name
+	}
+	return renamedRows{Rows: rows, fields: fields}
+}
+
+// renamedRows overrides the column names of the rows.
+type renamedRows struct {
+	pgx.Rows
+	fields []pgconn.FieldDescription
+}
+
+func (r renamedRows) FieldDescriptions() []pgconn.FieldDescription {
+	return r.fields
+}
+
+// WithMaxRows returns a copy of the querier whose GetAll and GetAllMaps fail
+// with a *MaxRowsError, matching ErrTooManyRows, as soon as the query returns
+// more than n rows, guarding against accidentally unbounded queries. The
+// destination holds the first n rows in that case. Zero disables the limit.
+func (q *Querier) WithMaxRows(n int) *Querier {
+	qq := *q
+	qq.maxRows = n
+	return &qq
+}
+
+// limitRows applies the max rows of the querier to the rows, if any.
+func (q *Querier) limitRows(rows pgx.Rows) pgx.Rows {
+	if q.maxRows <= 0 {
+		return rows
+	}
+	return &maxRows{Rows: rows, max: q.maxRows}
+}
+
+// maxRows stops reading the rows once more than max rows were read.
+type maxRows struct {
+	pgx.Rows
+	max  int
+	read int
+	err  error
+}
+
+func (r *maxRows) Next() bool {
+	if r.err != nil || !r.Rows.Next() {
+		return false
+	}
+	r.read++
+	if r.read > r.max {
+		r.err = &MaxRowsError{Max: r.max}
+		r.Rows.Close()
+		return false
+	}
+	return true
+}
+
+func (r *maxRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Rows.Err()
+}
@@ -0,0 +1,233 @@
+package pgkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goware/pgkit/v2/dbtype"
+)
+
+// SchemaDiff is the difference between a record struct and the live schema
+// of its table, see Querier.DiffSchema.
+type SchemaDiff struct {
+	TableName string
+	// MissingColumns are the mapped struct columns missing from the table.
+	MissingColumns []ColumnDiff
+	// TypeMismatches are the columns whose table type doesn't match the type
+	// of their struct field.
+	TypeMismatches []ColumnDiff
+	// UnmappedColumns are the table columns without struct field.
+	UnmappedColumns []string
+}
+
+// ColumnDiff describes a column of a SchemaDiff. DBType is the current type
+// of the column, empty for missing columns, and WantType the type inferred
+// from the struct field, empty if it couldn't be inferred.
+type ColumnDiff struct {
+	Column   string
+	GoType   string
+	DBType   string
+	WantType string
+}
+
+// Empty reports whether the struct matches the table.
+func (d *SchemaDiff) Empty() bool {
+	return len(d.MissingColumns) == 0 && len(d.TypeMismatches) == 0 && len(d.UnmappedColumns) == 0
+}
+
+// Migration returns a draft migration applying the diff to the table, for
+// review. Columns whose type couldn't be inferred and unmapped columns are
+// left as comments.
+func (d *SchemaDiff) Migration() string {
+	var b strings.Builder
+	table := Ident(d.TableName).String()
+
+	for _, col := range d.MissingColumns {
+		if col.WantType == "" {
+			fmt.Fprintf(&b, "-- TODO: ALTER TABLE %s ADD COLUMN %s <type>; -- Go type %s\n", table, Ident(col.Column), col.GoType)
+			continue
+		}
+		fmt.Fprintf(&b, "ALTER TABLE %s ADD COLUMN %s %s;\n", table, Ident(col.Column), col.WantType)
+	}
+	for _, col := range d.TypeMismatches {
+		fmt.Fprintf(&b, "ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s; -- was %s, Go type %s\n",
+			table, Ident(col.Column), col.WantType, Ident(col.Column), col.WantType, col.DBType, col.GoType)
+	}
+	for _, col := range d.UnmappedColumns {
+		fmt.Fprintf(&b, "-- column %s is not mapped by the struct\n", Ident(col))
+	}
+	return b.String()
+}
+
+// DiffSchema compares the `db:""` tagged fields of the record struct against
+// the live schema of its table, see getTableName, and returns the
+// differences. It is meant as a development tool to draft migrations when
+// evolving record types, see SchemaDiff.Migration.
+func (q *Querier) DiffSchema(ctx context.Context, record interface{}, optTableName ...string) (*SchemaDiff, error) {
+	recordT := reflect.TypeOf(record)
+	for recordT != nil && recordT.Kind() == reflect.Ptr {
+		recordT = recordT.Elem()
+	}
+	if recordT == nil || recordT.Kind() != reflect.Struct {
+		return nil, wrapErr(ErrExpectingPointerToEitherMapOrStruct)
+	}
+
+	tableName := getTableName(record, optTableName...)
+	if tableName == "" {
+		return nil, wrapErr(fmt.Errorf("diff schema: unknown table name of %s", recordT))
+	}
+
+	schema, table := "", tableName
+	if i := strings.LastIndex(tableName, "."); i >= 0 {
+		schema, table = tableName[:i], tableName[i+1:]
+	}
+
+	var cols []struct {
+		Name string `db:"column_name"`
+		Type string `db:"udt_name"`
+	}
+	query := RawQuery(`SELECT column_name, udt_name FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?
+		ORDER BY ordinal_position`).Build(schema, table)
+	if err := q.GetAll(ctx, query, &cols); err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return nil, wrapErr(fmt.Errorf("diff schema: table %q not found", tableName))
+	}
+
+	dbTypes := make(map[string]string, len(cols))
+	for _, col := range cols {
+		dbTypes[col.Name] = col.Type
+	}
+
+	diff := &SchemaDiff{TableName: tableName}
+	mapped := map[string]bool{}
+	for _, fi := range Mapper.TypeMap(recordT).Index {
+		if !strings.Contains(string(fi.Field.Tag), dbTagPrefix) || fi.Name == "" || fi.Name == "-" {
+			continue
+		}
+		mapped[fi.Name] = true
+
+		col := ColumnDiff{Column: fi.Name, GoType: fi.Field.Type.String()}
+		want, compatible := pgTypeOf(fi.Field.Type)
+		col.WantType = want
+
+		dbType, ok := dbTypes[fi.Name]
+		if !ok {
+			diff.MissingColumns = append(diff.MissingColumns, col)
+			continue
+		}
+		col.DBType = dbType
+		if want != "" && !compatible[dbType] {
+			diff.TypeMismatches = append(diff.TypeMismatches, col)
+		}
+	}
+
+	for _, col := range cols {
+		if !mapped[col.Name] {
+			diff.UnmappedColumns = append(diff.UnmappedColumns, col.Name)
+		}
+	}
+	sort.Slice(diff.MissingColumns, func(i, j int) bool { return diff.MissingColumns[i].Column < diff.MissingColumns[j].Column })
+	sort.Slice(diff.TypeMismatches, func(i, j int) bool { return diff.TypeMismatches[i].Column < diff.TypeMismatches[j].Column })
+
+	return diff, nil
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	bigIntType     = reflect.TypeOf(big.Int{})
+	bigRatType     = reflect.TypeOf(big.Rat{})
+	dbtypePkgPath  = reflect.TypeOf(dbtype.Date{}).PkgPath()
+)
+
+func typeSet(udtNames ...string) map[string]bool {
+	set := make(map[string]bool, len(udtNames))
+	for _, name := range udtNames {
+		set[name] = true
+	}
+	return set
+}
+
+// pgTypeOf returns the Postgres type to create a column of the Go type with,
+// and the udt names of the column types compatible with it. It returns an
+// empty type for Go types it doesn't know.
+func pgTypeOf(t reflect.Type) (string, map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.PkgPath() == dbtypePkgPath {
+		switch {
+		case t.Name() == "BigInt":
+			return "numeric", typeSet("numeric")
+		case t.Name() == "BigIntArray", t.Name() == "DecimalArray":
+			return "numeric[]", typeSet("_numeric")
+		case t.Name() == "Date":
+			return "date", typeSet("date")
+		case t.Name() == "ULID":
+			return "uuid", typeSet("uuid")
+		case t.Name() == "Vector":
+			return "vector", typeSet("vector")
+		case t.Name() == "Geometry", t.Name() == "Point":
+			return "geometry", typeSet("geometry", "geography")
+		case t.Name() == "HexBytes", t.Name() == "Hash32", t.Name() == "Address20":
+			return "bytea", typeSet("bytea")
+		case strings.HasPrefix(t.Name(), "JSON["):
+			return "jsonb", typeSet("jsonb", "json")
+		}
+		return "", nil
+	}
+
+	switch t {
+	case timeType:
+		return "timestamptz", typeSet("timestamptz", "timestamp", "date")
+	case rawMessageType:
+		return "jsonb", typeSet("jsonb", "json")
+	case bigIntType, bigRatType:
+		return "numeric", typeSet("numeric")
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", typeSet("bool")
+	case reflect.Int16, reflect.Int8, reflect.Uint8:
+		return "smallint", typeSet("int2", "int4", "int8")
+	case reflect.Int32, reflect.Uint16:
+		return "integer", typeSet("int4", "int8")
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "bigint", typeSet("int8", "int4", "int2")
+	case reflect.Uint, reflect.Uint64:
+		return "numeric", typeSet("numeric", "int8")
+	case reflect.Float32:
+		return "real", typeSet("float4", "float8", "numeric")
+	case reflect.Float64:
+		return "double precision", typeSet("float8", "float4", "numeric")
+	case reflect.String:
+		return "text", typeSet("text", "varchar", "bpchar", "citext")
+	case reflect.Map:
+		return "jsonb", typeSet("jsonb", "json")
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytea", typeSet("bytea")
+		}
+		elem, compatible := pgTypeOf(t.Elem())
+		if elem == "" || strings.HasSuffix(elem, "[]") {
+			return "", nil
+		}
+		arrays := make(map[string]bool, len(compatible))
+		for name := range compatible {
+			arrays["_"+name] = true
+		}
+		return elem + "[]", arrays
+	}
+	return "", nil
+}
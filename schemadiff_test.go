@@ -0,0 +1,30 @@
+package pgkit_test
+
+import (
+	"testing"
+
+	"github.com/goware/pgkit/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaDiffMigration(t *testing.T) {
+	diff := &pgkit.SchemaDiff{
+		TableName: "accounts",
+		MissingColumns: []pgkit.ColumnDiff{
+			{Column: "email", GoType: "string", WantType: "text"},
+			{Column: "meta", GoType: "chan int"},
+		},
+		TypeMismatches: []pgkit.ColumnDiff{
+			{Column: "balance", GoType: "int64", DBType: "text", WantType: "bigint"},
+		},
+		UnmappedColumns: []string{"legacy"},
+	}
+	require.False(t, diff.Empty())
+	require.Equal(t, `ALTER TABLE "accounts" ADD COLUMN "email" text;
+-- TODO: ALTER TABLE "accounts" ADD COLUMN "meta" <type>; -- Go type chan int
+ALTER TABLE "accounts" ALTER COLUMN "balance" TYPE bigint USING "balance"::bigint; -- was text, Go type int64
+-- column "legacy" is not mapped by the struct
+`, diff.Migration())
+
+	require.True(t, (&pgkit.SchemaDiff{}).Empty())
+}
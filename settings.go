@@ -0,0 +1,164 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// settings are the session settings of a querier, see WithSettings.
+type settings struct {
+	values map[string]string
+	sql    string
+	args   []interface{}
+
+	// mu guards applied, the settings are applied once per transaction
+	mu      sync.Mutex
+	applied bool
+}
+
+// WithSettings returns a copy of the querier which runs its statements with
+// the given Postgres settings, ie. work_mem for reports, statement_timeout or
+// role, with `SET LOCAL` semantics, so they never leak onto other users of the
+// pooled connections:
+//
+//	err := db.BeginFunc(ctx, func(q *pgkit.Querier) error {
+//		q = q.WithSettings(map[string]string{"work_mem": "256MB"})
+//		return q.GetAll(ctx, reportQuery, &rows)
+//	})
+//
+// Within a transaction, the settings are set once, before its first statement
+// run by the returned querier, and last until the transaction ends. They are
+// set again after a rolled back Savepoint, which may have undone them. Outside
+// of a transaction, each statement runs with the settings in an implicit
+// transaction of its own. Batches and copies require a transaction querier.
+func (q *Querier) WithSettings(values map[string]string) *Querier {
+	qq := *q
+	qq.settings = newSettings(values)
+	return &qq
+}
+
+// newSettings returns the settings setting the values, or nil if there are
+// none.
+func newSettings(values map[string]string) *settings {
+	if len(values) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	exprs := make([]string, len(names))
+	args := make([]interface{}, 0, 2*len(names))
+	for i, name := range names {
+		exprs[i] = fmt.Sprintf("set_config($%d, $%d, true)", 2*i+1, 2*i+2)
+		args = append(args, name, values[name])
+	}
+	return &settings{values: values, sql: "SELECT " + strings.Join(exprs, ", "), args: args}
+}
+
+// applySettings sets the settings of the querier in its transaction, if not
+// already done. Queriers without transaction can only apply the settings to
+// single statements, see runWithSettings.
+func (q *Querier) applySettings(ctx context.Context) error {
+	if q.settings == nil {
+		return nil
+	}
+	if q.tx == nil {
+		return fmt.Errorf("settings of batches and copies require a transaction querier")
+	}
+
+	q.settings.mu.Lock()
+	defer q.settings.mu.Unlock()
+	if q.settings.applied {
+		return nil
+	}
+	if _, err := q.tx.Exec(ctx, q.settings.sql, q.settings.args...); err != nil {
+		return err
+	}
+	q.settings.applied = true
+	return nil
+}
+
+// reset marks the settings as not applied, so they are set again before the
+// next statement of the transaction.
+func (s *settings) reset() {
+	s.mu.Lock()
+	s.applied = false
+	s.mu.Unlock()
+}
+
+// runWithSettings runs the statement with the settings in a single batch,
+// which runs as an implicit transaction the settings are local to. done is
+// called once the statement is done.
+func (q *Querier) runWithSettings(ctx context.Context, stmt Statement, settings *settings, done func()) (Result, error) {
+	report, err := q.guard()
+	if err != nil {
+		done()
+		return Result{}, err
+	}
+
+	var res Result
+	batch := &pgx.Batch{}
+	batch.Queue(settings.sql, settings.args...)
+	batch.Queue(stmt.SQL, stmt.Args...)
+
+	results := q.pool.SendBatch(ctx, batch)
+	if _, err = results.Exec(); err == nil {
+		if !stmt.Rows {
+			res.CommandTag, err = results.Exec()
+			if closeErr := results.Close(); err == nil {
+				err = closeErr
+			}
+			report(err)
+			done()
+			if err != nil {
+				return Result{}, err
+			}
+			return res, nil
+		}
+		res.Rows, err = results.Query()
+	}
+	if err != nil {
+		results.Close()
+		report(err)
+		done()
+		return Result{}, err
+	}
+
+	res.Rows = batchRows{Rows: res.Rows, results: results, done: done}
+	if q.breaker != nil {
+		// server errors may only show up while reading the rows
+		res.Rows = breakerRows{Rows: res.Rows, report: report}
+	}
+	return res, nil
+}
+
+// batchRows closes the batch results of the rows, and calls done, once the
+// rows are closed.
+type batchRows struct {
+	pgx.Rows
+	results pgx.BatchResults
+	done    func()
+}
+
+func (r batchRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.Close()
+	return false
+}
+
+func (r batchRows) Close() {
+	r.Rows.Close()
+	r.results.Close()
+	r.done()
+}
@@ -0,0 +1,94 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sync"
+)
+
+// ShardedDB routes queries to one of N shard databases by hashing a shard
+// key, ie. an account id.
+type ShardedDB struct {
+	Shards   []*DB
+	HashFunc func(key string) uint64
+}
+
+// NewShardedDB returns a ShardedDB over the given shards. If hashFunc is nil,
+// FNV-1a is used. The order of shards must be stable across deployments, as
+// it determines which shard a key maps to.
+func NewShardedDB(shards []*DB, hashFunc func(key string) uint64) (*ShardedDB, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("pgkit: sharded db requires at least one shard")
+	}
+	if hashFunc == nil {
+		hashFunc = fnvHash
+	}
+	return &ShardedDB{Shards: shards, HashFunc: hashFunc}, nil
+}
+
+// Shard returns the shard database the key maps to.
+func (s *ShardedDB) Shard(key string) *DB {
+	return s.Shards[s.ShardIndex(key)]
+}
+
+// ShardIndex returns the index of the shard the key maps to.
+func (s *ShardedDB) ShardIndex(key string) int {
+	return int(s.HashFunc(key) % uint64(len(s.Shards)))
+}
+
+// ShardedGetAll runs the query on all shards concurrently and appends the
+// results of every shard into dest, which must be a pointer to a slice. The
+// order of rows across shards is not defined.
+func (s *ShardedDB) ShardedGetAll(ctx context.Context, query Sqlizer, dest interface{}) error {
+	destV := reflect.ValueOf(dest)
+	if destV.Kind() != reflect.Ptr || destV.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("pgkit: sharded query destination must be a pointer to a slice")
+	}
+	sliceT := destV.Elem().Type()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results  = make([]reflect.Value, len(s.Shards))
+		errOnce  sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for i, shard := range s.Shards {
+		wg.Add(1)
+		go func(i int, shard *DB) {
+			defer wg.Done()
+			result := reflect.New(sliceT)
+			if err := shard.Query.GetAll(ctx, query, result.Interface()); err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("shard %d: %w", i, err)
+					cancel()
+				})
+				return
+			}
+			results[i] = result.Elem()
+		}(i, shard)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return wrapErr(firstErr)
+	}
+
+	merged := reflect.MakeSlice(sliceT, 0, 0)
+	for _, result := range results {
+		merged = reflect.AppendSlice(merged, result)
+	}
+	destV.Elem().Set(merged)
+
+	return nil
+}
+
+func fnvHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
@@ -0,0 +1,31 @@
+package pgkit_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/goware/pgkit/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedDB(t *testing.T) {
+	shards := []*pgkit.DB{{}, {}, {}}
+
+	_, err := pgkit.NewShardedDB(nil, nil)
+	require.Error(t, err)
+
+	sharded, err := pgkit.NewShardedDB(shards, nil)
+	require.NoError(t, err)
+
+	counts := make([]int, len(shards))
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("%d", i)
+		idx := sharded.ShardIndex(key)
+		require.Same(t, shards[idx], sharded.Shard(key))
+		require.Equal(t, idx, sharded.ShardIndex(key), "shard must be stable")
+		counts[idx]++
+	}
+	for _, n := range counts {
+		require.NotZero(t, n)
+	}
+}
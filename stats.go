@@ -0,0 +1,139 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type statsCtxKey struct{}
+
+type statsStartCtxKey struct{}
+
+// QueryStats collects the queries run with a context returned by WithStats,
+// ie. during a single HTTP request. It is safe for concurrent use.
+type QueryStats struct {
+	mu sync.Mutex
+
+	queries      int
+	errors       int
+	canceled     int
+	totalTime    time.Duration
+	slowestQuery string
+	slowestTime  time.Duration
+}
+
+// QueryStatsSnapshot is a point in time copy of QueryStats.
+type QueryStatsSnapshot struct {
+	Queries int
+	// Errors is the number of failed queries, excluding the canceled ones.
+	Errors int
+	// Canceled is the number of queries aborted by the cancellation of their
+	// context, ie. by the client of a request going away.
+	Canceled     int
+	TotalTime    time.Duration
+	SlowestQuery string
+	SlowestTime  time.Duration
+}
+
+// WithStats returns a context carrying a new QueryStats collector. The
+// collector is fed by StatsTracer, which must be part of the connection's
+// tracers, see Config.Tracer.
+func WithStats(ctx context.Context) context.Context {
+	return context.WithValue(ctx, statsCtxKey{}, &QueryStats{})
+}
+
+// GetStats returns the QueryStats collector of the context, or nil if the
+// context was not created with WithStats.
+func GetStats(ctx context.Context) *QueryStats {
+	stats, _ := ctx.Value(statsCtxKey{}).(*QueryStats)
+	return stats
+}
+
+// Snapshot returns a copy of the collected stats.
+func (s *QueryStats) Snapshot() QueryStatsSnapshot {
+	if s == nil {
+		return QueryStatsSnapshot{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return QueryStatsSnapshot{
+		Queries:      s.queries,
+		Errors:       s.errors,
+		Canceled:     s.canceled,
+		TotalTime:    s.totalTime,
+		SlowestQuery: s.slowestQuery,
+		SlowestTime:  s.slowestTime,
+	}
+}
+
+func (s *QueryStats) record(query string, duration time.Duration, err error, canceled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queries++
+	switch {
+	case err == nil:
+	case canceled:
+		s.canceled++
+	default:
+		s.errors++
+	}
+	s.totalTime += duration
+	if duration > s.slowestTime {
+		s.slowestTime = duration
+		s.slowestQuery = query
+	}
+}
+
+// StatsTracer is a pgx query and batch tracer which feeds the QueryStats
+// collector of the query context, if any.
+type StatsTracer struct{}
+
+func NewStatsTracer() *StatsTracer {
+	return &StatsTracer{}
+}
+
+type statsStart struct {
+	query string
+	start time.Time
+}
+
+func (t *StatsTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if GetStats(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, statsStartCtxKey{}, statsStart{query: data.SQL, start: time.Now()})
+}
+
+func (t *StatsTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	t.end(ctx, data.Err)
+}
+
+func (t *StatsTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if GetStats(ctx) == nil || data.Batch == nil || data.Batch.Len() == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, statsStartCtxKey{}, statsStart{query: data.Batch.QueuedQueries[0].SQL, start: time.Now()})
+}
+
+func (t *StatsTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	// do nothing
+}
+
+func (t *StatsTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	t.end(ctx, data.Err)
+}
+
+func (t *StatsTracer) end(ctx context.Context, err error) {
+	stats := GetStats(ctx)
+	start, ok := ctx.Value(statsStartCtxKey{}).(statsStart)
+	if stats == nil || !ok {
+		return
+	}
+	canceled := errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled)
+	stats.record(start.query, time.Since(start.start), err, canceled)
+}
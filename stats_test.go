@@ -0,0 +1,40 @@
+package pgkit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/goware/pgkit/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsTracer(t *testing.T) {
+	st := pgkit.NewStatsTracer()
+
+	// no collector in context
+	ctx := st.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	st.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	require.Nil(t, pgkit.GetStats(ctx))
+
+	ctx = pgkit.WithStats(context.Background())
+
+	qctx := st.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	st.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{})
+
+	qctx = st.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 2"})
+	st.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: errors.New("failed")})
+
+	cctx, cancel := context.WithCancel(ctx)
+	qctx = st.TraceQueryStart(cctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 3"})
+	cancel()
+	st.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: errors.New("query_canceled")})
+
+	stats := pgkit.GetStats(ctx).Snapshot()
+	require.Equal(t, 3, stats.Queries)
+	require.Equal(t, 1, stats.Errors)
+	require.Equal(t, 1, stats.Canceled)
+	require.NotEmpty(t, stats.SlowestQuery)
+	require.GreaterOrEqual(t, stats.TotalTime, stats.SlowestTime)
+}
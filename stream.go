@@ -0,0 +1,185 @@
+package pgkit
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// StreamJSON encodes the rows of the query as a JSON array of objects to w,
+// one row at a time, without buffering the whole result in memory. The keys
+// of the objects are the columns, in the order of the query. Duplicate column
+// names, ie. of "a.id, b.id" joins, are kept as duplicate keys, alias them to
+// keep them apart.
+func (q *Querier) StreamJSON(ctx context.Context, w io.Writer, query Sqlizer) error {
+	rows, err := q.QueryRows(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	bw := bufio.NewWriter(w)
+	keys, err := jsonKeys(columnNames(rows))
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if _, err := bw.WriteString("["); err != nil {
+		return wrapErr(err)
+	}
+	for n := 0; rows.Next(); n++ {
+		values, err := streamValues(rows)
+		if err != nil {
+			return wrapErr(err)
+		}
+
+		if n > 0 {
+			if _, err := bw.WriteString(","); err != nil {
+				return wrapErr(err)
+			}
+		}
+		if err := writeJSONObject(bw, keys, values); err != nil {
+			return wrapErr(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return wrapErr(err)
+	}
+	if _, err := bw.WriteString("]\n"); err != nil {
+		return wrapErr(err)
+	}
+
+	return wrapErr(bw.Flush())
+}
+
+// jsonKeys returns the JSON encoded object keys of the columns, along with
+// their colon.
+func jsonKeys(cols []string) ([][]byte, error) {
+	keys := make([][]byte, len(cols))
+	for i, col := range cols {
+		data, err := json.Marshal(col)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = append(data, ':')
+	}
+	return keys, nil
+}
+
+// writeJSONObject writes the values as a JSON object of the keys, in order.
+func writeJSONObject(w *bufio.Writer, keys [][]byte, values []interface{}) error {
+	if err := w.WriteByte('{'); err != nil {
+		return err
+	}
+	for i, v := range values {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if err := w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(keys[i]); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return w.WriteByte('}')
+}
+
+// StreamCSV encodes the rows of the query as CSV to w, with a header row of
+// column names, one row at a time, without buffering the whole result in
+// memory. NULL values are written as empty fields.
+func (q *Querier) StreamCSV(ctx context.Context, w io.Writer, query Sqlizer) error {
+	rows, err := q.QueryRows(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	cols := columnNames(rows)
+	if err := cw.Write(cols); err != nil {
+		return wrapErr(err)
+	}
+
+	record := make([]string, len(cols))
+	for rows.Next() {
+		values, err := streamValues(rows)
+		if err != nil {
+			return wrapErr(err)
+		}
+		for i, v := range values {
+			record[i] = csvValue(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return wrapErr(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return wrapErr(err)
+	}
+
+	cw.Flush()
+	return wrapErr(cw.Error())
+}
+
+// streamValues returns the values of the current row, with the uuid values,
+// decoded as [16]byte, formatted as their canonical string.
+func streamValues(rows pgx.Rows) ([]interface{}, error) {
+	values, err := rows.Values()
+	if err != nil {
+		return nil, err
+	}
+	for i, fd := range rows.FieldDescriptions() {
+		if u, ok := values[i].([16]byte); ok && fd.DataTypeOID == pgtype.UUIDOID {
+			values[i] = formatUUID(u)
+		}
+	}
+	return values, nil
+}
+
+func formatUUID(u [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+func columnNames(rows pgx.Rows) []string {
+	fields := rows.FieldDescriptions()
+	cols := make([]string, len(fields))
+	for i, fd := range fields {
+		cols[i] = fd.Name
+	}
+	return cols
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return fmt.Sprintf("\\x%x", v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
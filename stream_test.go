@@ -0,0 +1,28 @@
+package pgkit
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJSONObject(t *testing.T) {
+	keys, err := jsonKeys([]string{"name", "id", "id", `a"b`})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	require.NoError(t, writeJSONObject(w, keys, []interface{}{"a", 1, 2, nil}))
+	require.NoError(t, w.Flush())
+
+	// column order and duplicate columns are kept
+	assert.Equal(t, `{"name":"a","id":1,"id":2,"a\"b":null}`, buf.String())
+}
+
+func TestFormatUUID(t *testing.T) {
+	u := [16]byte{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}
+	assert.Equal(t, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", formatUUID(u))
+}
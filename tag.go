@@ -0,0 +1,60 @@
+package pgkit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/goware/pgkit/v2/tracer"
+)
+
+// WithQueryTag returns a context tagging the queries run with it with a
+// logical operation name, ie. "sync-orders", to attribute database load to
+// operations rather than raw SQL text. The tracer includes the tag in its
+// logs, and queriers with tag comments enabled embed it in the statements,
+// see Querier.WithTagComments.
+func WithQueryTag(ctx context.Context, tag string) context.Context {
+	return tracer.WithQueryTag(ctx, tag)
+}
+
+// QueryTag returns the query tag of the context, or "" if it has none.
+func QueryTag(ctx context.Context) string {
+	return tracer.QueryTag(ctx)
+}
+
+// WithTagComments returns a copy of the querier which prefixes the statements
+// it runs with the query tag of the context as a SQL comment, ie.
+// "/* sync-orders */ SELECT ...", so the tag shows up in pg_stat_activity,
+// pg_stat_statements and the server logs. The characters of the tag outside
+// of [A-Za-z0-9_.:/ -] are replaced with "_" in the comments.
+func (q *Querier) WithTagComments() *Querier {
+	qq := *q
+	qq.tagComments = true
+	return &qq
+}
+
+// tagged prefixes the query with the query tag comment, if enabled.
+func (q *Querier) tagged(ctx context.Context, sql string) string {
+	if !q.tagComments {
+		return sql
+	}
+	tag := QueryTag(ctx)
+	if tag == "" {
+		return sql
+	}
+	return "/* " + commentTag(tag) + " */ " + sql
+}
+
+// commentTag replaces the characters of the tag outside of [A-Za-z0-9_.:/ -]
+// with "_", so the tag can neither close the comment early with "*/" nor
+// open a nested one with "/*", as Postgres block comments nest.
+func commentTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("_.:/ -", r):
+			return r
+		}
+		return '_'
+	}, tag)
+}
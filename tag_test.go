@@ -0,0 +1,30 @@
+package pgkit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaggedComment(t *testing.T) {
+	q := (&Querier{}).WithTagComments()
+
+	tests := []struct {
+		tag string
+		sql string
+	}{
+		{"sync-orders", "/* sync-orders */ SELECT 1"},
+		{"jobs/billing: run 2", "/* jobs/billing: run 2 */ SELECT 1"},
+		{"a*/b", "/* a_/b */ SELECT 1"},
+		{"a/*b", "/* a/_b */ SELECT 1"},
+		{"a'; DROP TABLE x;\n", "/* a__ DROP TABLE x__ */ SELECT 1"},
+	}
+	for _, tt := range tests {
+		ctx := WithQueryTag(context.Background(), tt.tag)
+		assert.Equal(t, tt.sql, q.tagged(ctx, "SELECT 1"), tt.tag)
+	}
+
+	assert.Equal(t, "SELECT 1", q.tagged(context.Background(), "SELECT 1"))
+	assert.Equal(t, "SELECT 1", (&Querier{}).tagged(WithQueryTag(context.Background(), "a"), "SELECT 1"))
+}
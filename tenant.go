@@ -0,0 +1,23 @@
+package pgkit
+
+import "context"
+
+// TenantSetting is the name of the Postgres setting WithTenant sets to the
+// tenant id, for use in row-level security policies, ie.
+//
+//	CREATE POLICY tenant_isolation ON accounts
+//	  USING (tenant_id = current_setting('app.tenant_id')::bigint);
+var TenantSetting = "app.tenant_id"
+
+// WithTenant runs fn within a transaction where TenantSetting is set to the
+// tenant id with `SET LOCAL` semantics, so Postgres RLS policies apply to all
+// queries done with the given querier. The setting is discarded when the
+// transaction ends, so it never leaks to other users of the pooled connection.
+func (d *DB) WithTenant(ctx context.Context, tenantID string, fn func(q *Querier) error) error {
+	return d.beginFunc(ctx, 2, func(q *Querier) error {
+		if _, err := q.Exec(ctx, RawQuery(`SELECT set_config(?, ?, true)`).Build(TenantSetting, tenantID)); err != nil {
+			return err
+		}
+		return fn(q)
+	})
+}
@@ -11,16 +11,24 @@ import (
 	"io"
 	"log"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/goware/pgkit/v2"
+	"github.com/goware/pgkit/v2/cdc"
 	"github.com/goware/pgkit/v2/db"
 	"github.com/goware/pgkit/v2/dbtype"
+	"github.com/goware/pgkit/v2/featureflag"
+	"github.com/goware/pgkit/v2/kvstore"
+	"github.com/goware/pgkit/v2/pgkittest"
 	"github.com/goware/pgkit/v2/tracer"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -513,6 +521,1207 @@ func TestSugarUpdateRecordColumns(t *testing.T) {
 	assert.True(t, accountResp2.ID == accountResp.ID)
 }
 
+func TestExecExpecting(t *testing.T) {
+	truncateTable(t, "accounts")
+
+	ctx := context.Background()
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b"}}))
+	require.NoError(t, err)
+
+	n, err := DB.Query.ExecAffected(ctx, DB.SQL.Update("accounts").Set("disabled", true))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	err = DB.Query.ExecExpecting(ctx, DB.SQL.Update("accounts").Set("disabled", false).Where(sq.Eq{"name": "a"}), 1)
+	require.NoError(t, err)
+
+	err = DB.Query.ExecExpecting(ctx, DB.SQL.Update("accounts").Set("disabled", false).Where(sq.Eq{"name": "nobody"}), 1)
+	require.ErrorIs(t, err, pgkit.ErrUnexpectedRowCount)
+
+	var rowCountErr *pgkit.RowCountError
+	require.ErrorAs(t, err, &rowCountErr)
+	assert.Equal(t, int64(1), rowCountErr.Expected)
+	assert.Equal(t, int64(0), rowCountErr.Actual)
+}
+
+func TestGetAllMaps(t *testing.T) {
+	truncateTable(t, "accounts")
+
+	ctx := context.Background()
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b", Disabled: true}}))
+	require.NoError(t, err)
+
+	rows, err := DB.Query.GetAllMaps(ctx, DB.SQL.Select("name", "disabled").From("accounts").OrderBy("name"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{
+		{"name": "a", "disabled": false},
+		{"name": "b", "disabled": true},
+	}, rows)
+}
+
+func TestStreamJSONAndCSV(t *testing.T) {
+	truncateTable(t, "accounts")
+
+	ctx := context.Background()
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b", Disabled: true}}))
+	require.NoError(t, err)
+
+	q := DB.SQL.Select("name", "disabled", "'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'::uuid AS uid").From("accounts").OrderBy("name")
+
+	var buf bytes.Buffer
+	require.NoError(t, DB.Query.StreamJSON(ctx, &buf, q))
+	// the keys are in the order of the query
+	assert.Equal(t, `[{"name":"a","disabled":false,"uid":"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},{"name":"b","disabled":true,"uid":"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"}]`+"\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, DB.Query.StreamCSV(ctx, &buf, q))
+	assert.Equal(t, "name,disabled,uid\na,false,a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11\nb,true,a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11\n", buf.String())
+}
+
+func TestAggregates(t *testing.T) {
+	truncateTable(t, "articles")
+
+	ctx := context.Background()
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Article{
+		{Author: "a", Content: Content{Views: 10}},
+		{Author: "a", Content: Content{Views: 20}},
+		{Author: "b", Content: Content{Views: 30}},
+	}, "articles"))
+	require.NoError(t, err)
+
+	sum, err := pgkit.Sum[int64](ctx, DB.Query, "articles", "(content->>'views')::int", sq.Eq{"author": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), sum)
+
+	sum, err = pgkit.Sum[int64](ctx, DB.Query, "articles", "(content->>'views')::int", sq.Eq{"author": "nobody"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), sum)
+
+	max, err := pgkit.Max[int64](ctx, DB.Query, "articles", "(content->>'views')::int", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), max)
+
+	_, err = pgkit.Min[int64](ctx, DB.Query, "articles", "(content->>'views')::int", sq.Eq{"author": "nobody"})
+	require.ErrorIs(t, err, pgkit.ErrNoRows)
+
+	authors, err := pgkit.Aggregate[int64](ctx, DB.Query, "COUNT(DISTINCT author)", "articles", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), authors)
+}
+
+func TestGroupCount(t *testing.T) {
+	truncateTable(t, "accounts")
+
+	ctx := context.Background()
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{
+		{Name: "a"}, {Name: "a", Disabled: true}, {Name: "b", Disabled: true},
+	}))
+	require.NoError(t, err)
+
+	counts, err := pgkit.GroupCount(ctx, DB.Query, "accounts", "disabled", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"false": 1, "true": 2}, counts)
+
+	groups, err := pgkit.GroupCountBy(ctx, DB.Query, "accounts", []string{"name", "disabled"}, sq.Eq{"name": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []pgkit.GroupCountRow{
+		{Values: []string{"a", "false"}, Count: 1},
+		{Values: []string{"a", "true"}, Count: 1},
+	}, groups)
+}
+
+func TestKVStore(t *testing.T) {
+	ctx := context.Background()
+	store := kvstore.New(DB, "kv")
+	require.NoError(t, store.CreateTable(ctx))
+	truncateTable(t, "kv")
+
+	type flags struct {
+		Beta bool `json:"beta"`
+	}
+
+	require.NoError(t, store.Set(ctx, "flags.web", flags{Beta: true}, 0))
+	require.NoError(t, store.Set(ctx, "flags.api", flags{}, 0))
+	require.NoError(t, store.Set(ctx, "session", "abc", time.Millisecond))
+
+	v, err := kvstore.Get[flags](ctx, store, "flags.web")
+	require.NoError(t, err)
+	assert.True(t, v.Beta)
+
+	time.Sleep(10 * time.Millisecond)
+	_, err = kvstore.Get[string](ctx, store, "session")
+	assert.ErrorIs(t, err, kvstore.ErrNotFound)
+
+	entries, err := store.List(ctx, "flags.")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "flags.api", entries[0].Key)
+
+	n, err := store.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	require.NoError(t, store.Delete(ctx, "flags.web"))
+	_, err = kvstore.Get[flags](ctx, store, "flags.web")
+	assert.ErrorIs(t, err, kvstore.ErrNotFound)
+}
+
+func TestHealthReport(t *testing.T) {
+	ctx := context.Background()
+	_, err := DB.Conn.Exec(ctx, `ANALYZE accounts`)
+	require.NoError(t, err)
+
+	report, err := DB.Query.HealthReport(ctx, &pgkit.HealthReportOptions{MinSeqScans: 1, MinRows: 1, MinBloatBytes: 1})
+	require.NoError(t, err)
+	assert.NotNil(t, report)
+}
+
+func TestMaintenance(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, DB.Analyze(ctx, "accounts"))
+	require.NoError(t, DB.Vacuum(ctx, "accounts", pgkit.VacuumOptions{Analyze: true}))
+	require.NoError(t, DB.Vacuum(ctx, "public.accounts", pgkit.VacuumOptions{Full: true, Freeze: true}))
+	require.NoError(t, DB.ReindexTable(ctx, "accounts"))
+}
+
+func TestLargeObjects(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := DB.Query.LargeObjects()
+	assert.Error(t, err)
+
+	data := bytes.Repeat([]byte("pgkit"), 500000)
+	oid, err := DB.WriteLargeObject(ctx, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	n, err := DB.ReadLargeObject(ctx, oid, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	assert.Equal(t, data, buf.Bytes())
+
+	require.NoError(t, DB.DeleteLargeObject(ctx, oid))
+	_, err = DB.ReadLargeObject(ctx, oid, io.Discard)
+	assert.Error(t, err)
+}
+
+func TestValidateStatements(t *testing.T) {
+	ctx := context.Background()
+
+	err := DB.ValidateStatements(ctx,
+		pgkit.RawQuery("SELECT * FROM accounts WHERE id = ?"),
+	)
+	require.NoError(t, err)
+
+	err = DB.ValidateStatements(ctx,
+		pgkit.RawQuery("SELECT * FROM accounts WHERE name = '?'"),
+		pgkit.RawQuery("SELEC * FROM accounts"),
+		pgkit.RawQuery("SELECT * FROM accounts WHERE id = ?"),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expecting 1 args but the query has 0 parameters")
+	assert.Contains(t, err.Error(), "SELEC * FROM accounts")
+}
+
+func TestQueryTagComments(t *testing.T) {
+	ctx := pgkit.WithQueryTag(context.Background(), "sync-orders */ x")
+	require.Equal(t, "sync-orders */ x", pgkit.QueryTag(ctx))
+
+	var query string
+	err := DB.Query.WithTagComments().QueryRow(ctx, pgkit.RawQuery("SELECT query FROM pg_stat_activity WHERE pid = pg_backend_pid()").Build()).Scan(&query)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(query, "/* sync-orders * / x */ SELECT"), query)
+
+	// untagged queries are unchanged
+	err = DB.Query.WithTagComments().QueryRow(context.Background(), pgkit.RawQuery("SELECT query FROM pg_stat_activity WHERE pid = pg_backend_pid()").Build()).Scan(&query)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(query, "SELECT"), query)
+}
+
+func TestQuerierLimiter(t *testing.T) {
+	limiter := pgkit.NewLimiter(pgkit.LimiterConfig{Limits: map[string]int{"reports": 1}, Reject: true})
+	q := DB.Query.WithLimiter(limiter)
+	ctx := pgkit.WithQueryTag(context.Background(), "reports")
+
+	rows, err := q.QueryRows(ctx, pgkit.RawQuery("SELECT 1").Build())
+	require.NoError(t, err)
+	assert.Equal(t, 1, limiter.InFlight("reports"))
+
+	_, err = q.Exec(ctx, pgkit.RawQuery("SELECT 1").Build())
+	require.ErrorIs(t, err, pgkit.ErrLimitExceeded)
+
+	// other labels aren't limited
+	_, err = q.Exec(context.Background(), pgkit.RawQuery("SELECT 1").Build())
+	require.NoError(t, err)
+
+	rows.Close()
+	assert.Equal(t, 0, limiter.InFlight("reports"))
+
+	var n int
+	require.NoError(t, q.QueryRow(ctx, pgkit.RawQuery("SELECT 1").Build()).Scan(&n))
+	assert.Equal(t, 0, limiter.InFlight("reports"))
+
+	// queued queries wait for a slot
+	limiter = pgkit.NewLimiter(pgkit.LimiterConfig{Limits: map[string]int{"reports": 1}, MaxWait: 50 * time.Millisecond})
+	q = DB.Query.WithLimiter(limiter)
+	rows, err = q.QueryRows(ctx, pgkit.RawQuery("SELECT 1").Build())
+	require.NoError(t, err)
+	_, err = q.Exec(ctx, pgkit.RawQuery("SELECT 1").Build())
+	require.ErrorIs(t, err, pgkit.ErrLimitExceeded)
+
+	time.AfterFunc(10*time.Millisecond, rows.Close)
+	_, err = q.Exec(ctx, pgkit.RawQuery("SELECT 1").Build())
+	require.NoError(t, err)
+}
+
+func TestTxMiddleware(t *testing.T) {
+	truncateTable(t, "accounts")
+
+	handler := DB.TxMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := DB.QueryContext(r.Context())
+		_, err := q.Exec(r.Context(), pgkit.RawQuery("INSERT INTO accounts (name) VALUES (?)").Build(r.URL.Query().Get("name")))
+		require.NoError(t, err)
+
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/?name=committed", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/?name=rolledback&fail=1", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var names []string
+	err := DB.Query.GetAll(context.Background(), pgkit.RawQuery("SELECT name FROM accounts").Build(), &names)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"committed"}, names)
+
+	// the plain func variant
+	ctx, commit, rollback, err := DB.BeginRequest(context.Background())
+	require.NoError(t, err)
+	defer rollback()
+	_, ok := pgkit.TxFromContext(ctx)
+	require.True(t, ok)
+	_, err = DB.QueryContext(ctx).Exec(ctx, pgkit.RawQuery("INSERT INTO accounts (name) VALUES (?)").Build("plain"))
+	require.NoError(t, err)
+	require.NoError(t, commit())
+	require.NoError(t, rollback())
+}
+
+func TestSavepoint(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	err := DB.Query.Savepoint(ctx, func(q *pgkit.Querier) error { return nil })
+	require.Error(t, err)
+
+	err = DB.BeginFunc(ctx, func(q *pgkit.Querier) error {
+		for _, name := range []string{"a", "fail", "b"} {
+			err := q.Savepoint(ctx, func(q *pgkit.Querier) error {
+				_, err := q.Exec(ctx, pgkit.RawQuery("INSERT INTO accounts (name) VALUES (?)").Build(name))
+				if err != nil {
+					return err
+				}
+				if name == "fail" {
+					return fmt.Errorf("failed record")
+				}
+				return nil
+			})
+			if name == "fail" {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	var names []string
+	err = DB.Query.GetAll(ctx, pgkit.RawQuery("SELECT name FROM accounts ORDER BY name").Build(), &names)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestBatchChunking(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	defer func(n int) { pgkit.BatchMaxStatements = n }(pgkit.BatchMaxStatements)
+	pgkit.BatchMaxStatements = 2
+
+	var queries pgkit.Queries
+	for i := 0; i < 5; i++ {
+		queries.Add(DB.SQL.InsertRecord(&Account{Name: fmt.Sprintf("user-%d", i)}))
+	}
+	tags, err := DB.Query.BatchExec(ctx, queries)
+	require.NoError(t, err)
+	require.Len(t, tags, 5)
+
+	queries = pgkit.Queries{}
+	for i := 0; i < 5; i++ {
+		queries.Add(DB.SQL.Select("name").From("accounts").Where(sq.Eq{"name": fmt.Sprintf("user-%d", i)}))
+	}
+	results, n, err := DB.Query.BatchQuery(ctx, queries)
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	for i := 0; i < n; i++ {
+		var name string
+		require.NoError(t, results.QueryRow().Scan(&name))
+		assert.Equal(t, fmt.Sprintf("user-%d", i), name)
+	}
+	require.NoError(t, results.Close())
+}
+
+func TestQuerierDefaultTimeout(t *testing.T) {
+	q := DB.Query.WithTimeout(50 * time.Millisecond)
+
+	_, err := q.Exec(context.Background(), pgkit.RawQuery("SELECT pg_sleep(0.2)").Build())
+	require.Error(t, err)
+
+	// the caller's deadline takes precedence
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = q.Exec(ctx, pgkit.RawQuery("SELECT pg_sleep(0.2)").Build())
+	require.NoError(t, err)
+
+	var n []int
+	require.NoError(t, q.GetAll(context.Background(), pgkit.RawQuery("SELECT 1").Build(), &n))
+	assert.Equal(t, []int{1}, n)
+}
+
+func TestExplainAssertions(t *testing.T) {
+	pgkittest.AssertIndexScan(t, DB.Conn, DB.SQL.Select("*").From("accounts").Where(sq.Eq{"id": 1}))
+	pgkittest.AssertNoSortSpill(t, DB.Conn, DB.SQL.Select("*").From("accounts").OrderBy("name"))
+
+	plan := pgkittest.Explain(t, DB.Conn, DB.SQL.Select("*").From("accounts"), false)
+	assert.Equal(t, "Seq Scan", plan.NodeType)
+}
+
+func TestDiffSchema(t *testing.T) {
+	diff, err := DB.Query.DiffSchema(context.Background(), &Account{})
+	require.NoError(t, err)
+	assert.Empty(t, diff.MissingColumns)
+	assert.Empty(t, diff.TypeMismatches)
+	assert.Equal(t, []string{"new_column_not_in_code"}, diff.UnmappedColumns)
+
+	type accountV2 struct {
+		ID       int64  `db:"id,omitempty"`
+		Name     string `db:"name"`
+		Email    string `db:"email"`
+		Disabled string `db:"disabled"`
+	}
+	diff, err = DB.Query.DiffSchema(context.Background(), &accountV2{}, "accounts")
+	require.NoError(t, err)
+	require.Len(t, diff.MissingColumns, 1)
+	assert.Equal(t, "text", diff.MissingColumns[0].WantType)
+	require.Len(t, diff.TypeMismatches, 1)
+	assert.Equal(t, "bool", diff.TypeMismatches[0].DBType)
+	assert.Contains(t, diff.Migration(), `ALTER TABLE "accounts" ADD COLUMN "email" text;`)
+}
+
+func TestCloneDatabase(t *testing.T) {
+	ctx := context.Background()
+	admin, err := connectToDb(pgkit.Config{
+		Database:        "postgres",
+		Host:            "localhost",
+		Username:        "postgres",
+		Password:        "postgres",
+		ConnMaxLifetime: "1h",
+	})
+	require.NoError(t, err)
+	defer admin.Close()
+
+	require.NoError(t, pgkit.DropDatabase(ctx, admin, "pgkit_test_clone"))
+	// cloning pgkit_test would terminate the connections of the other tests
+	require.NoError(t, pgkit.CloneDatabase(ctx, admin, "template1", "pgkit_test_clone"))
+
+	clone, err := connectToDb(pgkit.Config{
+		Database:        "pgkit_test_clone",
+		Host:            "localhost",
+		Username:        "postgres",
+		Password:        "postgres",
+		ConnMaxLifetime: "1h",
+	})
+	require.NoError(t, err)
+
+	var n int
+	require.NoError(t, clone.Query.QueryRow(ctx, pgkit.RawQuery("SELECT 1").Build()).Scan(&n))
+
+	// open connections are terminated
+	require.NoError(t, pgkit.DropDatabase(ctx, admin, "pgkit_test_clone"))
+	clone.Close()
+}
+
+func TestReadModel(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecord(&Account{Name: name, Disabled: name == "c"}))
+		require.NoError(t, err)
+	}
+
+	type accountSummary struct {
+		Name  string `db:"name"`
+		Label string `db:"label"`
+	}
+	model := pgkit.NewReadModel[accountSummary](DB.Query,
+		DB.SQL.Select("name", "name || '!' AS label").From("accounts").Where(sq.Eq{"disabled": false}),
+		pgkit.WithSort[accountSummary]("name"))
+
+	record, err := model.Get(ctx, sq.Eq{"label": "b!"})
+	require.NoError(t, err)
+	assert.Equal(t, "b", record.Name)
+
+	_, err = model.Get(ctx, sq.Eq{"label": "c!"})
+	require.ErrorIs(t, err, pgkit.ErrNoRows)
+
+	count, err := model.Count(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	page := pgkit.NewPage(1, 1)
+	list, err := model.List(ctx, nil, page)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "a", list[0].Name)
+	assert.True(t, page.More)
+}
+
+func TestJoinNestedColumns(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecord(&Account{Name: "peter"}))
+	require.NoError(t, err)
+
+	type accountWithParent struct {
+		Account
+		Parent Account `db:"parent"`
+	}
+	q := DB.SQL.Select(pgkit.Columns("a", &Account{})...).
+		Columns(pgkit.NestedColumns("p", "parent", &Account{})...).
+		From("accounts a").
+		Join("accounts p ON p.id = a.id")
+
+	var record accountWithParent
+	require.NoError(t, DB.Query.GetOne(ctx, q, &record))
+	assert.Equal(t, "peter", record.Name)
+	assert.Equal(t, "peter", record.Parent.Name)
+	assert.Equal(t, record.ID, record.Parent.ID)
+}
+
+func TestGetAllScanOptions(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "a"}, {Name: "b"}}))
+	require.NoError(t, err)
+
+	type nameCount struct {
+		Name  string `db:"name"`
+		Count int64  `db:"count"`
+		ID    int64  `db:"id"`
+	}
+	var counts []nameCount
+	err = DB.Query.GetAll(ctx,
+		DB.SQL.Select("name", "COUNT(*) AS total", "MAX(id) AS id").From("accounts").GroupBy("name").OrderBy("name"),
+		&counts, pgkit.ScanRename("total", "count"), pgkit.ScanIgnore("id"))
+	require.NoError(t, err)
+	require.Len(t, counts, 2)
+	assert.Equal(t, nameCount{Name: "a", Count: 2}, counts[0])
+
+	var count nameCount
+	err = DB.Query.GetOne(ctx, DB.SQL.Select("name", "COUNT(*) AS total").From("accounts").Where(sq.Eq{"name": "b"}).GroupBy("name"),
+		&count, pgkit.ScanRename("total", "count"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count.Count)
+}
+
+func TestGetExactlyOne(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "a"}, {Name: "b"}}))
+	require.NoError(t, err)
+
+	var account Account
+	err = DB.Query.GetExactlyOne(ctx, DB.SQL.Select("*").From("accounts").Where(sq.Eq{"name": "b"}), &account)
+	require.NoError(t, err)
+	assert.Equal(t, "b", account.Name)
+
+	err = DB.Query.GetExactlyOne(ctx, DB.SQL.Select("*").From("accounts").Where(sq.Eq{"name": "a"}), &account)
+	require.ErrorIs(t, err, pgkit.ErrTooManyRows)
+
+	err = DB.Query.GetExactlyOne(ctx, DB.SQL.Select("*").From("accounts").Where(sq.Eq{"name": "c"}), &account)
+	require.ErrorIs(t, err, pgkit.ErrNoRows)
+}
+
+func TestDeleteReturning(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b", Disabled: true}, {Name: "c", Disabled: true}}))
+	require.NoError(t, err)
+
+	var deleted []*Account
+	err = DB.Query.DeleteReturning(ctx, DB.SQL.Delete("accounts").Where(sq.Eq{"disabled": true}), &deleted)
+	require.NoError(t, err)
+	require.Len(t, deleted, 2)
+
+	names := []string{deleted[0].Name, deleted[1].Name}
+	sort.Strings(names)
+	assert.Equal(t, []string{"b", "c"}, names)
+
+	var count int
+	require.NoError(t, DB.Query.QueryRow(ctx, DB.SQL.Select("COUNT(*)").From("accounts")).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestUpsertCreated(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecord(&Account{ID: 1, Name: "a"}))
+	require.NoError(t, err)
+
+	records := []*Account{{ID: 1, Name: "a2"}, {ID: 1000, Name: "b"}}
+	created, updated, err := DB.Query.UpsertCounts(ctx, DB.SQL.UpsertRecords(records, []string{"id"}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), created)
+	assert.Equal(t, int64(1), updated)
+
+	type result struct {
+		ID      int64 `db:"id"`
+		Created bool  `db:"created"`
+	}
+	var results []result
+	err = DB.Query.GetAll(ctx, DB.SQL.UpsertRecords(&Account{ID: 2000, Name: "c"}, []string{"id"}).ReturningCreated("id"), &results)
+	require.NoError(t, err)
+	assert.Equal(t, []result{{ID: 2000, Created: true}}, results)
+}
+
+func TestBulkInsertPartialSuccess(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecord(&Account{ID: 1, Name: "a"}))
+	require.NoError(t, err)
+
+	skipped, err := DB.Query.InsertRecordsSkipping(ctx, []*Account{{ID: 1, Name: "a2"}, {ID: 2, Name: "b"}}, "id")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, skipped)
+
+	// non-text keys are matched on their value
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.FixedZone("CET", 3600))
+	skipped, err = DB.Query.InsertRecordsSkipping(ctx, []*Account{{ID: 10, Name: "t", CreatedAt: createdAt}}, "created_at")
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+
+	// nil keys
+	type accountName struct {
+		Name *string `db:"name"`
+	}
+	_, err = DB.Query.InsertRecordsSkipping(ctx, []accountName{{}}, "name", "accounts")
+	require.Error(t, err)
+
+	err = DB.Query.InsertRecordsEach(ctx, []*Account{{ID: 3, Name: "c"}, {ID: 1, Name: "dup"}, {ID: 4, Name: "d"}})
+	var bulkErr *pgkit.BulkInsertError
+	require.ErrorAs(t, err, &bulkErr)
+	require.Len(t, bulkErr.Rows, 1)
+	assert.Equal(t, 1, bulkErr.Rows[0].Index)
+
+	var count int
+	require.NoError(t, DB.Query.QueryRow(ctx, DB.SQL.Select("COUNT(*)").From("accounts")).Scan(&count))
+	assert.Equal(t, 5, count)
+}
+
+func TestParallel(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b", Disabled: true}}))
+	require.NoError(t, err)
+
+	var (
+		accounts []*Account
+		disabled Account
+		count    int
+	)
+	err = pgkit.Parallel(ctx, DB.Query,
+		pgkit.Into(DB.SQL.Select("*").From("accounts").OrderBy("name"), &accounts),
+		pgkit.Into(DB.SQL.Select("*").From("accounts").Where(sq.Eq{"disabled": true}), &disabled),
+		pgkit.Into(DB.SQL.Select("COUNT(*)").From("accounts"), &count),
+	)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, "a", accounts[0].Name)
+	assert.Equal(t, "b", disabled.Name)
+	assert.Equal(t, 2, count)
+
+	err = pgkit.Parallel(ctx, DB.Query,
+		pgkit.Into(DB.SQL.Select("*").From("accounts"), &accounts),
+		pgkit.Into(DB.SQL.Select("*").From("accounts").Where(sq.Eq{"name": "c"}), &disabled),
+	)
+	require.ErrorIs(t, err, pgkit.ErrNoRows)
+}
+
+func TestCopyFrom(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	defer func(interval int64) { pgkit.CopyProgressInterval = interval }(pgkit.CopyProgressInterval)
+	pgkit.CopyProgressInterval = 2
+
+	rows := [][]interface{}{{"a", false}, {"b", true}, {"c", false}}
+
+	var reports []pgkit.CopyProgress
+	n, err := DB.Query.CopyFrom(ctx, "accounts", []string{"name", "disabled"}, pgx.CopyFromRows(rows), func(p pgkit.CopyProgress) {
+		reports = append(reports, p)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	require.Len(t, reports, 2)
+	assert.Equal(t, int64(2), reports[0].Rows)
+	assert.False(t, reports[0].Done)
+	assert.Equal(t, int64(3), reports[1].Rows)
+	assert.True(t, reports[1].Done)
+
+	// canceled after the first report, no rows are copied
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	_, err = DB.Query.CopyFrom(cctx, "accounts", []string{"name", "disabled"}, pgx.CopyFromRows(rows), func(p pgkit.CopyProgress) {
+		cancel()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	var count int
+	err = DB.Query.GetOne(ctx, DB.SQL.Select("COUNT(*)").From("accounts"), &count)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// schema-qualified table
+	n, err = DB.Query.CopyFrom(ctx, "public.accounts", []string{"name"}, pgx.CopyFromRows([][]interface{}{{"d"}}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestQuerierMiddleware(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b", Disabled: true}}))
+	require.NoError(t, err)
+
+	// hide disabled accounts
+	q := DB.Query.Use(func(next pgkit.QueryFunc) pgkit.QueryFunc {
+		return func(ctx context.Context, stmt pgkit.Statement) (pgkit.Result, error) {
+			if stmt.Rows {
+				stmt.SQL = "SELECT * FROM (" + stmt.SQL + ") t WHERE NOT t.disabled"
+			}
+			return next(ctx, stmt)
+		}
+	})
+
+	var accounts []*Account
+	err = q.GetAll(ctx, DB.SQL.Select("*").From("accounts"), &accounts)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "a", accounts[0].Name)
+
+	var name string
+	err = q.QueryRow(ctx, DB.SQL.Select("name", "disabled").From("accounts").Where(sq.Eq{"name": "b"})).Scan(&name, new(bool))
+	require.ErrorIs(t, err, pgkit.ErrNoRows)
+
+	n, err := q.ExecAffected(ctx, DB.SQL.Update("accounts").Set("disabled", false))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestResultCache(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b", Disabled: true}}))
+	require.NoError(t, err)
+
+	var queries int
+	cache := pgkit.NewResultCache(time.Minute)
+	q := DB.Query.Use(cache.Middleware(), func(next pgkit.QueryFunc) pgkit.QueryFunc {
+		return func(ctx context.Context, stmt pgkit.Statement) (pgkit.Result, error) {
+			queries++
+			return next(ctx, stmt)
+		}
+	})
+
+	cctx := pgkit.WithCacheTags(ctx, "accounts")
+	query := DB.SQL.Select("*").From("accounts").OrderBy("name")
+
+	for i := 0; i < 2; i++ {
+		var accounts []*Account
+		err = q.GetAll(cctx, query, &accounts)
+		require.NoError(t, err)
+		require.Len(t, accounts, 2)
+		assert.Equal(t, "a", accounts[0].Name)
+		assert.True(t, accounts[1].Disabled)
+		assert.False(t, accounts[1].CreatedAt.IsZero())
+	}
+	assert.Equal(t, 1, queries)
+
+	// not cached without cache tags
+	var count int
+	err = q.GetOne(ctx, DB.SQL.Select("COUNT(*)").From("accounts"), &count)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 2, queries)
+
+	_, err = q.Exec(ctx, DB.SQL.Delete("accounts").Where(sq.Eq{"name": "a"}))
+	require.NoError(t, err)
+	cache.Invalidate("accounts")
+
+	var accounts []*Account
+	err = q.GetAll(cctx, query, &accounts)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, 4, queries)
+
+	// the reads of transactions, which may see their uncommitted writes, are
+	// not cached
+	cache.Purge()
+	errRollback := errors.New("rollback")
+	err = DB.BeginFunc(ctx, func(tx *pgkit.Querier) error {
+		tx = tx.Use(cache.Middleware())
+		if _, err := tx.Exec(ctx, DB.SQL.InsertRecord(&Account{Name: "c"})); err != nil {
+			return err
+		}
+		var accounts []*Account
+		if err := tx.GetAll(cctx, query, &accounts); err != nil {
+			return err
+		}
+		assert.Len(t, accounts, 2)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	accounts = nil
+	err = q.GetAll(cctx, query, &accounts)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, 5, queries)
+}
+
+func TestQuerierWithSettings(t *testing.T) {
+	ctx := context.Background()
+	settings := map[string]string{"work_mem": "7MB", "application_name": "reports"}
+	query := pgkit.RawQuery("SELECT current_setting('work_mem')").Build()
+
+	var defaultWorkMem string
+	err := DB.Query.QueryRow(ctx, query).Scan(&defaultWorkMem)
+	require.NoError(t, err)
+	require.NotEqual(t, "7MB", defaultWorkMem)
+
+	err = DB.BeginFunc(ctx, func(q *pgkit.Querier) error {
+		q = q.WithSettings(settings)
+		for i := 0; i < 2; i++ {
+			var workMem string
+			if err := q.QueryRow(ctx, query).Scan(&workMem); err != nil {
+				return err
+			}
+			assert.Equal(t, "7MB", workMem)
+		}
+		_, err := q.BatchExec(ctx, pgkit.Queries{query})
+		return err
+	})
+	require.NoError(t, err)
+
+	// rolling back a savepoint the settings were set in undoes them, they are
+	// set again before the next statement
+	errRollback := errors.New("rollback")
+	err = DB.BeginFunc(ctx, func(q *pgkit.Querier) error {
+		q = q.WithSettings(settings)
+		err := q.Savepoint(ctx, func(q *pgkit.Querier) error {
+			var workMem string
+			if err := q.QueryRow(ctx, query).Scan(&workMem); err != nil {
+				return err
+			}
+			assert.Equal(t, "7MB", workMem)
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			return err
+		}
+
+		var workMem string
+		if err := q.QueryRow(ctx, query).Scan(&workMem); err != nil {
+			return err
+		}
+		assert.Equal(t, "7MB", workMem)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// outside of a transaction
+	q := DB.Query.WithSettings(settings)
+	var workMem string
+	err = q.QueryRow(ctx, query).Scan(&workMem)
+	require.NoError(t, err)
+	assert.Equal(t, "7MB", workMem)
+
+	var values []string
+	err = q.GetAll(ctx, pgkit.RawQuery("SELECT current_setting('application_name')").Build(), &values)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reports"}, values)
+
+	_, err = q.BatchExec(ctx, pgkit.Queries{query})
+	require.Error(t, err)
+
+	// the settings don't leak onto the pooled connections
+	err = DB.Query.QueryRow(ctx, query).Scan(&workMem)
+	require.NoError(t, err)
+	assert.Equal(t, defaultWorkMem, workMem)
+}
+
+func TestWithApplicationName(t *testing.T) {
+	ctx := pgkit.WithApplicationName(context.Background(), "billing-job")
+	query := pgkit.RawQuery("SELECT current_setting('application_name')").Build()
+
+	var defaultName string
+	err := DB.Query.QueryRow(context.Background(), query).Scan(&defaultName)
+	require.NoError(t, err)
+	require.NotEqual(t, "billing-job", defaultName)
+
+	err = DB.BeginFunc(ctx, func(q *pgkit.Querier) error {
+		var name string
+		if err := q.QueryRow(ctx, query).Scan(&name); err != nil {
+			return err
+		}
+		assert.Equal(t, "billing-job", name)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// outside of a transaction, along with the querier settings
+	var name string
+	err = DB.Query.QueryRow(ctx, query).Scan(&name)
+	require.NoError(t, err)
+	assert.Equal(t, "billing-job", name)
+
+	var value string
+	q := DB.Query.WithSettings(map[string]string{"work_mem": "7MB"})
+	err = q.QueryRow(ctx, pgkit.RawQuery("SELECT current_setting('application_name') || current_setting('work_mem')").Build()).Scan(&value)
+	require.NoError(t, err)
+	assert.Equal(t, "billing-job7MB", value)
+
+	// the name doesn't leak onto the pooled connections
+	err = DB.Query.QueryRow(context.Background(), query).Scan(&name)
+	require.NoError(t, err)
+	assert.Equal(t, defaultName, name)
+}
+
+func TestWriteFencing(t *testing.T) {
+	ctx := context.Background()
+	conf := pgkit.Config{
+		Database:        "pgkit_test",
+		Host:            "localhost",
+		Username:        "postgres",
+		Password:        "postgres",
+		ConnMaxLifetime: "1h",
+		WriteFencing:    true,
+	}
+
+	primary, err := connectToDb(conf)
+	require.NoError(t, err)
+	defer primary.Close()
+
+	_, err = primary.Query.Exec(ctx, primary.SQL.Update("accounts").Set("disabled", false).Where(sq.Eq{"id": 0}))
+	require.NoError(t, err)
+
+	// a server only accepting read-only transactions is fenced on connect
+	conf.Override = func(cfg *pgx.ConnConfig) {
+		cfg.RuntimeParams["default_transaction_read_only"] = "on"
+	}
+	replica, err := pgkit.Connect("pgkit_test", conf)
+	require.NoError(t, err)
+	defer replica.Close()
+
+	_, err = replica.Query.Exec(ctx, replica.SQL.Update("accounts").Set("disabled", false).Where(sq.Eq{"id": 0}))
+	require.ErrorIs(t, err, pgkit.ErrReadOnlyReplica)
+}
+
+func TestPurgeDeletedBefore(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b"}, {Name: "c"}}))
+	require.NoError(t, err)
+
+	// schema-qualified table, quoted identifiers
+	n, err := DB.Query.PurgeDeletedBefore(ctx, "public.accounts", time.Now().Add(time.Hour), &pgkit.PurgeOptions{Column: "created_at", BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	_, err = DB.Query.PurgeDeletedBefore(ctx, "accounts", time.Now(), &pgkit.PurgeOptions{Column: "created_at?"})
+	require.Error(t, err)
+}
+
+func TestNotifyTriggerWideRow(t *testing.T) {
+	truncateTable(t, "reviews")
+	ctx := context.Background()
+
+	require.NoError(t, cdc.InstallNotifyTrigger(ctx, DB, "reviews", "reviews_changes"))
+	defer cdc.UninstallNotifyTrigger(ctx, DB, "reviews")
+
+	conn, err := DB.Conn.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+	_, err = conn.Exec(ctx, "LISTEN reviews_changes")
+	require.NoError(t, err)
+	defer conn.Exec(context.Background(), "UNLISTEN reviews_changes")
+
+	// the row is too wide for a NOTIFY payload, the write must not fail
+	var id int64
+	err = DB.Query.QueryRow(ctx, DB.SQL.InsertRecord(&Review{Name: "a", Comments: strings.Repeat("a", 9000)}, "reviews").Suffix("RETURNING id")).Scan(&id)
+	require.NoError(t, err)
+
+	wctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	n, err := conn.Conn().WaitForNotification(wctx)
+	require.NoError(t, err)
+
+	var payload struct {
+		Op        string                 `json:"op"`
+		Truncated bool                   `json:"truncated"`
+		New       map[string]interface{} `json:"new"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(n.Payload), &payload))
+	assert.Equal(t, "I", payload.Op)
+	assert.True(t, payload.Truncated)
+	assert.Equal(t, map[string]interface{}{"id": float64(id)}, payload.New)
+}
+
+func TestFeatureFlagsWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := kvstore.New(DB, "kv")
+	require.NoError(t, store.CreateTable(ctx))
+	truncateTable(t, "kv")
+
+	flags := featureflag.New(store, "flags.")
+	require.NoError(t, flags.InstallTrigger(ctx, "kv_changes"))
+	defer cdc.UninstallNotifyTrigger(context.Background(), DB, "kv")
+
+	// set before watching, picked up by the initial load
+	require.NoError(t, flags.Set(ctx, "beta", true))
+
+	done := make(chan error, 1)
+	go func() { done <- flags.Watch(ctx, "kv_changes") }()
+	require.Eventually(t, func() bool { return flags.Bool("beta", false) }, 5*time.Second, 10*time.Millisecond)
+
+	// values too large for a NOTIFY payload can still be written, and are
+	// picked up by key
+	large := strings.Repeat("a", 9000)
+	require.NoError(t, flags.Set(ctx, "banner", large))
+	require.Eventually(t, func() bool { return flags.String("banner", "") == large }, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.Error(t, <-done)
+}
+
+func TestHeavyQueryTimeout(t *testing.T) {
+	ctx := context.Background()
+	db, err := connectToDb(pgkit.Config{
+		Database:            "pgkit_test",
+		Host:                "localhost",
+		Username:            "postgres",
+		Password:            "postgres",
+		ConnMaxLifetime:     "1h",
+		DefaultQueryTimeout: "100ms",
+		Heavy:               &pgkit.HeavyConfig{StatementTimeout: "5s"},
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	query := pgkit.RawQuery("SELECT pg_sleep(0.3)").Build()
+
+	_, err = db.Query.Exec(ctx, query)
+	require.Error(t, err)
+
+	// heavy queries outlive the default query timeout
+	_, err = db.Query.Heavy().Exec(ctx, query)
+	require.NoError(t, err)
+}
+
+func TestHistoryTableQuotedNames(t *testing.T) {
+	ctx := context.Background()
+
+	// a table named with a reserved word and a case sensitive id column
+	_, err := DB.Query.BatchExec(ctx, pgkit.Queries{
+		pgkit.RawSQL{Query: `DROP TABLE IF EXISTS "order", "order_history"`},
+		pgkit.RawSQL{Query: `CREATE TABLE "order" ("ID" SERIAL PRIMARY KEY, name TEXT)`},
+	})
+	require.NoError(t, err)
+
+	history := pgkit.HistoryTable{TableName: "order", IDColumn: "ID"}
+	require.NoError(t, history.Install(ctx, DB.Query))
+
+	type order struct {
+		ID        int64     `db:"ID"`
+		Name      string    `db:"name"`
+		ValidFrom time.Time `db:"valid_from"`
+		ValidTo   time.Time `db:"valid_to"`
+	}
+
+	var id int64
+	err = DB.Query.QueryRow(ctx, pgkit.RawSQL{Query: `INSERT INTO "order" (name) VALUES ('a') RETURNING "ID"`}).Scan(&id)
+	require.NoError(t, err)
+	_, err = DB.Query.Exec(ctx, pgkit.RawSQL{Query: `UPDATE "order" SET name = 'b' WHERE "ID" = $1`, Args: []interface{}{id}})
+	require.NoError(t, err)
+
+	var versions []*order
+	require.NoError(t, history.Versions(ctx, DB.Query, id, &versions))
+	require.Len(t, versions, 2)
+	assert.Equal(t, "a", versions[0].Name)
+	assert.Equal(t, "b", versions[1].Name)
+
+	var asOf order
+	require.NoError(t, history.GetAsOf(ctx, DB.Query, id, versions[0].ValidFrom, &asOf))
+	assert.Equal(t, "a", asOf.Name)
+}
+
+func TestAsRole(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, pgkit.RawSQL{Query: `DO $$ BEGIN
+		IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'pgkit_reader') THEN
+			CREATE ROLE pgkit_reader;
+		END IF;
+	END $$`})
+	require.NoError(t, err)
+	_, err = DB.Query.Exec(ctx, pgkit.RawSQL{Query: "GRANT SELECT ON accounts TO pgkit_reader"})
+	require.NoError(t, err)
+
+	err = DB.AsRole(ctx, "pgkit_reader", func(q *pgkit.Querier) error {
+		var user string
+		if err := q.QueryRow(ctx, pgkit.RawSQL{Query: "SELECT current_user"}).Scan(&user); err != nil {
+			return err
+		}
+		assert.Equal(t, "pgkit_reader", user)
+
+		var count int
+		return q.GetOne(ctx, DB.SQL.Select("COUNT(*)").From("accounts"), &count)
+	})
+	require.NoError(t, err)
+
+	err = DB.AsRole(ctx, "pgkit_reader", func(q *pgkit.Querier) error {
+		_, err := q.Exec(ctx, DB.SQL.InsertRecord(&Account{Name: "reader"}))
+		return err
+	})
+	require.ErrorContains(t, err, "permission denied")
+
+	var user string
+	err = DB.Query.QueryRow(ctx, pgkit.RawSQL{Query: "SELECT current_user"}).Scan(&user)
+	require.NoError(t, err)
+	assert.NotEqual(t, "pgkit_reader", user)
+}
+
+func TestAnyArray(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b"}, {Name: "c"}}))
+	require.NoError(t, err)
+
+	var accounts []*Account
+	err = DB.Query.GetAll(ctx, DB.SQL.Select("*").From("accounts").Where(db.Cond{"name": db.AnyArray([]string{"a", "c", "d"})}).OrderBy("name"), &accounts)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, "a", accounts[0].Name)
+	assert.Equal(t, "c", accounts[1].Name)
+
+	ids := []int64{accounts[0].ID, accounts[1].ID}
+	accounts = nil
+	err = DB.Query.GetAll(ctx, DB.SQL.Select("*").From("accounts").Where(db.Cond{"id": db.NotAnyArray(ids)}), &accounts)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "b", accounts[0].Name)
+}
+
+func TestUnnest(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	names := []string{"a", "b", "c"}
+	disabled := []bool{false, true, false}
+	n, err := DB.Query.ExecAffected(ctx, DB.SQL.Insert("accounts").Columns("name", "disabled").
+		Select(db.UnnestSelect([]string{"name", "disabled"}, names, disabled)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	var accounts []*Account
+	err = DB.Query.GetAll(ctx, DB.SQL.Select("a.*").From("accounts a").
+		JoinClause(sq.Expr("JOIN ? AS t(name, disabled) ON t.name = a.name AND t.disabled = a.disabled", db.Unnest([]string{"b", "c"}, []bool{true, true}))), &accounts)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "b", accounts[0].Name)
+}
+
+func TestPaginatorForEachPage(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b"}, {Name: "b"}, {Name: "c"}, {Name: "d", Disabled: true}}))
+	require.NoError(t, err)
+
+	paginator := pgkit.NewPaginator[*Account](pgkit.WithMaxSize[*Account](2), pgkit.WithSort[*Account]("-name", "id"))
+
+	var (
+		names  []string
+		chunks int
+	)
+	err = paginator.ForEachPage(ctx, DB.Query, DB.SQL.Select("*").From("accounts").Where(sq.Eq{"disabled": false}), func(rows []*Account) error {
+		chunks++
+		assert.LessOrEqual(t, len(rows), 2)
+		for _, row := range rows {
+			names = append(names, row.Name)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b", "b", "a"}, names)
+	assert.Equal(t, 2, chunks)
+}
+
+func TestQuerierWithMaxRows(t *testing.T) {
+	truncateTable(t, "accounts")
+	ctx := context.Background()
+
+	_, err := DB.Query.Exec(ctx, DB.SQL.InsertRecords([]*Account{{Name: "a"}, {Name: "b"}, {Name: "c"}}))
+	require.NoError(t, err)
+
+	var accounts []*Account
+	err = DB.Query.WithMaxRows(3).GetAll(ctx, DB.SQL.Select("*").From("accounts"), &accounts)
+	require.NoError(t, err)
+	assert.Len(t, accounts, 3)
+
+	accounts = nil
+	err = DB.Query.WithMaxRows(2).GetAll(ctx, DB.SQL.Select("*").From("accounts"), &accounts)
+	require.ErrorIs(t, err, pgkit.ErrTooManyRows)
+	var maxRowsErr *pgkit.MaxRowsError
+	require.ErrorAs(t, err, &maxRowsErr)
+	assert.Equal(t, 2, maxRowsErr.Max)
+
+	_, err = DB.Query.WithMaxRows(2).GetAllMaps(ctx, DB.SQL.Select("*").From("accounts"))
+	require.ErrorIs(t, err, pgkit.ErrTooManyRows)
+}
+
 func TestTransactionBasics(t *testing.T) {
 	truncateTable(t, "accounts")
 
@@ -813,6 +2022,8 @@ type LogRecord struct {
 	Args     []interface{} `json:"args,omitempty"`
 	Err      string        `json:"err"`
 	Duration time.Duration `json:"duration,omitempty"`
+	Tag      string        `json:"tag,omitempty"`
+	Caller   string        `json:"caller,omitempty"`
 }
 
 func TestSlogQueryTracerWithValuesReplaced(t *testing.T) {
@@ -1128,6 +2339,78 @@ func TestSlogTracerBatchQuery(t *testing.T) {
 	}
 }
 
+func TestSlogQueryTracerWithCallerInfo(t *testing.T) {
+	buf, slogTracer := getTracer([]tracer.Option{tracer.WithLogAllQueries(), tracer.WithCallerInfo()})
+
+	dbClient, err := connectToDb(pgkit.Config{
+		Database:        "pgkit_test",
+		Host:            "localhost",
+		Username:        "postgres",
+		Password:        "postgres",
+		ConnMaxLifetime: "1h",
+		Tracer:          tracer.NewSQLTracer(slogTracer),
+	})
+	require.NoError(t, err)
+	defer dbClient.Conn.Close()
+
+	ctx := pgkit.WithQueryTag(context.Background(), "list-accounts")
+	accounts := []*Account{}
+	err = dbClient.Query.GetAll(ctx, pgkit.RawQuery("SELECT * FROM accounts").Build(), &accounts)
+	require.NoError(t, err)
+
+	var record LogRecord
+	line, _, err := bufio.NewReader(buf).ReadLine()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(line, &record))
+
+	assert.Equal(t, "list-accounts", record.Tag)
+	assert.Contains(t, record.Caller, "tests/pgkit_test.go:")
+}
+
+func TestPoolEventTracing(t *testing.T) {
+	var connects, closes int
+	var acquireErr error
+	logTracer := tracer.NewLogTracer(nil,
+		tracer.WithLogPoolEvents(),
+		tracer.WithLogConnectHook(func(ctx context.Context, duration time.Duration, err error) {
+			connects++
+		}),
+		tracer.WithLogAcquireHook(func(ctx context.Context, stat *pgxpool.Stat, wait time.Duration, err error) {
+			acquireErr = err
+		}),
+		tracer.WithLogConnCloseHook(func(ctx context.Context, pid uint32) {
+			closes++
+		}),
+	)
+
+	dbClient, err := connectToDb(pgkit.Config{
+		Database:        "pgkit_test",
+		Host:            "localhost",
+		Username:        "postgres",
+		Password:        "postgres",
+		MaxConns:        1,
+		ConnMaxLifetime: "1h",
+		Tracer:          tracer.NewSQLTracer(logTracer),
+	})
+	require.NoError(t, err)
+
+	conn, err := dbClient.Conn.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, connects)
+
+	// the pool is exhausted
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = dbClient.Conn.Acquire(ctx)
+	require.Error(t, err)
+	require.Error(t, acquireErr)
+	assert.Equal(t, tracer.ErrorClassTimeout, tracer.ClassifyError(acquireErr))
+
+	conn.Release()
+	dbClient.Conn.Close()
+	assert.Equal(t, 1, closes)
+}
+
 func getTracer(opts []tracer.Option) (*bytes.Buffer, *tracer.LogTracer) {
 	buf := &bytes.Buffer{}
 	handler := slog.NewJSONHandler(buf, nil)
@@ -0,0 +1,89 @@
+package pgkit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WithTimeout returns a copy of the querier which applies the timeout to the
+// queries run with a context without deadline, see Config.DefaultQueryTimeout.
+// A zero timeout disables it.
+func (q *Querier) WithTimeout(timeout time.Duration) *Querier {
+	qq := *q
+	qq.timeout = timeout
+	return &qq
+}
+
+// execContext applies the default query timeout to the context and takes the
+// limiter slot of the query. The returned func must be called once the query
+// is done.
+func (q *Querier) execContext(ctx context.Context) (context.Context, func(), error) {
+	cancel := context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok && q.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, q.timeout)
+	}
+
+	release, err := q.limit(ctx)
+	if err != nil {
+		cancel()
+		return ctx, nil, err
+	}
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			release()
+			cancel()
+		})
+	}, nil
+}
+
+// tracksDone reports whether the results of the querier's queries must be
+// wrapped to call the done func of execContext once read.
+func (q *Querier) tracksDone() bool {
+	return q.limiter != nil || q.timeout > 0
+}
+
+// doneRows calls done once the rows are closed.
+type doneRows struct {
+	pgx.Rows
+	done func()
+}
+
+func (r doneRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.done()
+	return false
+}
+
+func (r doneRows) Close() {
+	r.Rows.Close()
+	r.done()
+}
+
+// doneRow calls done once the row is scanned.
+type doneRow struct {
+	pgx.Row
+	done func()
+}
+
+func (r doneRow) Scan(dest ...interface{}) error {
+	defer r.done()
+	return r.Row.Scan(dest...)
+}
+
+// doneBatchResults calls done once the results are closed.
+type doneBatchResults struct {
+	pgx.BatchResults
+	done func()
+}
+
+func (r doneBatchResults) Close() error {
+	defer r.done()
+	return r.BatchResults.Close()
+}
@@ -0,0 +1,56 @@
+package tracer
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+var contextKeyCaller = ctxKey("caller")
+
+// callerSkipPrefixes are the packages whose frames are skipped to find the
+// application code which issued a query.
+var callerSkipPrefixes = []string{
+	"runtime.",
+	"github.com/goware/pgkit/",
+	"github.com/jackc/pgx/",
+	"github.com/georgysavva/scany/",
+}
+
+// Caller returns the application file:line which issued the query, recorded
+// by a LogTracer with CallerInfo enabled, or "" if unknown.
+func Caller(ctx context.Context) string {
+	caller, _ := ctx.Value(contextKeyCaller).(string)
+	return caller
+}
+
+// caller walks the stack past the library frames and returns the file:line
+// of the first application frame.
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" && !isLibraryFrame(frame) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func isLibraryFrame(frame runtime.Frame) bool {
+	// pgkit's own tests are application code
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	for _, prefix := range callerSkipPrefixes {
+		if strings.HasPrefix(frame.Function, prefix) {
+			return true
+		}
+	}
+	return false
+}
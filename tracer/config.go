@@ -3,6 +3,8 @@ package tracer
 import (
 	"context"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type config struct {
@@ -14,6 +16,14 @@ type config struct {
 	logSlowQueryHook        func(ctx context.Context, query string, duration time.Duration)
 	logEndQueryHook         func(ctx context.Context, query string, duration time.Duration)
 	logFailedQueryHook      func(ctx context.Context, query string, err error)
+	logLongTxHook           func(ctx context.Context, callSite string, duration time.Duration)
+	lockDiagnostics         LockQuerier
+	callerInfo              bool
+	logPoolEvents           bool
+	logSlowAcquireThreshold time.Duration
+	logConnectHook          func(ctx context.Context, duration time.Duration, err error)
+	logAcquireHook          func(ctx context.Context, stat *pgxpool.Stat, wait time.Duration, err error)
+	logConnCloseHook        func(ctx context.Context, pid uint32)
 }
 
 type optionFunc func(config *config)
@@ -50,6 +60,12 @@ func WithLogEndHook(f func(ctx context.Context, query string, duration time.Dura
 	})
 }
 
+func WithLogLongTxHook(f func(ctx context.Context, callSite string, duration time.Duration)) Option {
+	return optionFunc(func(c *config) {
+		c.logLongTxHook = f
+	})
+}
+
 func WithLogAllQueries() Option {
 	return optionFunc(func(config *config) {
 		config.logAllQueries = true
@@ -73,3 +89,61 @@ func WithLogSlowQueriesThreshold(threshold time.Duration) Option {
 		config.logSlowQueriesThreshold = threshold
 	})
 }
+
+// WithLockDiagnostics captures the likely blocking sessions, the ones holding
+// locks on the relation of the error, when a query fails with a lock timeout,
+// and attaches them to the error passed to the failed query hook as a
+// *LockError. The lookup runs in the background, on a connection of q, one at
+// a time, so the failed query hook of lock timeouts may be called after the
+// query returned. Deadlock errors are passed as a *LockError without
+// blockers, as the deadlock is resolved by the time the error arrives; their
+// detail lists the processes which were involved.
+func WithLockDiagnostics(q LockQuerier) Option {
+	return optionFunc(func(config *config) {
+		config.lockDiagnostics = q
+	})
+}
+
+// WithCallerInfo records the application file:line which issued each query,
+// skipping the pgkit, pgx and scany frames, and includes it in the logs as the
+// "caller" attribute. Custom hooks can read it with Caller.
+func WithCallerInfo() Option {
+	return optionFunc(func(config *config) {
+		config.callerInfo = true
+	})
+}
+
+// WithLogPoolEvents logs connection establishment, with its duration, and
+// connection close. Connect and acquire failures, ie. acquire timeouts, are
+// always logged.
+func WithLogPoolEvents() Option {
+	return optionFunc(func(config *config) {
+		config.logPoolEvents = true
+	})
+}
+
+// WithLogSlowAcquireThreshold logs connection acquisitions which waited on the
+// pool longer than the threshold, a sign of pool starvation.
+func WithLogSlowAcquireThreshold(threshold time.Duration) Option {
+	return optionFunc(func(config *config) {
+		config.logSlowAcquireThreshold = threshold
+	})
+}
+
+func WithLogConnectHook(f func(ctx context.Context, duration time.Duration, err error)) Option {
+	return optionFunc(func(c *config) {
+		c.logConnectHook = f
+	})
+}
+
+func WithLogAcquireHook(f func(ctx context.Context, stat *pgxpool.Stat, wait time.Duration, err error)) Option {
+	return optionFunc(func(c *config) {
+		c.logAcquireHook = f
+	})
+}
+
+func WithLogConnCloseHook(f func(ctx context.Context, pid uint32)) Option {
+	return optionFunc(func(c *config) {
+		c.logConnCloseHook = f
+	})
+}
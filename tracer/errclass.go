@@ -0,0 +1,99 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrorClass is the category of a failed query error, to tell user input
+// errors apart from infrastructure failures in alerting.
+type ErrorClass string
+
+const (
+	ErrorClassConstraint    ErrorClass = "constraint_violation"
+	ErrorClassData          ErrorClass = "data"
+	ErrorClassSerialization ErrorClass = "serialization"
+	ErrorClassTimeout       ErrorClass = "timeout"
+	ErrorClassCanceled      ErrorClass = "canceled"
+	ErrorClassConnection    ErrorClass = "connection"
+	ErrorClassSyntax        ErrorClass = "syntax"
+	ErrorClassOther         ErrorClass = "other"
+)
+
+// QueryError is the error passed to the failed query hook of LogTracer, which
+// wraps the query error with its class.
+type QueryError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError returns the class of the error, derived from the SQLSTATE of
+// a *pgconn.PgError, or from the connection and timeout errors of pgconn.
+// Queries aborted by the cancellation of their context, ie. by the client
+// of a request going away, are classified as canceled rather than timeout.
+func ClassifyError(err error) ErrorClass {
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return classifySQLState(pgErr.Code)
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return ErrorClassTimeout
+	case errors.As(err, &connectErr), errors.As(err, &netErr), pgconn.SafeToRetry(err):
+		return ErrorClassConnection
+	}
+	return ErrorClassOther
+}
+
+// classifyQueryError is like ClassifyError, but also classifies the errors of
+// queries whose context was canceled as canceled, ie. the query_canceled
+// error of the cancel request pgx sends to the server on cancellation.
+func classifyQueryError(ctx context.Context, err error) ErrorClass {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ErrorClassCanceled
+	}
+	return ClassifyError(err)
+}
+
+// see: https://www.postgresql.org/docs/current/errcodes-appendix.html
+func classifySQLState(code string) ErrorClass {
+	switch code {
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return ErrorClassSerialization
+	case "57014", "55P03", "25P03": // query_canceled, lock_not_available, idle_in_transaction_session_timeout
+		return ErrorClassTimeout
+	case "57P01", "57P02", "57P03": // admin_shutdown, crash_shutdown, cannot_connect_now
+		return ErrorClassConnection
+	}
+
+	switch {
+	case strings.HasPrefix(code, "23"): // integrity constraint violation
+		return ErrorClassConstraint
+	case strings.HasPrefix(code, "22"): // data exception
+		return ErrorClassData
+	case strings.HasPrefix(code, "08"): // connection exception
+		return ErrorClassConnection
+	case strings.HasPrefix(code, "42"): // syntax error or access rule violation
+		return ErrorClassSyntax
+	}
+	return ErrorClassOther
+}
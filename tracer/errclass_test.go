@@ -0,0 +1,44 @@
+package tracer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err   error
+		class ErrorClass
+	}{
+		{&pgconn.PgError{Code: "23505"}, ErrorClassConstraint},
+		{&pgconn.PgError{Code: "22P02"}, ErrorClassData},
+		{&pgconn.PgError{Code: "40001"}, ErrorClassSerialization},
+		{&pgconn.PgError{Code: "40P01"}, ErrorClassSerialization},
+		{&pgconn.PgError{Code: "57014"}, ErrorClassTimeout},
+		{&pgconn.PgError{Code: "08006"}, ErrorClassConnection},
+		{&pgconn.PgError{Code: "42601"}, ErrorClassSyntax},
+		{&pgconn.PgError{Code: "XX000"}, ErrorClassOther},
+		{fmt.Errorf("query: %w", &pgconn.PgError{Code: "23503"}), ErrorClassConstraint},
+		{&LockError{Err: &pgconn.PgError{Code: "55P03"}}, ErrorClassTimeout},
+		{context.DeadlineExceeded, ErrorClassTimeout},
+		{context.Canceled, ErrorClassCanceled},
+		{fmt.Errorf("timeout: %w", context.Canceled), ErrorClassCanceled},
+		{fmt.Errorf("boom"), ErrorClassOther},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.class, ClassifyError(tt.err), tt.err.Error())
+	}
+}
+
+func TestClassifyQueryError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := &pgconn.PgError{Code: "57014"}
+	assert.Equal(t, ErrorClassTimeout, classifyQueryError(ctx, err))
+
+	cancel()
+	assert.Equal(t, ErrorClassCanceled, classifyQueryError(ctx, err))
+}
@@ -0,0 +1,154 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	sqlStateDeadlockDetected = "40P01"
+	sqlStateLockNotAvailable = "55P03"
+)
+
+// LockQuerier runs the lock diagnostics queries, ie. a *pgxpool.Pool. It must
+// not be the connection of the failed query, which may be in an aborted
+// transaction.
+type LockQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Blocker is a session which was blocking other sessions on a lock when a
+// lock related error was captured.
+type Blocker struct {
+	PID       int32
+	State     string
+	Query     string
+	XactStart *time.Time
+}
+
+// LockError wraps a deadlock or lock timeout error with the likely blocking
+// sessions found in pg_stat_activity and pg_locks after the error, see
+// WithLockDiagnostics. Deadlock errors never have blockers.
+type LockError struct {
+	Err      error
+	Blockers []Blocker
+}
+
+func (e *LockError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+
+	var pgErr *pgconn.PgError
+	if errors.As(e.Err, &pgErr) && pgErr.Detail != "" {
+		b.WriteString(" (" + pgErr.Detail + ")")
+	}
+
+	for _, blocker := range e.Blockers {
+		fmt.Fprintf(&b, "; blocked by pid %d (%s", blocker.PID, blocker.State)
+		if blocker.XactStart != nil {
+			fmt.Fprintf(&b, ", in transaction for %s", time.Since(*blocker.XactStart).Round(time.Millisecond))
+		}
+		fmt.Fprintf(&b, "): %s", blocker.Query)
+	}
+	return b.String()
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// blockersQuery returns the other sessions holding locks on the relation
+// stronger than the ACCESS SHARE lock of plain reads, ie. the row and
+// table locks of writes, SELECT FOR UPDATE or LOCK TABLE.
+const blockersQuery = `SELECT a.pid, coalesce(a.state, ''), coalesce(a.query, ''), a.xact_start
+FROM pg_stat_activity a
+WHERE a.pid <> $1 AND a.pid IN (
+	SELECT l.pid FROM pg_locks l
+	WHERE l.granted AND l.mode <> 'AccessShareLock' AND l.relation = to_regclass($2)
+)
+ORDER BY a.xact_start`
+
+// isLockErr reports whether err is a deadlock or a lock timeout.
+func isLockErr(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == sqlStateDeadlockDetected || pgErr.Code == sqlStateLockNotAvailable
+}
+
+// isLockTimeoutErr reports whether err is a lock timeout, or a failed NOWAIT
+// lock.
+func isLockTimeoutErr(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == sqlStateLockNotAvailable
+}
+
+var lockRelationRe = regexp.MustCompile(`relation "((?:[^"]|"")+)"`)
+
+// lockRelation returns the relation of the lock the error failed to obtain,
+// as found in its table name, message or context, ie. `while updating tuple
+// (0,1) in relation "accounts"`, or "" if unknown.
+func lockRelation(err error) string {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return ""
+	}
+	if pgErr.TableName != "" {
+		if pgErr.SchemaName != "" {
+			return pgx.Identifier{pgErr.SchemaName, pgErr.TableName}.Sanitize()
+		}
+		return pgx.Identifier{pgErr.TableName}.Sanitize()
+	}
+	for _, s := range []string{pgErr.Message, pgErr.Where} {
+		if m := lockRelationRe.FindStringSubmatch(s); m != nil {
+			return pgx.Identifier{strings.ReplaceAll(m[1], `""`, `"`)}.Sanitize()
+		}
+	}
+	return ""
+}
+
+// captureLockDiagnostics returns err wrapped in a LockError listing the other
+// sessions holding locks on the relation of the lock timeout, the likely
+// blockers of the session pid which ran the failed query. As the failed
+// session no longer waits once the error arrives, its blockers can't be
+// looked up directly.
+//
+// Deadlock errors are returned without blockers: the deadlock is already
+// resolved by the time the error arrives, the error detail lists the
+// processes which were involved. So are lock timeouts whose relation is
+// unknown. Errors of the diagnostics query itself are ignored, returning a
+// LockError without blockers.
+func captureLockDiagnostics(ctx context.Context, q LockQuerier, pid uint32, err error) error {
+	lockErr := &LockError{Err: err}
+
+	relation := lockRelation(err)
+	if !isLockTimeoutErr(err) || relation == "" {
+		return lockErr
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	rows, qerr := q.Query(ctx, blockersQuery, int64(pid), relation)
+	if qerr != nil {
+		return lockErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b Blocker
+		if err := rows.Scan(&b.PID, &b.State, &b.Query, &b.XactStart); err != nil {
+			break
+		}
+		lockErr.Blockers = append(lockErr.Blockers, b)
+	}
+	return lockErr
+}
@@ -0,0 +1,57 @@
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockRelation(t *testing.T) {
+	tests := []struct {
+		err      error
+		relation string
+	}{
+		{&pgconn.PgError{Code: "55P03", Message: `could not obtain lock on row in relation "accounts"`}, `"accounts"`},
+		{&pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout", Where: `while updating tuple (0,1) in relation "Orders"`}, `"Orders"`},
+		{&pgconn.PgError{Code: "55P03", Message: `could not obtain lock on relation "a""b"`}, `"a""b"`},
+		{&pgconn.PgError{Code: "55P03", SchemaName: "billing", TableName: "invoices"}, `"billing"."invoices"`},
+		{&pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout"}, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.relation, lockRelation(tt.err))
+	}
+}
+
+// lockQuerierFunc is a LockQuerier calling the func.
+type lockQuerierFunc func(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+
+func (f lockQuerierFunc) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return f(ctx, sql, args...)
+}
+
+func TestCaptureLockDiagnostics(t *testing.T) {
+	var queries [][]any
+	q := lockQuerierFunc(func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+		queries = append(queries, args)
+		return nil, context.DeadlineExceeded
+	})
+
+	// the deadlock is resolved by the time the error arrives
+	deadlock := &pgconn.PgError{Code: "40P01", Message: "deadlock detected", Where: `while updating tuple (0,1) in relation "accounts"`}
+	err := captureLockDiagnostics(context.Background(), q, 42, deadlock)
+	var lockErr *LockError
+	require.ErrorAs(t, err, &lockErr)
+	assert.Empty(t, lockErr.Blockers)
+	assert.Empty(t, queries)
+
+	// the blockers of lock timeouts are looked up on the relation
+	timeout := &pgconn.PgError{Code: "55P03", Message: `could not obtain lock on row in relation "accounts"`}
+	err = captureLockDiagnostics(context.Background(), q, 42, timeout)
+	require.ErrorAs(t, err, &lockErr)
+	require.ErrorIs(t, err, timeout)
+	assert.Equal(t, [][]any{{int64(42), `"accounts"`}}, queries)
+}
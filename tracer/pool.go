@@ -0,0 +1,156 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	contextKeyConnectStart = ctxKey("connect_start")
+	contextKeyAcquireStart = ctxKey("acquire_start")
+)
+
+// PoolTracer traces the connection pool lifecycle: connection establishment,
+// connection acquisition and connection close. Pool starvation doesn't show
+// up in query level traces, but in the acquire wait times.
+type PoolTracer interface {
+	pgx.ConnectTracer
+	pgxpool.AcquireTracer
+	TraceConnClose(ctx context.Context, conn *pgx.Conn)
+}
+
+var (
+	_ PoolTracer = &SQLTracer{}
+	_ PoolTracer = &LogTracer{}
+)
+
+func (s *SQLTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	for _, tracer := range s.tracers {
+		if t, ok := tracer.(pgx.ConnectTracer); ok {
+			ctx = t.TraceConnectStart(ctx, data)
+		}
+	}
+	return ctx
+}
+
+func (s *SQLTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	for _, tracer := range s.tracers {
+		if t, ok := tracer.(pgx.ConnectTracer); ok {
+			t.TraceConnectEnd(ctx, data)
+		}
+	}
+}
+
+func (s *SQLTracer) TraceAcquireStart(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireStartData) context.Context {
+	for _, tracer := range s.tracers {
+		if t, ok := tracer.(pgxpool.AcquireTracer); ok {
+			ctx = t.TraceAcquireStart(ctx, pool, data)
+		}
+	}
+	return ctx
+}
+
+func (s *SQLTracer) TraceAcquireEnd(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	for _, tracer := range s.tracers {
+		if t, ok := tracer.(pgxpool.AcquireTracer); ok {
+			t.TraceAcquireEnd(ctx, pool, data)
+		}
+	}
+}
+
+// TraceConnClose implements pgkit.ConnCloseTracer, notifying the tracers which
+// implement it.
+func (s *SQLTracer) TraceConnClose(ctx context.Context, conn *pgx.Conn) {
+	for _, tracer := range s.tracers {
+		if t, ok := tracer.(interface {
+			TraceConnClose(ctx context.Context, conn *pgx.Conn)
+		}); ok {
+			t.TraceConnClose(ctx, conn)
+		}
+	}
+}
+
+func (l *LogTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	return context.WithValue(ctx, contextKeyConnectStart, time.Now())
+}
+
+func (l *LogTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	start, _ := ctx.Value(contextKeyConnectStart).(time.Time)
+	if l.ConnectHook != nil && (l.LogPoolEvents || data.Err != nil) {
+		l.ConnectHook(ctx, time.Since(start), data.Err)
+	}
+}
+
+func (l *LogTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	return context.WithValue(ctx, contextKeyAcquireStart, time.Now())
+}
+
+func (l *LogTracer) TraceAcquireEnd(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	if l.AcquireHook == nil {
+		return
+	}
+	start, _ := ctx.Value(contextKeyAcquireStart).(time.Time)
+	wait := time.Since(start)
+	if data.Err != nil || (l.LogSlowAcquireThreshold > 0 && wait > l.LogSlowAcquireThreshold) {
+		l.AcquireHook(ctx, pool.Stat(), wait, data.Err)
+	}
+}
+
+// TraceConnClose implements pgkit.ConnCloseTracer.
+func (l *LogTracer) TraceConnClose(ctx context.Context, conn *pgx.Conn) {
+	if l.ConnCloseHook != nil && l.LogPoolEvents {
+		l.ConnCloseHook(ctx, conn.PgConn().PID())
+	}
+}
+
+func defaultPoolHooks(logger *slog.Logger) (
+	logConnect func(ctx context.Context, duration time.Duration, err error),
+	logAcquire func(ctx context.Context, stat *pgxpool.Stat, wait time.Duration, err error),
+	logConnClose func(ctx context.Context, pid uint32),
+) {
+	logConnect = func(ctx context.Context, duration time.Duration, err error) {
+		if logger == nil {
+			return
+		}
+		if err != nil {
+			logger.LogAttrs(ctx, slog.LevelError, "connect failed", slog.Duration("duration", duration), slog.String("err", err.Error()))
+			return
+		}
+		logger.LogAttrs(ctx, slog.LevelInfo, "connection established", slog.Duration("duration", duration))
+	}
+
+	logAcquire = func(ctx context.Context, stat *pgxpool.Stat, wait time.Duration, err error) {
+		if logger == nil {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.Duration("wait", wait),
+			slog.Int("acquired_conns", int(stat.AcquiredConns())),
+			slog.Int("max_conns", int(stat.MaxConns())),
+		}
+		if err != nil {
+			class := classifyQueryError(ctx, err)
+			attrs = append(attrs, slog.String("err", err.Error()), slog.String("err_class", string(class)))
+			// a canceled request is not a pool failure
+			level := slog.LevelError
+			if class == ErrorClassCanceled || errors.Is(err, context.Canceled) {
+				level = slog.LevelInfo
+			}
+			logger.LogAttrs(ctx, level, "acquire failed", withCtxAttrs(ctx, attrs...)...)
+			return
+		}
+		logger.LogAttrs(ctx, slog.LevelWarn, "slow acquire", withCtxAttrs(ctx, attrs...)...)
+	}
+
+	logConnClose = func(ctx context.Context, pid uint32) {
+		if logger != nil {
+			logger.LogAttrs(ctx, slog.LevelInfo, "connection closed", slog.Int("pid", int(pid)))
+		}
+	}
+	return
+}
@@ -0,0 +1,22 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTracerWithoutPoolHooks(t *testing.T) {
+	l := &LogTracer{LogPoolEvents: true}
+	ctx := context.Background()
+	err := errors.New("connection refused")
+
+	assert.NotPanics(t, func() {
+		l.TraceConnectEnd(l.TraceConnectStart(ctx, pgx.TraceConnectStartData{}), pgx.TraceConnectEndData{Err: err})
+		l.TraceAcquireEnd(l.TraceAcquireStart(ctx, nil, pgxpool.TraceAcquireStartData{}), nil, pgxpool.TraceAcquireEndData{Err: err})
+	})
+}
@@ -8,10 +8,13 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type LogTracer struct {
@@ -22,39 +25,69 @@ type LogTracer struct {
 	LogValues bool
 	// enabled if non-zero value is provided
 	LogSlowQueriesThreshold time.Duration
+	// capture blocking sessions on lock timeouts, if set, see
+	// WithLockDiagnostics
+	LockDiagnostics LockQuerier
+	// lockDiagnosing is set while the blockers of a lock timeout are captured
+	lockDiagnosing atomic.Bool
+	// record the application file:line which issued the query, see Caller
+	CallerInfo bool
+	// log connection establishment and close, failures are always logged
+	LogPoolEvents bool
+	// log acquire waits above the threshold, enabled if non-zero value is
+	// provided, acquire failures are always logged
+	LogSlowAcquireThreshold time.Duration
 
 	// give client power to change each section which is being logged
-	StartQueryHook  func(ctx context.Context, query string, args []any)
-	SlowQueryHook   func(ctx context.Context, query string, duration time.Duration)
-	EndQueryHook    func(ctx context.Context, query string, duration time.Duration)
+	StartQueryHook func(ctx context.Context, query string, args []any)
+	SlowQueryHook  func(ctx context.Context, query string, duration time.Duration)
+	EndQueryHook   func(ctx context.Context, query string, duration time.Duration)
+	// err is a *QueryError, which holds the class of the error
 	FailedQueryHook func(ctx context.Context, query string, err error)
+	LongTxHook      func(ctx context.Context, callSite string, duration time.Duration)
+	ConnectHook     func(ctx context.Context, duration time.Duration, err error)
+	AcquireHook     func(ctx context.Context, stat *pgxpool.Stat, wait time.Duration, err error)
+	ConnCloseHook   func(ctx context.Context, pid uint32)
 }
 
 func NewLogTracer(logger *slog.Logger, opts ...Option) *LogTracer {
 	logStart := func(ctx context.Context, query string, args []any) {
 		if logger != nil {
-			logger.LogAttrs(ctx, slog.LevelInfo, "query start", slog.String("query", query), slog.Any("args", args))
+			logger.LogAttrs(ctx, slog.LevelInfo, "query start", withCtxAttrs(ctx, slog.String("query", query), slog.Any("args", args))...)
 		}
 	}
 
 	logSlowQuery := func(ctx context.Context, query string, duration time.Duration) {
 		if logger != nil {
-			logger.LogAttrs(ctx, slog.LevelWarn, "slow query took", slog.Any("query", query), slog.Duration("duration", duration))
+			logger.LogAttrs(ctx, slog.LevelWarn, "slow query took", withCtxAttrs(ctx, slog.Any("query", query), slog.Duration("duration", duration))...)
 		}
 	}
 
 	logEnd := func(ctx context.Context, query string, duration time.Duration) {
 		if logger != nil {
-			logger.LogAttrs(ctx, slog.LevelInfo, "query end", slog.Any("query", query), slog.Duration("duration", duration))
+			logger.LogAttrs(ctx, slog.LevelInfo, "query end", withCtxAttrs(ctx, slog.Any("query", query), slog.Duration("duration", duration))...)
 		}
 	}
 
 	logFailed := func(ctx context.Context, query string, err error) {
 		if logger != nil {
-			logger.LogAttrs(ctx, slog.LevelError, "query failed", slog.Any("query", query), slog.String("err", err.Error()))
+			attrs := []slog.Attr{slog.Any("query", query), slog.String("err", err.Error())}
+			var queryErr *QueryError
+			if errors.As(err, &queryErr) {
+				attrs = append(attrs, slog.String("err_class", string(queryErr.Class)))
+			}
+			logger.LogAttrs(ctx, slog.LevelError, "query failed", withCtxAttrs(ctx, attrs...)...)
 		}
 	}
 
+	logLongTx := func(ctx context.Context, callSite string, duration time.Duration) {
+		if logger != nil {
+			logger.LogAttrs(ctx, slog.LevelWarn, "long running transaction", withCtxAttrs(ctx, slog.String("call_site", callSite), slog.Duration("duration", duration))...)
+		}
+	}
+
+	logConnect, logAcquire, logConnClose := defaultPoolHooks(logger)
+
 	cfg := &config{
 		logAllQueries:           false,
 		logFailedQueries:        false,
@@ -64,6 +97,10 @@ func NewLogTracer(logger *slog.Logger, opts ...Option) *LogTracer {
 		logSlowQueryHook:        logSlowQuery,
 		logEndQueryHook:         logEnd,
 		logFailedQueryHook:      logFailed,
+		logLongTxHook:           logLongTx,
+		logConnectHook:          logConnect,
+		logAcquireHook:          logAcquire,
+		logConnCloseHook:        logConnClose,
 	}
 
 	for _, opt := range opts {
@@ -75,10 +112,18 @@ func NewLogTracer(logger *slog.Logger, opts ...Option) *LogTracer {
 		LogFailedQueries:        cfg.logFailedQueries,
 		LogValues:               cfg.logValues,
 		LogSlowQueriesThreshold: cfg.logSlowQueriesThreshold,
+		LockDiagnostics:         cfg.lockDiagnostics,
+		CallerInfo:              cfg.callerInfo,
+		LogPoolEvents:           cfg.logPoolEvents,
+		LogSlowAcquireThreshold: cfg.logSlowAcquireThreshold,
 		StartQueryHook:          cfg.logStartHook,
 		SlowQueryHook:           cfg.logSlowQueryHook,
 		EndQueryHook:            cfg.logEndQueryHook,
 		FailedQueryHook:         cfg.logFailedQueryHook,
+		LongTxHook:              cfg.logLongTxHook,
+		ConnectHook:             cfg.logConnectHook,
+		AcquireHook:             cfg.logAcquireHook,
+		ConnCloseHook:           cfg.logConnCloseHook,
 	}
 }
 
@@ -88,6 +133,10 @@ func (l *LogTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.T
 		query = replacePlaceholders(query, data.Args)
 	}
 
+	if l.CallerInfo {
+		ctx = context.WithValue(ctx, contextKeyCaller, caller())
+	}
+
 	if l.LogAllQueries || isTracingEnabled(ctx) {
 		l.StartQueryHook(ctx, query, data.Args)
 	}
@@ -114,7 +163,22 @@ func (l *LogTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.
 	}
 
 	if l.LogFailedQueries && data.Err != nil && !errors.Is(data.Err, sql.ErrNoRows) {
-		l.FailedQueryHook(ctx, query, data.Err)
+		err := data.Err
+		if l.LockDiagnostics != nil && isLockErr(err) {
+			if conn != nil && isLockTimeoutErr(err) && l.lockDiagnosing.CompareAndSwap(false, true) {
+				// capture the blockers off the query path, one lookup at a
+				// time, as the pool is likely under lock contention
+				pid := conn.PgConn().PID()
+				go func() {
+					defer l.lockDiagnosing.Store(false)
+					err := captureLockDiagnostics(ctx, l.LockDiagnostics, pid, err)
+					l.FailedQueryHook(ctx, query, &QueryError{Class: classifyQueryError(ctx, err), Err: err})
+				}()
+				return
+			}
+			err = &LockError{Err: err}
+		}
+		l.FailedQueryHook(ctx, query, &QueryError{Class: classifyQueryError(ctx, err), Err: err})
 	}
 }
 
@@ -138,6 +202,40 @@ func (l *LogTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.
 	})
 }
 
+// TraceCopyFromStart implements pgx.CopyFromTracer, tracing the copy as a
+// COPY FROM STDIN query.
+func (l *LogTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	query := "COPY " + data.TableName.Sanitize() + " (" + strings.Join(data.ColumnNames, ", ") + ") FROM STDIN"
+
+	return l.TraceQueryStart(ctx, conn, pgx.TraceQueryStartData{SQL: query})
+}
+
+func (l *LogTracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	l.TraceQueryEnd(ctx, conn, pgx.TraceQueryEndData{
+		CommandTag: data.CommandTag,
+		Err:        data.Err,
+	})
+}
+
+// TraceLongTx implements pgkit.LongTxTracer.
+func (l *LogTracer) TraceLongTx(ctx context.Context, callSite string, duration time.Duration) {
+	if l.LongTxHook != nil {
+		l.LongTxHook(ctx, callSite, duration)
+	}
+}
+
+// withCtxAttrs appends the query tag and caller of the context, if any, to the
+// log attributes.
+func withCtxAttrs(ctx context.Context, attrs ...slog.Attr) []slog.Attr {
+	if tag := QueryTag(ctx); tag != "" {
+		attrs = append(attrs, slog.String("tag", tag))
+	}
+	if caller := Caller(ctx); caller != "" {
+		attrs = append(attrs, slog.String("caller", caller))
+	}
+	return attrs
+}
+
 func getCtxQuery(ctx context.Context) string {
 	query, ok := ctx.Value(ctxKey("query")).(string)
 	if !ok {
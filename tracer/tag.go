@@ -0,0 +1,20 @@
+package tracer
+
+import (
+	"context"
+)
+
+var contextKeyQueryTag = ctxKey("query_tag")
+
+// WithQueryTag returns a context tagging the queries run with it with a
+// logical operation name, ie. "sync-orders", which LogTracer includes in its
+// logs, to attribute load to operations rather than raw SQL text.
+func WithQueryTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, contextKeyQueryTag, tag)
+}
+
+// QueryTag returns the query tag of the context, or "" if it has none.
+func QueryTag(ctx context.Context) string {
+	tag, _ := ctx.Value(contextKeyQueryTag).(string)
+	return tag
+}
@@ -2,6 +2,8 @@ package tracer
 
 import (
 	"context"
+	"time"
+
 	"github.com/jackc/pgx/v5"
 )
 
@@ -15,7 +17,8 @@ var (
 
 // Tracer
 // see: https://github.com/jackc/pgx/blob/master/tracer.go
-// Not implemented: CopyFromTracer, PrepareTracer, PrepareTracer ( not needed now )
+// Not implemented: PrepareTracer ( not needed now ). pgx.CopyFromTracer is
+// forwarded by SQLTracer to the tracers which implement it.
 type Tracer interface {
 	pgx.QueryTracer
 	pgx.BatchTracer
@@ -75,3 +78,35 @@ func (s *SQLTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.
 		tracer.TraceBatchEnd(ctx, conn, data)
 	}
 }
+
+// TraceCopyFromStart implements pgx.CopyFromTracer, notifying the tracers
+// which implement it.
+func (s *SQLTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	for _, tracer := range s.tracers {
+		if t, ok := tracer.(pgx.CopyFromTracer); ok {
+			ctx = t.TraceCopyFromStart(ctx, conn, data)
+		}
+	}
+
+	return ctx
+}
+
+func (s *SQLTracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	for _, tracer := range s.tracers {
+		if t, ok := tracer.(pgx.CopyFromTracer); ok {
+			t.TraceCopyFromEnd(ctx, conn, data)
+		}
+	}
+}
+
+// TraceLongTx implements pgkit.LongTxTracer, notifying the tracers which
+// implement it.
+func (s *SQLTracer) TraceLongTx(ctx context.Context, callSite string, duration time.Duration) {
+	for _, tracer := range s.tracers {
+		if t, ok := tracer.(interface {
+			TraceLongTx(ctx context.Context, callSite string, duration time.Duration)
+		}); ok {
+			t.TraceLongTx(ctx, callSite, duration)
+		}
+	}
+}
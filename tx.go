@@ -0,0 +1,98 @@
+package pgkit
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LongTxTracer is implemented by query tracers which want to be notified of
+// transactions open longer than DB.LongTxThreshold, see tracer.LogTracer.
+type LongTxTracer interface {
+	TraceLongTx(ctx context.Context, callSite string, duration time.Duration)
+}
+
+// BeginFunc runs fn within a transaction, which is committed if fn returns
+// nil and rolled back otherwise.
+//
+// If DB.LongTxThreshold is set and the transaction is still open after the
+// threshold, the connection's tracer is notified with the call site which
+// opened the transaction, if it implements LongTxTracer. This helps to catch
+// idle-in-transaction leaks before they block vacuum.
+func (d *DB) BeginFunc(ctx context.Context, fn func(q *Querier) error) error {
+	return d.beginFunc(ctx, 2, fn)
+}
+
+func (d *DB) beginFunc(ctx context.Context, skip int, fn func(q *Querier) error) error {
+	if d.LongTxThreshold > 0 {
+		stop := d.watchTx(ctx, callSite(skip))
+		defer stop()
+	}
+
+	return pgx.BeginFunc(ctx, d.Conn, func(tx pgx.Tx) error {
+		if err := setApplicationName(ctx, tx); err != nil {
+			return err
+		}
+		return fn(d.TxQuery(tx))
+	})
+}
+
+// watchTx notifies the tracer once the transaction opened at callSite has been
+// open longer than the threshold. The returned func stops the watchdog.
+func (d *DB) watchTx(ctx context.Context, callSite string) func() {
+	tracer, ok := d.Conn.Config().ConnConfig.Tracer.(LongTxTracer)
+	if !ok {
+		return func() {}
+	}
+
+	start := time.Now()
+	timer := time.AfterFunc(d.LongTxThreshold, func() {
+		tracer.TraceLongTx(ctx, callSite, time.Since(start))
+	})
+	return func() { timer.Stop() }
+}
+
+// callSite returns the file:line of the frame skip levels above the function
+// calling callSite, ie. skip 1 is the caller of that function.
+func callSite(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// Savepoint runs fn within a savepoint of the querier's transaction. If fn
+// returns an error, the changes made by fn are rolled back to the savepoint and
+// the error is returned, while the transaction stays usable. This allows to
+// skip individual failed records of a batch processed within a single
+// transaction, instead of aborting the whole batch:
+//
+//	for _, rec := range records {
+//		err := q.Savepoint(ctx, func(q *pgkit.Querier) error {
+//			return process(ctx, q, rec)
+//		})
+//		if err != nil {
+//			failed = append(failed, rec)
+//		}
+//	}
+func (q *Querier) Savepoint(ctx context.Context, fn func(q *Querier) error) error {
+	if q.tx == nil {
+		return wrapErr(fmt.Errorf("savepoint requires a transaction querier"))
+	}
+
+	err := pgx.BeginFunc(ctx, q.tx, func(tx pgx.Tx) error {
+		qq := *q
+		qq.tx = tx
+		return fn(&qq)
+	})
+	if err != nil && q.settings != nil {
+		// rolling back to the savepoint undoes the settings if they were set
+		// within it, set them again before the next statement
+		q.settings.reset()
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrTxCommitFailed is returned by the writes of an http response after the
+// request transaction failed to commit, see DB.TxMiddleware.
+var ErrTxCommitFailed = errors.New("request transaction commit failed")
+
+type txCtxKey struct{}
+
+// WithTx returns a context carrying the transaction, so code deep down the
+// call stack runs its queries within it, see DB.QueryContext.
+func WithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txCtxKey{}, tx)
+}
+
+// TxFromContext returns the transaction of the context set by WithTx.
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txCtxKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// QueryContext returns a querier bound to the transaction of the context, see
+// WithTx, or d.Query if the context carries none.
+func (d *DB) QueryContext(ctx context.Context) *Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return d.TxQuery(tx)
+	}
+	return d.Query
+}
+
+// BeginRequest opens a transaction and returns a context carrying it, along
+// with the funcs to commit or roll it back. Calling rollback after commit is a
+// no-op, so it can be deferred:
+//
+//	ctx, commit, rollback, err := db.BeginRequest(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer rollback()
+//	...
+//	return commit()
+func (d *DB) BeginRequest(ctx context.Context) (context.Context, func() error, func() error, error) {
+	tx, err := d.Conn.Begin(ctx)
+	if err != nil {
+		return ctx, nil, nil, wrapErr(err)
+	}
+	if err := setApplicationName(ctx, tx); err != nil {
+		_ = tx.Rollback(ctx)
+		return ctx, nil, nil, wrapErr(err)
+	}
+
+	commit := func() error {
+		return wrapErr(tx.Commit(ctx))
+	}
+	rollback := func() error {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			return wrapErr(err)
+		}
+		return nil
+	}
+	return WithTx(ctx, tx), commit, rollback, nil
+}
+
+// TxMiddleware runs each request within a transaction carried by the request
+// context, see QueryContext. The transaction is committed when the handler
+// writes a non-error status, before the status is sent, so a failed commit is
+// reported to the client as a 500. It is rolled back when the handler
+// responds with a 4xx or 5xx status, or panics. As the transaction is done
+// once the response status is written, handlers must run their queries
+// before writing the response.
+func (d *DB) TxMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, commit, rollback, err := d.BeginRequest(r.Context())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer rollback()
+
+		tw := &txResponseWriter{ResponseWriter: w, commit: commit}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+		if !tw.wroteHeader {
+			tw.WriteHeader(http.StatusOK)
+		}
+	})
+}
+
+// txResponseWriter commits the request transaction right before a non-error
+// status is written.
+type txResponseWriter struct {
+	http.ResponseWriter
+	commit      func() error
+	wroteHeader bool
+	failed      bool
+}
+
+func (w *txResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if status < http.StatusBadRequest {
+		if err := w.commit(); err != nil {
+			w.failed = true
+			http.Error(w.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *txResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.failed {
+		return 0, ErrTxCommitFailed
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *txResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,9 +1,49 @@
 package pgkit
 
-import "github.com/jackc/pgx/v5"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
 
 var ErrNoRows = pgx.ErrNoRows
 
+var ErrUnexpectedRowCount = errors.New("unexpected row count")
+
+// ErrTooManyRows is returned by Querier.GetExactlyOne when more than one row
+// matches the query.
+var ErrTooManyRows = errors.New("too many rows")
+
+// RowCountError is returned by Querier.ExecExpecting when the number of rows
+// affected doesn't match the expected count.
+type RowCountError struct {
+	Expected int64
+	Actual   int64
+}
+
+func (e *RowCountError) Error() string {
+	return fmt.Sprintf("pgkit: %s, expected %d rows affected but got %d", ErrUnexpectedRowCount, e.Expected, e.Actual)
+}
+
+func (e *RowCountError) Unwrap() error {
+	return ErrUnexpectedRowCount
+}
+
+// MaxRowsError is returned by GetAll when the query returns more rows than the
+// max rows of the querier, see Querier.WithMaxRows. It matches ErrTooManyRows.
+type MaxRowsError struct {
+	Max int
+}
+
+func (e *MaxRowsError) Error() string {
+	return fmt.Sprintf("pgkit: %s, the query returned more than %d rows", ErrTooManyRows, e.Max)
+}
+
+func (e *MaxRowsError) Unwrap() error {
+	return ErrTooManyRows
+}
+
 type errRow struct {
 	err error
 }
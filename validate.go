@@ -0,0 +1,88 @@
+package pgkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// TrackStatementsEnv is the environment variable which enables statement
+// tracking from program start, so statements declared as package variables
+// are tracked too, see TrackStatements.
+const TrackStatementsEnv = "PGKIT_TRACK_STATEMENTS"
+
+var (
+	trackStatements   atomic.Bool
+	trackedStatements sync.Map // query -> RawStatement
+)
+
+func init() {
+	if os.Getenv(TrackStatementsEnv) != "" {
+		trackStatements.Store(true)
+	}
+}
+
+// TrackStatements enables tracking of the statements created by RawQuery
+// from now on, for validation with DB.ValidateStatements, ie. in development
+// and CI. Statements are tracked once per distinct query.
+func TrackStatements() {
+	trackStatements.Store(true)
+}
+
+// TrackedStatements returns the statements tracked so far, ordered by query.
+func TrackedStatements() []RawStatement {
+	var stmts []RawStatement
+	trackedStatements.Range(func(_, v interface{}) bool {
+		stmts = append(stmts, v.(RawStatement))
+		return true
+	})
+	sort.Slice(stmts, func(i, j int) bool { return stmts[i].raw < stmts[j].raw })
+	return stmts
+}
+
+func trackStatement(rs RawStatement) {
+	if trackStatements.Load() {
+		trackedStatements.LoadOrStore(rs.raw, rs)
+	}
+}
+
+// ValidateStatements prepares each statement against the database, which
+// surfaces syntax errors, unknown tables or columns, and placeholder count
+// mismatches, ie. a ? within a string literal, before traffic hits them. It
+// validates the tracked statements if none are given, see TrackStatements.
+// All invalid statements are reported in the returned error.
+func (d *DB) ValidateStatements(ctx context.Context, stmts ...RawStatement) error {
+	if len(stmts) == 0 {
+		stmts = TrackedStatements()
+	}
+
+	conn, err := d.Conn.Acquire(ctx)
+	if err != nil {
+		return wrapErr(err)
+	}
+	defer conn.Release()
+
+	var errs []error
+	for _, stmt := range stmts {
+		if stmt.Err() != nil {
+			errs = append(errs, fmt.Errorf("pgkit: invalid statement %q: %w", stmt.raw, stmt.Err()))
+			continue
+		}
+
+		// the unnamed statement is replaced by the next one, no need to
+		// deallocate it
+		desc, err := conn.Conn().Prepare(ctx, "", stmt.GetQuery())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pgkit: invalid statement %q: %w", stmt.raw, err))
+			continue
+		}
+		if len(desc.ParamOIDs) != stmt.NumArgs() {
+			errs = append(errs, fmt.Errorf("pgkit: invalid statement %q: expecting %d args but the query has %d parameters", stmt.raw, stmt.NumArgs(), len(desc.ParamOIDs)))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,23 @@
+package pgkit_test
+
+import (
+	"testing"
+
+	"github.com/goware/pgkit/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackStatements(t *testing.T) {
+	pgkit.TrackStatements()
+
+	pgkit.RawQuery("SELECT * FROM tracked WHERE id = ?")
+	pgkit.RawQuery("SELECT * FROM tracked WHERE id = ?")
+
+	var n int
+	for _, stmt := range pgkit.TrackedStatements() {
+		if stmt.GetQuery() == "SELECT * FROM tracked WHERE id = $1" {
+			n++
+		}
+	}
+	assert.Equal(t, 1, n)
+}
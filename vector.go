@@ -0,0 +1,19 @@
+package pgkit
+
+import (
+	"context"
+	"strconv"
+)
+
+// WithEfSearch runs fn within a transaction where the pgvector HNSW
+// `hnsw.ef_search` setting is set to efSearch with `SET LOCAL` semantics, to
+// trade speed for recall of the similarity searches done with the given
+// querier only.
+func (d *DB) WithEfSearch(ctx context.Context, efSearch int, fn func(q *Querier) error) error {
+	return d.beginFunc(ctx, 2, func(q *Querier) error {
+		if _, err := q.Exec(ctx, RawQuery(`SELECT set_config('hnsw.ef_search', ?, true)`).Build(strconv.Itoa(efSearch))); err != nil {
+			return err
+		}
+		return fn(q)
+	})
+}